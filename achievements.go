@@ -0,0 +1,110 @@
+package main
+
+import "time"
+
+type Achievement struct {
+	ID          string
+	Icon        string
+	Name        string
+	Description string
+}
+
+type achievementContext struct {
+	state        *GameState
+	player       *Player
+	correct      bool
+	responseTime time.Duration
+}
+
+type achievementRule struct {
+	Achievement
+	atGameEnd bool
+	earned    func(ctx achievementContext) bool
+}
+
+// achievementRules are checked after every answer, or once per player when
+// the game ends for rules marked atGameEnd
+var achievementRules = []achievementRule{
+	{
+		Achievement: Achievement{"first-flag", "🏁", "First Flag", "Answer a round correctly"},
+		earned:      func(ctx achievementContext) bool { return ctx.correct },
+	},
+	{
+		Achievement: Achievement{"hat-trick", "🎩", "Hat Trick", "Get 3 answers in a row right"},
+		earned:      func(ctx achievementContext) bool { return ctx.player.Streak >= 3 },
+	},
+	{
+		Achievement: Achievement{"on-fire", "🔥", "On Fire", "Get 10 answers in a row right"},
+		earned:      func(ctx achievementContext) bool { return ctx.player.Streak >= 10 },
+	},
+	{
+		Achievement: Achievement{"sharp-eye", "🦅", "Sharp Eye", "Catch a fake whose colors changed by less than 10 ΔE"},
+		earned: func(ctx achievementContext) bool {
+			return ctx.correct && ctx.state.DeltaE > 0 && ctx.state.DeltaE < 10
+		},
+	},
+	{
+		Achievement: Achievement{"pixel-perfect", "🔬", "Pixel Perfect", "Catch a 1-ΔE shade change"},
+		earned: func(ctx achievementContext) bool {
+			return ctx.correct && ctx.state.DeltaE > 0 && ctx.state.DeltaE <= 1
+		},
+	},
+	{
+		Achievement: Achievement{"speed-demon", "⚡", "Speed Demon", "Answer correctly within 2 seconds"},
+		earned: func(ctx achievementContext) bool {
+			return ctx.correct && ctx.responseTime > 0 && ctx.responseTime < 2*time.Second
+		},
+	},
+	{
+		Achievement: Achievement{"perfect-game", "💯", "Perfect Game", "Finish a game of 5 or more answers without a mistake"},
+		atGameEnd:   true,
+		earned: func(ctx achievementContext) bool {
+			return ctx.player.Total >= 5 && ctx.player.Incorrect == 0
+		},
+	},
+	{
+		Achievement: Achievement{"last-one-standing", "🛡️", "Last One Standing", "Outlast everyone else in a survival game"},
+		atGameEnd:   true,
+		earned: func(ctx achievementContext) bool {
+			return ctx.state.Lives > 0 && len(ctx.state.Players) > 1 && !ctx.player.Eliminated
+		},
+	},
+}
+
+// awardAchievements records and returns the achievements the player has
+// just earned. Each achievement is only awarded once per game
+func awardAchievements(state *GameState, player *Player, correct bool, responseTime time.Duration, gameEnd bool) []Achievement {
+	ctx := achievementContext{state: state, player: player, correct: correct, responseTime: responseTime}
+
+	var earned []Achievement
+	for _, rule := range achievementRules {
+		if rule.atGameEnd != gameEnd || player.HasAchievement(rule.ID) || !rule.earned(ctx) {
+			continue
+		}
+		player.Achievements = append(player.Achievements, rule.ID)
+		earned = append(earned, rule.Achievement)
+	}
+	return earned
+}
+
+func (p Player) HasAchievement(id string) bool {
+	for _, earned := range p.Achievements {
+		if earned == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Badges returns the player's achievements in the order they were earned
+func (p Player) Badges() []Achievement {
+	var badges []Achievement
+	for _, id := range p.Achievements {
+		for _, rule := range achievementRules {
+			if rule.ID == id {
+				badges = append(badges, rule.Achievement)
+			}
+		}
+	}
+	return badges
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// a flag needs this many guesses before it can count as one of the hardest
+	hardestMinAttempts = 2
+	hardestFlagsShown  = 10
+	unknownRegion      = "Unknown"
+)
+
+// GuessStore keeps every guess made in every game
+type GuessStore interface {
+	RecordGuesses(guesses []Guess) error
+	Guesses() ([]Guess, error)
+}
+
+// FileGuessStore appends guesses to a JSON lines file
+type FileGuessStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileGuessStore(path string) *FileGuessStore {
+	return &FileGuessStore{path: path}
+}
+
+func (s *FileGuessStore) RecordGuesses(guesses []Guess) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return appendJSONLines(s.path, guesses...)
+}
+
+func (s *FileGuessStore) Guesses() ([]Guess, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return readJSONLines[Guess](s.path)
+}
+
+// logGuesses writes the guesses made since the last call to the guess
+// store, if there is one
+func logGuesses(deps *Dependencies) {
+	state := deps.GameState
+	if deps.Guesses == nil || state.loggedGuesses >= len(state.Guesses) {
+		return
+	}
+	if err := deps.Guesses.RecordGuesses(state.Guesses[state.loggedGuesses:]); err != nil {
+		warnf("⚠️  Could not record guesses for game %s: %v", state.GameID, err)
+		return
+	}
+	state.loggedGuesses = len(state.Guesses)
+}
+
+// guessPlayerKey tells players apart: by profile where they have one,
+// otherwise by name
+func guessPlayerKey(guess Guess) string {
+	if guess.ProfileID != "" {
+		return guess.ProfileID
+	}
+	return profileKey(guess.Player)
+}
+
+// AccuracyRow is how often one group of guesses was right
+type AccuracyRow struct {
+	Label       string
+	Correct     int
+	Attempts    int
+	totalDeltaE float64
+	totalMS     int64
+}
+
+func (r *AccuracyRow) add(guess Guess) {
+	r.Attempts++
+	if guess.Correct {
+		r.Correct++
+	}
+	r.totalDeltaE += guess.DeltaE
+	r.totalMS += guess.ResponseMS
+}
+
+func (r AccuracyRow) Percentage() int {
+	if r.Attempts == 0 {
+		return 0
+	}
+	return r.Correct * 100 / r.Attempts
+}
+
+// AverageDeltaE is how subtle the changes in this group were on average
+func (r AccuracyRow) AverageDeltaE() string {
+	if r.Attempts == 0 {
+		return "0.0"
+	}
+	return strconv.FormatFloat(r.totalDeltaE/float64(r.Attempts), 'f', 1, 64)
+}
+
+// AverageSeconds is how long answers in this group took on average
+func (r AccuracyRow) AverageSeconds() string {
+	if r.Attempts == 0 {
+		return "0.0"
+	}
+	return strconv.FormatFloat(float64(r.totalMS)/float64(r.Attempts)/1000, 'f', 1, 64)
+}
+
+// accuracyTable groups guesses by a label and keeps the groups in order
+type accuracyTable map[string]*AccuracyRow
+
+func (t accuracyTable) add(label string, guess Guess) {
+	row, exists := t[label]
+	if !exists {
+		row = &AccuracyRow{Label: label}
+		t[label] = row
+	}
+	row.add(guess)
+}
+
+// rows lists the groups, weakest first
+func (t accuracyTable) rows() []AccuracyRow {
+	rows := make([]AccuracyRow, 0, len(t))
+	for _, row := range t {
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if a, b := rows[i].Percentage(), rows[j].Percentage(); a != b {
+			return a < b
+		}
+		if rows[i].Attempts != rows[j].Attempts {
+			return rows[i].Attempts > rows[j].Attempts
+		}
+		return rows[i].Label < rows[j].Label
+	})
+	return rows
+}
+
+// GuessStats summarises a set of guesses
+type GuessStats struct {
+	Total     AccuracyRow
+	ByCountry []AccuracyRow
+	ByRegion  []AccuracyRow
+	ByTamper  []AccuracyRow
+	Hardest   []AccuracyRow
+}
+
+func summarizeGuesses(guesses []Guess) GuessStats {
+	stats := GuessStats{Total: AccuracyRow{Label: "Total"}}
+	countries, regions, tampers := accuracyTable{}, accuracyTable{}, accuracyTable{}
+	for _, guess := range guesses {
+		stats.Total.add(guess)
+		countries.add(guess.Country, guess)
+		region := guess.Region
+		if region == "" {
+			region = unknownRegion
+		}
+		regions.add(region, guess)
+		tamper := guess.TamperMode
+		if tamper == "" {
+			tamper = TamperNone
+		}
+		tampers.add(tamper, guess)
+	}
+
+	stats.ByCountry = countries.rows()
+	stats.ByRegion = regions.rows()
+	stats.ByTamper = tampers.rows()
+	for _, row := range stats.ByCountry {
+		if len(stats.Hardest) == hardestFlagsShown {
+			break
+		}
+		if row.Attempts >= hardestMinAttempts && row.Correct < row.Attempts {
+			stats.Hardest = append(stats.Hardest, row)
+		}
+	}
+	return stats
+}
+
+// filterGuesses keeps one player's guesses, or everyone's for an empty key
+func filterGuesses(guesses []Guess, player string) []Guess {
+	if player == "" {
+		return guesses
+	}
+	var filtered []Guess
+	for _, guess := range guesses {
+		if guessPlayerKey(guess) == player {
+			filtered = append(filtered, guess)
+		}
+	}
+	return filtered
+}
+
+// StatsPlayer is someone who can be picked on the stats page
+type StatsPlayer struct {
+	Key  string
+	Name string
+}
+
+// statsPlayers lists everyone who has made a guess, under their latest name
+func statsPlayers(guesses []Guess) []StatsPlayer {
+	names := make(map[string]string)
+	for _, guess := range guesses {
+		names[guessPlayerKey(guess)] = guess.Player
+	}
+	players := make([]StatsPlayer, 0, len(names))
+	for key, name := range names {
+		players = append(players, StatsPlayer{Key: key, Name: name})
+	}
+	sort.Slice(players, func(i, j int) bool { return profileKey(players[i].Name) < profileKey(players[j].Name) })
+	return players
+}
+
+// statsView is what the stats page shows
+type statsView struct {
+	Player     string
+	PlayerName string
+	Players    []StatsPlayer
+	Stats      GuessStats
+}
+
+func loadGuesses(deps *Dependencies) ([]Guess, error) {
+	if deps.Guesses == nil {
+		return nil, nil
+	}
+	return deps.Guesses.Guesses()
+}
+
+// statsHandler shows the team's accuracy, or one player's, by country,
+// region and tamper mode
+func statsHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		guesses, err := loadGuesses(deps)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		view := statsView{Player: r.FormValue("player"), Players: statsPlayers(guesses)}
+		for _, player := range view.Players {
+			if player.Key == view.Player {
+				view.PlayerName = player.Name
+			}
+		}
+		view.Stats = summarizeGuesses(filterGuesses(guesses, view.Player))
+		renderPage(w, statsTemplate, view)
+	}
+}
+
+var guessCSVHeader = []string{"game_id", "mode", "round", "player", "profile_id", "country", "region", "tamper_mode", "delta_e", "truth", "answer", "correct", "response_ms", "at"}
+
+// statsExportHandler downloads the raw guesses behind the stats page as CSV
+// or JSON
+func statsExportHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		guesses, err := loadGuesses(deps)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		guesses = filterGuesses(guesses, r.FormValue("player"))
+
+		switch format := r.FormValue("format"); format {
+		case "json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Disposition", `attachment; filename="guesses.json"`)
+			if guesses == nil {
+				guesses = []Guess{}
+			}
+			json.NewEncoder(w).Encode(guesses)
+		case "csv", "":
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Content-Disposition", `attachment; filename="guesses.csv"`)
+			writeGuessesCSV(w, guesses)
+		default:
+			http.Error(w, fmt.Sprintf("unknown export format %q", format), http.StatusBadRequest)
+		}
+	}
+}
+
+func writeGuessesCSV(w io.Writer, guesses []Guess) error {
+	out := csv.NewWriter(w)
+	out.Write(guessCSVHeader)
+	for _, guess := range guesses {
+		out.Write([]string{
+			guess.GameID,
+			string(guess.Mode),
+			strconv.Itoa(guess.Round),
+			guess.Player,
+			guess.ProfileID,
+			guess.Country,
+			guess.Region,
+			guess.TamperMode,
+			strconv.FormatFloat(guess.DeltaE, 'f', 2, 64),
+			strconv.FormatBool(guess.Truth),
+			guess.Answer,
+			strconv.FormatBool(guess.Correct),
+			strconv.FormatInt(guess.ResponseMS, 10),
+			guess.At.Format(time.RFC3339),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test_GIVEN_TamperedFlag_WHEN_Guessing_THEN_ExpectGuessRecorded tests that every guess reaches the analytics log
+func Test_GIVEN_TamperedFlag_WHEN_Guessing_THEN_ExpectGuessRecorded(t *testing.T) {
+	// Arrange
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	store := NewFileGuessStore(filepath.Join(t.TempDir(), "guesses.jsonl"))
+	deps := &Dependencies{
+		GameState:      &GameState{},
+		CountryService: &MockCountryService{country: CountryFlag{Name: "Austria", Region: "Europe"}},
+		ImageService:   &MockImageService{},
+		Images:         NewImageStore(8),
+		Guesses:        store,
+		Now:            func() time.Time { return now },
+	}
+	initializeGameState(deps.GameState, []Player{{Name: "Anna"}}, 3, GameSettings{Mode: ModeTrueFalse})
+	if err := startRound(deps); err != nil {
+		t.Fatal(err)
+	}
+	deps.GameState.IsCorrect = false
+	deps.GameState.TamperMode = TamperShade
+	deps.GameState.DeltaE = 12.5
+	now = now.Add(1500 * time.Millisecond)
+
+	// Act
+	guessHandler(deps).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/guess?answer=incorrect", nil))
+
+	// Assert
+	guesses, err := store.Guesses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(guesses) != 1 {
+		t.Fatalf("Expected 1 recorded guess, got %d", len(guesses))
+	}
+	guess := guesses[0]
+	if guess.Country != "Austria" || guess.Region != "Europe" || guess.TamperMode != TamperShade || guess.DeltaE != 12.5 {
+		t.Errorf("Unexpected flag details %+v", guess)
+	}
+	if guess.Truth || guess.Answer != "incorrect" || !guess.Correct || guess.ResponseMS != 1500 || guess.Player != "Anna" {
+		t.Errorf("Unexpected answer details %+v", guess)
+	}
+	if !guess.At.Equal(now) || guess.GameID != deps.GameState.GameID || guess.Mode != ModeTrueFalse {
+		t.Errorf("Unexpected game details %+v", guess)
+	}
+}
+
+func TestSummarizeGuesses(t *testing.T) {
+	// Arrange
+	guesses := []Guess{
+		{Player: "Anna", Country: "Chad", Region: "Africa", TamperMode: TamperShade, Correct: false},
+		{Player: "Ben", Country: "Chad", Region: "Africa", TamperMode: TamperShade, Correct: false},
+		{Player: "Anna", Country: "Romania", Region: "Europe", TamperMode: TamperDrastic, Correct: true},
+		{Player: "Ben", Country: "Romania", Region: "Europe", TamperMode: TamperNone, Correct: false},
+		{Player: "Anna", Country: "Peru", TamperMode: TamperDrastic, Correct: true},
+	}
+
+	// Act
+	team := summarizeGuesses(guesses)
+	anna := summarizeGuesses(filterGuesses(guesses, "anna"))
+
+	// Assert
+	if team.Total.Attempts != 5 || team.Total.Percentage() != 40 {
+		t.Errorf("Expected 2 of 5 correct, got %+v", team.Total)
+	}
+	if len(team.Hardest) != 2 || team.Hardest[0].Label != "Chad" || team.Hardest[1].Label != "Romania" {
+		t.Errorf("Expected Chad then Romania as the hardest flags, got %+v", team.Hardest)
+	}
+	if len(team.ByTamper) != 3 || team.ByTamper[0].Label != TamperShade || team.ByTamper[2].Label != TamperDrastic {
+		t.Errorf("Expected shade changes to be hardest and drastic ones easiest, got %+v", team.ByTamper)
+	}
+	if last := team.ByRegion[len(team.ByRegion)-1]; last.Label != unknownRegion || last.Percentage() != 100 {
+		t.Errorf("Expected guesses without a region under %q, got %+v", unknownRegion, team.ByRegion)
+	}
+	if anna.Total.Attempts != 3 || anna.Total.Correct != 2 || len(anna.Hardest) != 0 {
+		t.Errorf("Expected Anna's 3 guesses only, got %+v", anna)
+	}
+}
+
+func TestStatsExportHandler(t *testing.T) {
+	// Arrange
+	store := NewFileGuessStore(filepath.Join(t.TempDir(), "guesses.jsonl"))
+	store.RecordGuesses([]Guess{
+		{GameID: "g1", Player: "Anna", ProfileID: "p1", Country: "Chad", TamperMode: TamperShade, DeltaE: 3.25, Answer: "correct", ResponseMS: 2100},
+		{GameID: "g1", Player: "Ben", Country: "Chad", TamperMode: TamperShade, Answer: "incorrect", Correct: true},
+	})
+	deps := &Dependencies{GameState: &GameState{}, Guesses: store}
+
+	// Act
+	csvResponse := httptest.NewRecorder()
+	statsExportHandler(deps).ServeHTTP(csvResponse, httptest.NewRequest("GET", "/stats/export?format=csv&player=p1", nil))
+	jsonResponse := httptest.NewRecorder()
+	statsExportHandler(deps).ServeHTTP(jsonResponse, httptest.NewRequest("GET", "/stats/export?format=json", nil))
+	badResponse := httptest.NewRecorder()
+	statsExportHandler(deps).ServeHTTP(badResponse, httptest.NewRequest("GET", "/stats/export?format=xml", nil))
+	page := httptest.NewRecorder()
+	statsHandler(deps).ServeHTTP(page, httptest.NewRequest("GET", "/stats?player=p1", nil))
+
+	// Assert
+	records, err := csv.NewReader(csvResponse.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1][3] != "Anna" || records[1][8] != "3.25" || records[1][12] != "2100" {
+		t.Errorf("Expected a header and Anna's guess, got %v", records)
+	}
+	var exported []Guess
+	if err := json.NewDecoder(jsonResponse.Body).Decode(&exported); err != nil || len(exported) != 2 {
+		t.Errorf("Expected both guesses as JSON, got %v (%v)", exported, err)
+	}
+	if badResponse.Code != 400 {
+		t.Errorf("Expected 400 for an unknown format, got %d", badResponse.Code)
+	}
+	if body := page.Body.String(); !strings.Contains(body, "Anna's answers: 0% of 1 correct") {
+		t.Errorf("Expected the stats page for Anna alone, got %s", body)
+	}
+}
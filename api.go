@@ -0,0 +1,438 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	apiGameIdleTimeout = 2 * time.Hour
+	maxAPIRounds       = 100
+)
+
+// APIError is the body of every failed API request. Code is stable enough
+// for clients to switch on, Message is meant for people
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type apiErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, apiErrorResponse{Error: APIError{Code: code, Message: message}})
+}
+
+// CreateGameRequest is what POST /api/v1/games takes. Everything but the
+// players is optional
+type CreateGameRequest struct {
+	Players     []string   `json:"players"`
+	Rounds      int        `json:"rounds,omitempty"`
+	Mode        GameMode   `json:"mode,omitempty"`
+	Difficulty  Difficulty `json:"difficulty,omitempty"`
+	PickOptions int        `json:"pickOptions,omitempty"`
+	TimeLimit   int        `json:"timeLimit,omitempty"`
+	Lives       int        `json:"lives,omitempty"`
+	Selection   string     `json:"selection,omitempty"`
+	Exploration float64    `json:"exploration,omitempty"`
+	Regions     []string   `json:"regions,omitempty"`
+	Seed        *int64     `json:"seed,omitempty"`
+}
+
+// PlayerScore is one player's standing in an API game
+type PlayerScore struct {
+	Name       string `json:"name"`
+	Points     int    `json:"points"`
+	Correct    int    `json:"correct"`
+	Incorrect  int    `json:"incorrect"`
+	Streak     int    `json:"streak"`
+	BestStreak int    `json:"bestStreak"`
+	Lives      int    `json:"lives,omitempty"`
+	Eliminated bool   `json:"eliminated,omitempty"`
+}
+
+// GameResponse describes an API game and its scores, best first
+type GameResponse struct {
+	ID          string        `json:"id"`
+	Status      string        `json:"status"`
+	Settings    GameSettings  `json:"settings"`
+	Round       int           `json:"round"`
+	TotalRounds int           `json:"totalRounds"`
+	Seed        *int64        `json:"seed,omitempty"`
+	Scores      []PlayerScore `json:"scores"`
+	Replay      string        `json:"replay,omitempty"`
+}
+
+// RoundResponse is the flag the current player has to answer. The country
+// is left out where naming it is the question
+type RoundResponse struct {
+	Round       int        `json:"round"`
+	TotalRounds int        `json:"totalRounds"`
+	Player      string     `json:"player"`
+	Mode        GameMode   `json:"mode"`
+	Country     string     `json:"country,omitempty"`
+	FlagURL     string     `json:"flagUrl,omitempty"`
+	Choices     []string   `json:"choices,omitempty"`
+	Options     []string   `json:"options,omitempty"`
+	FlagWidth   int        `json:"flagWidth,omitempty"`
+	FlagHeight  int        `json:"flagHeight,omitempty"`
+	Deadline    *time.Time `json:"deadline,omitempty"`
+}
+
+// AnswerRequest is what POST /api/v1/games/{id}/answers takes. Answers are
+// written the way the guess log keeps them: "correct" or "incorrect" for a
+// real flag or a fake, a country name, a 0-based option or an "x,y" click
+type AnswerRequest struct {
+	Answer string `json:"answer"`
+}
+
+// AnswerResponse is the revealed result of an answer
+type AnswerResponse struct {
+	Correct           bool    `json:"correct"`
+	Expired           bool    `json:"expired"`
+	Points            int     `json:"points"`
+	Message           string  `json:"message"`
+	Country           string  `json:"country"`
+	Real              bool    `json:"real"`
+	TamperDescription string  `json:"tamperDescription,omitempty"`
+	DeltaE            float64 `json:"deltaE,omitempty"`
+	OriginalURL       string  `json:"originalUrl,omitempty"`
+	GameOver          bool    `json:"gameOver"`
+}
+
+// apiGame is a game played through the API. Requests for the same game are
+// served one at a time
+type apiGame struct {
+	mu         sync.Mutex
+	deps       *Dependencies
+	lastActive time.Time
+}
+
+// APIGames keeps the games created through the API, each with its own
+// state but sharing the stores of the web game
+type APIGames struct {
+	mu    sync.Mutex
+	games map[string]*apiGame
+}
+
+func NewAPIGames() *APIGames {
+	return &APIGames{games: make(map[string]*apiGame)}
+}
+
+func (g *APIGames) add(game *apiGame) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := game.lastActive
+	for id, other := range g.games {
+		other.mu.Lock()
+		idle := now.Sub(other.lastActive) > apiGameIdleTimeout
+		other.mu.Unlock()
+		if idle {
+			delete(g.games, id)
+			other.deps.Images.Close()
+		}
+	}
+	g.games[game.deps.GameState.GameID] = game
+}
+
+func (g *APIGames) get(id string) *apiGame {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.games[id]
+}
+
+// validate checks a request the way the setup form would clean it up, but
+// reports what is wrong instead of quietly using defaults
+func (req CreateGameRequest) validate(deps *Dependencies) (GameSettings, int, error) {
+	if len(req.Players) == 0 || len(req.Players) > deps.maxPlayers() {
+		return GameSettings{}, 0, fmt.Errorf("a game needs between 1 and %d players", deps.maxPlayers())
+	}
+	for _, name := range req.Players {
+		if normalizeDisplayName(name) == "" {
+			return GameSettings{}, 0, errNameRequired
+		}
+	}
+	rounds := req.Rounds
+	if rounds == 0 {
+		rounds = parseRoundsCount("")
+	}
+	if rounds < 0 || rounds > maxAPIRounds {
+		return GameSettings{}, 0, fmt.Errorf("rounds must be between 1 and %d", maxAPIRounds)
+	}
+	if req.Mode != "" && parseGameMode(string(req.Mode)) != req.Mode {
+		return GameSettings{}, 0, fmt.Errorf("unknown mode %q", req.Mode)
+	}
+	if req.Difficulty != "" && parseDifficulty(string(req.Difficulty)) != req.Difficulty {
+		return GameSettings{}, 0, fmt.Errorf("unknown difficulty %q", req.Difficulty)
+	}
+	if req.Selection != "" && parseSelection(req.Selection) != req.Selection {
+		return GameSettings{}, 0, fmt.Errorf("unknown selection %q", req.Selection)
+	}
+	if req.PickOptions != 0 && (req.PickOptions < minPickOptions || req.PickOptions > maxPickOptions) {
+		return GameSettings{}, 0, fmt.Errorf("pickOptions must be between %d and %d", minPickOptions, maxPickOptions)
+	}
+	if req.TimeLimit < 0 || req.Lives < 0 {
+		return GameSettings{}, 0, errors.New("timeLimit and lives cannot be negative")
+	}
+	if req.Exploration < 0 || req.Exploration > 1 {
+		return GameSettings{}, 0, errors.New("exploration must be between 0 and 1")
+	}
+	if len(req.Regions) > 0 && len(filterRegions(deps.CountryService.AllCountries(), req.Regions)) == 0 {
+		return GameSettings{}, 0, fmt.Errorf("no countries in the regions %v", req.Regions)
+	}
+
+	settings := GameSettings{
+		Mode:        parseGameMode(string(req.Mode)),
+		Difficulty:  parseDifficulty(string(req.Difficulty)),
+		PickOptions: parsePickOptions(strconv.Itoa(req.PickOptions)),
+		TimeLimit:   req.TimeLimit,
+		Lives:       req.Lives,
+		Selection:   parseSelection(req.Selection),
+		Regions:     req.Regions,
+	}
+	if settings.Selection == SelectWeakness {
+		settings.Exploration = defaultExploration
+		if req.Exploration > 0 {
+			settings.Exploration = req.Exploration
+		}
+	}
+	return settings, rounds, nil
+}
+
+func newGameResponse(state *GameState) GameResponse {
+	response := GameResponse{
+		ID:          state.GameID,
+		Status:      "playing",
+		Settings:    state.GameSettings,
+		Round:       state.CurrentRound,
+		TotalRounds: state.TotalRounds,
+		Replay:      state.ReplayID,
+		Scores:      []PlayerScore{},
+	}
+	if state.GameOver {
+		response.Status = "finished"
+		if state.Lives == 0 && response.Round > state.TotalRounds {
+			// the round counter has moved one past the last round
+			response.Round = state.TotalRounds
+		}
+	}
+	if state.Seeded {
+		seed := state.Seed
+		response.Seed = &seed
+	}
+	for _, player := range state.Standings() {
+		response.Scores = append(response.Scores, PlayerScore{
+			Name:       player.Name,
+			Points:     player.Points,
+			Correct:    player.Correct,
+			Incorrect:  player.Incorrect,
+			Streak:     player.Streak,
+			BestStreak: player.BestStreak,
+			Lives:      player.Lives,
+			Eliminated: player.Eliminated,
+		})
+	}
+	return response
+}
+
+func newRoundResponse(state *GameState) RoundResponse {
+	response := RoundResponse{
+		Round:       state.CurrentRound,
+		TotalRounds: state.TotalRounds,
+		Player:      state.Players[state.CurrentPlayer].TurnName(),
+		Mode:        state.Mode,
+		Choices:     state.Choices,
+		Options:     state.FlagOptions,
+	}
+	if state.Mode != ModeChoice {
+		response.Country = state.CountryName
+	}
+	if state.Mode != ModePickReal {
+		response.FlagURL = state.FlagSrc
+	}
+	if state.Mode == ModeSpot {
+		response.FlagWidth, response.FlagHeight = state.FlagWidth, state.FlagHeight
+	}
+	if !state.RoundDeadline.IsZero() {
+		deadline := state.RoundDeadline
+		response.Deadline = &deadline
+	}
+	return response
+}
+
+// nextAPIRound moves an API game past a revealed result, to the next flag
+// or to the end of the game
+func nextAPIRound(deps *Dependencies) error {
+	state := deps.GameState
+	if state.GameOver || !state.ShowResult || !advanceTurn(deps) {
+		return nil
+	}
+	return startRound(deps)
+}
+
+// withAPIGame looks up the game of the request and runs fn with the game
+// locked
+func withAPIGame(deps *Dependencies, w http.ResponseWriter, r *http.Request, fn func(game *apiGame)) {
+	game := deps.API.get(r.PathValue("id"))
+	if game == nil {
+		writeAPIError(w, http.StatusNotFound, "game_not_found", "no game with ID "+r.PathValue("id"))
+		return
+	}
+	game.mu.Lock()
+	defer game.mu.Unlock()
+	game.lastActive = deps.now()
+	fn(game)
+}
+
+// apiCreateGameHandler starts a game and deals its first flag
+func apiCreateGameHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CreateGameRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid_json", err.Error())
+			return
+		}
+		settings, rounds, err := req.validate(deps)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid_game", err.Error())
+			return
+		}
+
+		gameDeps := *deps
+		gameDeps.GameState = &GameState{}
+		gameDeps.Events = nil
+		gameDeps.Images = deps.Images.ForGame()
+		gameDeps.Snapshots = nil
+		players := make([]Player, len(req.Players))
+		for i, name := range req.Players {
+			players[i] = Player{Name: normalizeDisplayName(name)}
+		}
+		initializeGameState(gameDeps.GameState, linkProfiles(&gameDeps, players), rounds, settings)
+		if req.Seed != nil {
+			gameDeps.GameState.SeedRandom(*req.Seed)
+		} else {
+			gameDeps.seedNewGame()
+		}
+		if err := startRound(&gameDeps); err != nil {
+			gameDeps.Images.Close()
+			writeAPIError(w, http.StatusBadGateway, "flag_unavailable", err.Error())
+			return
+		}
+
+		deps.API.add(&apiGame{deps: &gameDeps, lastActive: deps.now()})
+		w.Header().Set("Location", "/api/v1/games/"+gameDeps.GameState.GameID)
+		writeJSON(w, http.StatusCreated, newGameResponse(gameDeps.GameState))
+	}
+}
+
+// apiGameHandler returns a game's settings and scores
+func apiGameHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		withAPIGame(deps, w, r, func(game *apiGame) {
+			writeJSON(w, http.StatusOK, newGameResponse(game.deps.GameState))
+		})
+	}
+}
+
+// apiRoundHandler returns the flag waiting for an answer. A round whose time
+// ran out counts as a wrong answer and the next flag is dealt
+func apiRoundHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		withAPIGame(deps, w, r, func(game *apiGame) {
+			expireRound(game.deps)
+			if err := nextAPIRound(game.deps); err != nil {
+				writeAPIError(w, http.StatusBadGateway, "flag_unavailable", err.Error())
+				return
+			}
+			state := game.deps.GameState
+			if state.GameOver {
+				writeAPIError(w, http.StatusConflict, "game_over", "the game is over, see its scores")
+				return
+			}
+			writeJSON(w, http.StatusOK, newRoundResponse(state))
+		})
+	}
+}
+
+// apiAnswerHandler scores the current player's answer, reveals the result
+// and deals the next flag
+func apiAnswerHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req AnswerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid_json", err.Error())
+			return
+		}
+
+		withAPIGame(deps, w, r, func(game *apiGame) {
+			// a flag that could not be dealt after the last answer gets
+			// another try first
+			if err := nextAPIRound(game.deps); err != nil {
+				writeAPIError(w, http.StatusBadGateway, "flag_unavailable", err.Error())
+				return
+			}
+			state := game.deps.GameState
+			if state.GameOver {
+				writeAPIError(w, http.StatusConflict, "game_over", "the game is over, see its scores")
+				return
+			}
+
+			player := &state.Players[state.CurrentPlayer]
+			pointsBefore := player.Points
+			expired := expireRound(game.deps)
+			if !expired {
+				if err := answerRound(game.deps, req.Answer); err != nil {
+					writeAPIError(w, http.StatusBadRequest, "invalid_answer", "the answer does not fit a "+string(state.Mode)+" round")
+					return
+				}
+			}
+
+			response := AnswerResponse{
+				Correct:           state.ResultCorrect,
+				Expired:           expired,
+				Points:            player.Points - pointsBefore,
+				Message:           state.ResultMessage,
+				Country:           state.CountryName,
+				Real:              state.IsCorrect,
+				TamperDescription: state.TamperDescription,
+				DeltaE:            state.DeltaE,
+				OriginalURL:       state.OriginalSrc,
+			}
+			if err := nextAPIRound(game.deps); err != nil {
+				writeAPIError(w, http.StatusBadGateway, "flag_unavailable", err.Error())
+				return
+			}
+			response.GameOver = state.GameOver
+			writeJSON(w, http.StatusOK, response)
+		})
+	}
+}
+
+// registerAPIRoutes adds the JSON API and its OpenAPI document to a mux
+func registerAPIRoutes(mux *http.ServeMux, deps *Dependencies) {
+	mux.HandleFunc("GET /api/openapi.json", openAPIHandler)
+	mux.HandleFunc("POST /api/v1/games", apiCreateGameHandler(deps))
+	mux.HandleFunc("GET /api/v1/games/{id}", apiGameHandler(deps))
+	mux.HandleFunc("GET /api/v1/games/{id}/round", apiRoundHandler(deps))
+	mux.HandleFunc("POST /api/v1/games/{id}/answers", apiAnswerHandler(deps))
+	mux.HandleFunc("/api/", apiNotFoundHandler)
+}
+
+// apiNotFoundHandler answers every other path under /api/ in JSON, so that
+// clients never have to parse the HTML game page
+func apiNotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeAPIError(w, http.StatusNotFound, "not_found", "no endpoint "+r.Method+" "+r.URL.Path)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newAPITestMux() (*http.ServeMux, *Dependencies) {
+	deps := &Dependencies{
+		GameState: &GameState{},
+		CountryService: &MockCountryService{countries: []CountryFlag{
+			{Name: "Austria", Region: "Europe"}, {Name: "Chad", Region: "Africa"}, {Name: "Peru", Region: "Americas"},
+		}},
+		ImageService: &MockImageService{},
+		Images:       NewImageStore(8),
+		API:          NewAPIGames(),
+		Now:          time.Now,
+	}
+	mux := http.NewServeMux()
+	registerAPIRoutes(mux, deps)
+	mux.HandleFunc("GET /flag/{roundID}/{file}", flagImageHandler(deps))
+	return mux, deps
+}
+
+func callAPI(mux *http.ServeMux, method, path, body string, out any) *httptest.ResponseRecorder {
+	response := httptest.NewRecorder()
+	mux.ServeHTTP(response, httptest.NewRequest(method, path, strings.NewReader(body)))
+	if out != nil {
+		json.NewDecoder(response.Body).Decode(out)
+	}
+	return response
+}
+
+// Test_GIVEN_APIGame_WHEN_PlayingToTheEnd_THEN_ExpectScoresAndResults tests the whole API game lifecycle
+func Test_GIVEN_APIGame_WHEN_PlayingToTheEnd_THEN_ExpectScoresAndResults(t *testing.T) {
+	// Arrange
+	mux, _ := newAPITestMux()
+	var game GameResponse
+	created := callAPI(mux, "POST", "/api/v1/games", `{"players": ["Anna"], "rounds": 2, "regions": ["africa"], "seed": 7}`, &game)
+	if created.Code != http.StatusCreated || game.ID == "" {
+		t.Fatalf("Expected the game to be created, got %d %s", created.Code, created.Body.String())
+	}
+	if created.Header().Get("Location") != "/api/v1/games/"+game.ID || game.Seed == nil || *game.Seed != 7 {
+		t.Errorf("Expected the location and seed of the game, got %v %+v", created.Header(), game)
+	}
+	base := "/api/v1/games/" + game.ID
+
+	// Act
+	var results []AnswerResponse
+	for i := 0; i < 2; i++ {
+		var round RoundResponse
+		if response := callAPI(mux, "GET", base+"/round", "", &round); response.Code != 200 {
+			t.Fatalf("Expected round %d, got %d %s", i+1, response.Code, response.Body.String())
+		}
+		if round.Round != i+1 || round.Player != "Anna" || round.Country != "Chad" || round.FlagURL == "" {
+			t.Errorf("Unexpected round %+v", round)
+		}
+		var result AnswerResponse
+		callAPI(mux, "POST", base+"/answers", `{"answer": "correct"}`, &result)
+		results = append(results, result)
+	}
+	var final GameResponse
+	callAPI(mux, "GET", base, "", &final)
+	afterEnd := callAPI(mux, "GET", base+"/round", "", nil)
+
+	// Assert
+	correct := 0
+	for _, result := range results {
+		if result.Correct != result.Real || result.Country != "Chad" {
+			t.Errorf("Expected answering real to be right for real flags only, got %+v", result)
+		}
+		if result.Correct {
+			correct++
+		}
+	}
+	if !results[1].GameOver || results[0].GameOver {
+		t.Errorf("Expected the game to end with the second answer, got %+v", results)
+	}
+	if final.Status != "finished" || final.Round != 2 || len(final.Scores) != 1 || final.Scores[0].Correct != correct {
+		t.Errorf("Expected the final scores, got %+v", final)
+	}
+	if afterEnd.Code != http.StatusConflict || !strings.Contains(afterEnd.Body.String(), `"game_over"`) {
+		t.Errorf("Expected a game_over conflict, got %d %s", afterEnd.Code, afterEnd.Body.String())
+	}
+}
+
+// Test_GIVEN_BadRequests_WHEN_CallingAPI_THEN_ExpectStructuredErrors tests the API's error responses
+func Test_GIVEN_BadRequests_WHEN_CallingAPI_THEN_ExpectStructuredErrors(t *testing.T) {
+	// Arrange
+	mux, _ := newAPITestMux()
+	var pick, trueFalse, choice GameResponse
+	callAPI(mux, "POST", "/api/v1/games", `{"players": ["Anna"], "mode": "pickreal"}`, &pick)
+	callAPI(mux, "POST", "/api/v1/games", `{"players": ["Anna"]}`, &trueFalse)
+	callAPI(mux, "POST", "/api/v1/games", `{"players": ["Anna"], "mode": "choice"}`, &choice)
+
+	tests := []struct {
+		name, method, path, body string
+		status                   int
+		code                     string
+	}{
+		{"broken JSON", "POST", "/api/v1/games", `{"players":`, 400, "invalid_json"},
+		{"no players", "POST", "/api/v1/games", `{"players": []}`, 400, "invalid_game"},
+		{"unknown mode", "POST", "/api/v1/games", `{"players": ["Anna"], "mode": "darts"}`, 400, "invalid_game"},
+		{"empty region", "POST", "/api/v1/games", `{"players": ["Anna"], "regions": ["Antarctica"]}`, 400, "invalid_game"},
+		{"missing game", "GET", "/api/v1/games/nope/round", "", 404, "game_not_found"},
+		{"option out of range", "POST", "/api/v1/games/" + pick.ID + "/answers", `{"answer": "9"}`, 400, "invalid_answer"},
+		{"neither real nor fake", "POST", "/api/v1/games/" + trueFalse.ID + "/answers", `{"answer": "real"}`, 400, "invalid_answer"},
+		{"country not offered", "POST", "/api/v1/games/" + choice.ID + "/answers", `{"answer": "Atlantis"}`, 400, "invalid_answer"},
+		{"unknown endpoint", "GET", "/api/v1/teapots", "", 404, "not_found"},
+	}
+	for _, test := range tests {
+		// Act
+		var body apiErrorResponse
+		response := callAPI(mux, test.method, test.path, test.body, &body)
+
+		// Assert
+		if response.Code != test.status || body.Error.Code != test.code || body.Error.Message == "" {
+			t.Errorf("%s: expected %d %s, got %d %+v", test.name, test.status, test.code, response.Code, body)
+		}
+		if response.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("%s: expected a JSON error, got %q", test.name, response.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestAPIRoundHidesCountryWhenNamingIt(t *testing.T) {
+	mux, _ := newAPITestMux()
+	var game GameResponse
+	callAPI(mux, "POST", "/api/v1/games", `{"players": ["Anna"], "mode": "choice"}`, &game)
+
+	var round RoundResponse
+	callAPI(mux, "GET", "/api/v1/games/"+game.ID+"/round", "", &round)
+
+	if round.Country != "" || len(round.Choices) == 0 || round.FlagURL == "" {
+		t.Errorf("Expected choices and a flag but no country name, got %+v", round)
+	}
+}
+
+func TestAPIGamesUseConfiguredSeed(t *testing.T) {
+	mux, deps := newAPITestMux()
+	seed := int64(42)
+	deps.Seed = &seed
+
+	var game GameResponse
+	callAPI(mux, "POST", "/api/v1/games", `{"players": ["Anna"]}`, &game)
+
+	if game.Seed == nil || *game.Seed != 42 {
+		t.Errorf("Expected the configured seed, got %v", game.Seed)
+	}
+}
+
+// Test_GIVEN_TimedAPIGame_WHEN_AnsweringAfterTheDeadline_THEN_ExpectExpiredResult tests that late answers are marked as such
+func Test_GIVEN_TimedAPIGame_WHEN_AnsweringAfterTheDeadline_THEN_ExpectExpiredResult(t *testing.T) {
+	// Arrange
+	mux, deps := newAPITestMux()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	deps.Now = func() time.Time { return now }
+	var game GameResponse
+	callAPI(mux, "POST", "/api/v1/games", `{"players": ["Anna"], "rounds": 2, "timeLimit": 5}`, &game)
+	base := "/api/v1/games/" + game.ID
+
+	// Act
+	var onTime, late AnswerResponse
+	callAPI(mux, "POST", base+"/answers", `{"answer": "correct"}`, &onTime)
+	now = now.Add(6 * time.Second)
+	response := callAPI(mux, "POST", base+"/answers", `{"answer": "correct"}`, &late)
+
+	// Assert
+	if onTime.Expired {
+		t.Errorf("Expected an answer in time to count, got %+v", onTime)
+	}
+	if response.Code != http.StatusOK || !late.Expired || late.Correct || late.Points != 0 {
+		t.Errorf("Expected the late answer to be expired and worth nothing, got %d %+v", response.Code, late)
+	}
+}
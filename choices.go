@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+const (
+	choiceCount = 4
+
+	minPickOptions     = 2
+	maxPickOptions     = 4
+	defaultPickOptions = 3
+
+	// Palettes further apart than this are not considered look-alikes
+	paletteSimilarityThreshold = 60.0
+	paletteColorsCompared      = 4
+)
+
+// PaletteIndex remembers the palettes of flags downloaded so far so that
+// wrong options can be picked from flags that look alike
+type PaletteIndex struct {
+	mu       sync.Mutex
+	palettes map[string][]color.RGBA
+}
+
+func NewPaletteIndex() *PaletteIndex {
+	return &PaletteIndex{palettes: make(map[string][]color.RGBA)}
+}
+
+func (p *PaletteIndex) Add(countryName string, img image.Image) {
+	palette := getDistinctColors(img)
+	if len(palette) > paletteColorsCompared {
+		palette = palette[:paletteColorsCompared]
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.palettes[countryName] = palette
+}
+
+// Similar returns up to n known countries whose palette is close to the
+// given country's, closest first
+func (p *PaletteIndex) Similar(countryName string, n int) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	target, exists := p.palettes[countryName]
+	if !exists || len(target) == 0 {
+		return nil
+	}
+
+	type match struct {
+		name     string
+		distance float64
+	}
+	var matches []match
+	for name, palette := range p.palettes {
+		if name == countryName || len(palette) == 0 {
+			continue
+		}
+		distance := (paletteDistance(target, palette) + paletteDistance(palette, target)) / 2
+		if distance < paletteSimilarityThreshold {
+			matches = append(matches, match{name, distance})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].distance < matches[j].distance })
+
+	var similar []string
+	for i := 0; i < len(matches) && i < n; i++ {
+		similar = append(similar, matches[i].name)
+	}
+	return similar
+}
+
+// paletteDistance averages how far each color of a is from its closest color in b
+func paletteDistance(a, b []color.RGBA) float64 {
+	total := 0.0
+	for _, ca := range a {
+		closest := math.MaxFloat64
+		for _, cb := range b {
+			dr := float64(ca.R) - float64(cb.R)
+			dg := float64(ca.G) - float64(cb.G)
+			db := float64(ca.B) - float64(cb.B)
+			closest = math.Min(closest, math.Sqrt(dr*dr+dg*dg+db*db))
+		}
+		total += closest
+	}
+	return total / float64(len(a))
+}
+
+// pickDistractors chooses n wrong answers for a country: at most half of them
+// come from look-alike palettes, the rest from the same region
+func pickDistractors(deps *Dependencies, country CountryFlag, n int, rng *rand.Rand) []string {
+	seen := map[string]bool{country.Name: true}
+	var distractors []string
+	add := func(name string) {
+		if len(distractors) < n && !seen[name] {
+			seen[name] = true
+			distractors = append(distractors, name)
+		}
+	}
+
+	if deps.Palettes != nil {
+		for _, name := range deps.Palettes.Similar(country.Name, n/2) {
+			add(name)
+		}
+	}
+	for _, name := range deps.CountryService.SimilarCountries(country, n, rng) {
+		add(name)
+	}
+	return distractors
+}
+
+// prepareChoices fills in the shuffled options for a multiple-choice round
+func prepareChoices(deps *Dependencies, country CountryFlag) {
+	rng := deps.GameState.random()
+	choices := append([]string{country.Name}, pickDistractors(deps, country, choiceCount-1, rng)...)
+	rng.Shuffle(len(choices), func(i, j int) { choices[i], choices[j] = choices[j], choices[i] })
+
+	deps.GameState.Choices = choices
+	deps.GameState.ChosenAnswer = ""
+}
+
+// preparePickOptions stores the genuine flag and tampered copies of it at
+// random positions for a "pick the real one" round
+func preparePickOptions(deps *Dependencies, originalImg image.Image) {
+	state := deps.GameState
+	count := state.PickOptions
+	if count < minPickOptions {
+		count = defaultPickOptions
+	}
+
+	state.RealOption = state.random().Intn(count)
+	state.PickedOption = -1
+	state.FlagOptions = make([]string, count)
+	state.DeltaE = 0
+	state.TamperMode = TamperNone
+	for i := range state.FlagOptions {
+		img := originalImg
+		if i != state.RealOption {
+			tamper := deps.ImageService.Tamper(originalImg, state.random(), state.Difficulty)
+			img = tamper.Image
+			// the hardest fake to rule out decides how subtle the round was
+			if state.DeltaE == 0 || tamper.DeltaE < state.DeltaE {
+				state.DeltaE = tamper.DeltaE
+				state.TamperMode = tamper.Mode()
+			}
+		}
+		variant := fmt.Sprintf("option-%d", i)
+		deps.Images.Put(state.RoundID, variant, img)
+		state.FlagOptions[i] = flagImageURL(state.RoundID, variant)
+	}
+}
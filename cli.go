@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// command is one of the subcommands of the flagsgui binary
+type command struct {
+	name    string
+	args    string // what follows the flags in the usage line
+	summary string
+	serves  bool // takes the server's --addr and --no-browser
+	// defaults adjusts the config before the file and environment are read
+	defaults func(cfg *Config)
+	// flags adds the command's own flags and returns what runs it
+	flags func(fs *flag.FlagSet) func(cli *cli, cfg Config, args []string) error
+}
+
+// cli is where the commands read and write
+type cli struct {
+	in     io.Reader
+	out    io.Writer
+	errOut io.Writer
+	getenv func(string) string
+}
+
+var commands = []command{
+	{
+		name:    "serve",
+		summary: "Run the game server and open it in a browser",
+		serves:  true,
+		flags: func(fs *flag.FlagSet) func(*cli, Config, []string) error {
+			return func(c *cli, cfg Config, args []string) error {
+				return serve(c, cfg)
+			}
+		},
+	},
+	{
+		name:    "play",
+		summary: "Play in the terminal, in-process or against a server",
+		flags: func(fs *flag.FlagSet) func(*cli, Config, []string) error {
+			var opts playOptions
+			opts.bindFlags(fs)
+			return func(c *cli, cfg Config, args []string) error {
+				return runPlay(cfg, opts, c.in, c.out)
+			}
+		},
+	},
+	{
+		name:     "debug-flag",
+		args:     "<country>",
+		summary:  "Run the server with every round showing a tampered flag of one country",
+		serves:   true,
+		defaults: func(cfg *Config) { cfg.LogLevel = "debug" },
+		flags: func(fs *flag.FlagSet) func(*cli, Config, []string) error {
+			return func(c *cli, cfg Config, args []string) error {
+				if len(args) == 0 {
+					return errUsage
+				}
+				debugCountry = strings.Join(args, " ")
+				fmt.Fprintf(c.out, "🐛 DEBUG MODE: Testing with country '%s'\n", debugCountry)
+				return serve(c, cfg)
+			}
+		},
+	},
+	{
+		name:    "validate-flags",
+		summary: "Download every country's flag and report the ones that fail",
+		flags: func(fs *flag.FlagSet) func(*cli, Config, []string) error {
+			workers := fs.Int("workers", 8, "flags to download at once")
+			return func(c *cli, cfg Config, args []string) error {
+				return validateFlags(newDependencies(cfg), max(*workers, 1), c.out)
+			}
+		},
+	},
+	{
+		name:    "export-stats",
+		summary: "Write the logged guesses behind the stats page as CSV or JSON",
+		flags: func(fs *flag.FlagSet) func(*cli, Config, []string) error {
+			format := fs.String("format", "csv", "csv or json")
+			player := fs.String("player", "", "only this player's guesses")
+			output := fs.String("output", "", "file to write, standard output if empty")
+			return func(c *cli, cfg Config, args []string) error {
+				out := c.out
+				if *output != "" {
+					file, err := os.Create(*output)
+					if err != nil {
+						return err
+					}
+					defer file.Close()
+					out = file
+				}
+				return exportStats(newDependencies(cfg), *format, *player, out)
+			}
+		},
+	},
+}
+
+// errUsage reports a command called with the wrong arguments; its usage is
+// printed instead of the error
+var errUsage = errors.New("wrong arguments")
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// runCLI runs the command named by the first argument, serve if there is
+// none, and returns the exit code
+func runCLI(args []string, c *cli) int {
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	switch name {
+	case "help", "-h", "-help", "--help":
+		if len(args) > 0 && findCommand(args[0]) != nil {
+			runCommand(findCommand(args[0]), []string{"-h"}, c)
+			return 0
+		}
+		c.usage(c.out)
+		return 0
+	}
+	if strings.HasPrefix(name, "-") {
+		// flags without a command are the server's
+		name, args = "serve", append([]string{name}, args...)
+	}
+
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(c.errOut, "flagsgui: unknown command %q\n\n", name)
+		c.usage(c.errOut)
+		return 2
+	}
+	return runCommand(cmd, args, c)
+}
+
+func runCommand(cmd *command, args []string, c *cli) int {
+	cfg := defaultConfig()
+	if cmd.defaults != nil {
+		cmd.defaults(&cfg)
+	}
+	path, explicit := configPath(args, c.getenv)
+	cfg, err := loadConfig(cfg, path, explicit, c.getenv)
+	if err != nil {
+		fmt.Fprintf(c.errOut, "flagsgui %s: %v\n", cmd.name, err)
+		return 2
+	}
+
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(c.errOut)
+	cfg.bindFlags(fs, cmd.serves)
+	run := cmd.flags(fs)
+	fs.Usage = func() { cmd.usage(fs) }
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if err := cfg.validate(); err != nil {
+		fmt.Fprintf(c.errOut, "flagsgui %s: %v\n", cmd.name, err)
+		return 2
+	}
+	logLevel, _ = parseLogLevel(cfg.LogLevel)
+
+	err = run(c, cfg, fs.Args())
+	if errors.Is(err, errUsage) {
+		fs.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(c.errOut, "flagsgui %s: %v\n", cmd.name, err)
+		return 1
+	}
+	return 0
+}
+
+func (cmd *command) usage(fs *flag.FlagSet) {
+	out := fs.Output()
+	fmt.Fprintf(out, "Usage: flagsgui %s [flags] %s\n\n%s\n\nFlags:\n", cmd.name, cmd.args, cmd.summary)
+	fs.PrintDefaults()
+}
+
+func (c *cli) usage(out io.Writer) {
+	fmt.Fprintln(out, "Usage: flagsgui <command> [flags] [arguments]")
+	fmt.Fprintln(out, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-16s%s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(out, "\nWithout a command, flagsgui serves. Run \"flagsgui help <command>\" for a command's flags.")
+	fmt.Fprintf(out, "Settings come from %s (or --config), then %s* environment variables, then flags.\n", defaultConfigPath(), configEnvPrefix)
+}
+
+// serve runs the game server until it fails
+func serve(c *cli, cfg Config) error {
+	deps := newDependencies(cfg)
+	go deps.Rooms.pruneEvery(roomPruneInterval, deps.now)
+	if saved, err := deps.Snapshots.List(); err == nil && len(saved) > 0 {
+		fmt.Fprintf(c.out, "💾 %d unfinished game(s) can be resumed from the setup page\n", len(saved))
+	}
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, deps)
+	listener, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return err
+	}
+	url := browserURL(listener.Addr())
+
+	fmt.Fprintf(c.out, "Starting Flag Quiz Game server (with DI) on %s\n", url)
+	if !cfg.NoBrowser {
+		fmt.Fprintln(c.out, "Opening browser...")
+		go openBrowser(url)
+	}
+	fmt.Fprintln(c.out, "Press Ctrl+C to stop")
+	return http.Serve(listener, mux)
+}
+
+// browserURL is the address a local browser can reach a listener at
+func browserURL(addr net.Addr) string {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "http://" + addr.String()
+	}
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// validateFlags downloads the flag of every country the game can pick and
+// reports the ones that cannot be fetched or decoded
+func validateFlags(deps *Dependencies, workers int, out io.Writer) error {
+	countries := deps.CountryService.AllCountries()
+	failures := make([]error, len(countries))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				_, failures[i] = deps.ImageService.DownloadFlag(countries[i].FlagURL)
+			}
+		}()
+	}
+	for i := range countries {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	failed := 0
+	for i, err := range failures {
+		if err != nil {
+			failed++
+			fmt.Fprintf(out, "❌ %s: %v (%s)\n", countries[i].Name, err, countries[i].FlagURL)
+		}
+	}
+	fmt.Fprintf(out, "✅ %d of %d flags loaded\n", len(countries)-failed, len(countries))
+	if failed > 0 {
+		return fmt.Errorf("%d flags could not be loaded", failed)
+	}
+	return nil
+}
+
+// exportStats writes the logged guesses, like the stats page's export
+func exportStats(deps *Dependencies, format, player string, out io.Writer) error {
+	guesses, err := loadGuesses(deps)
+	if err != nil {
+		return err
+	}
+	guesses = filterGuesses(guesses, player)
+
+	switch format {
+	case "json":
+		if guesses == nil {
+			guesses = []Guess{}
+		}
+		return json.NewEncoder(out).Encode(guesses)
+	case "csv":
+		return writeGuessesCSV(out, guesses)
+	}
+	return fmt.Errorf("unknown export format %q, expected csv or json", format)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"image"
+	"image/png"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runTestCLI(args ...string) (int, string, string) {
+	var out, errOut strings.Builder
+	code := runCLI(args, &cli{in: strings.NewReader(""), out: &out, errOut: &errOut, getenv: envFrom(nil)})
+	return code, out.String(), errOut.String()
+}
+
+// Test_GIVEN_CommandLines_WHEN_RunningCLI_THEN_ExpectUsageAndExitCodes tests the help and argument errors of the CLI
+func Test_GIVEN_CommandLines_WHEN_RunningCLI_THEN_ExpectUsageAndExitCodes(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantCode int
+		wantOut  string
+		wantErr  string
+	}{
+		{[]string{"help"}, 0, "validate-flags", ""},
+		{[]string{"help", "serve"}, 0, "", "-no-browser"},
+		{[]string{"play", "-h"}, 0, "", "-players"},
+		{[]string{"Chad"}, 2, "", `unknown command "Chad"`},
+		{[]string{"debug-flag"}, 2, "", "Usage: flagsgui debug-flag [flags] <country>"},
+		{[]string{"serve", "--log-level", "loud"}, 2, "", "unknown log level"},
+		{[]string{"export-stats", "--seed", "x"}, 2, "", "not a whole number"},
+		{[]string{"serve", "--max-players", "0"}, 2, "", "max players must be at least 1"},
+	}
+	for _, test := range tests {
+		// Act
+		code, out, errOut := runTestCLI(test.args...)
+
+		// Assert
+		if code != test.wantCode || !strings.Contains(out, test.wantOut) || !strings.Contains(errOut, test.wantErr) {
+			t.Errorf("%v: expected %d with %q / %q, got %d:\n%s\n%s", test.args, test.wantCode, test.wantOut, test.wantErr, code, out, errOut)
+		}
+	}
+}
+
+func TestExportStatsCommand(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	output := filepath.Join(t.TempDir(), "guesses.csv")
+
+	code, _, errOut := runTestCLI("export-stats", "--output", output)
+
+	data, err := os.ReadFile(output)
+	if code != 0 || err != nil || !strings.HasPrefix(string(data), strings.Join(guessCSVHeader, ",")) {
+		t.Errorf("Expected an empty CSV export, got %d %v %q %s", code, err, data, errOut)
+	}
+	if code, _, _ := runTestCLI("export-stats", "--format", "xml"); code != 1 {
+		t.Errorf("Expected an unknown format to fail, got %d", code)
+	}
+}
+
+// Test_GIVEN_BrokenFlags_WHEN_ValidatingFlags_THEN_ExpectEveryFailureReported tests validate-flags
+func Test_GIVEN_BrokenFlags_WHEN_ValidatingFlags_THEN_ExpectEveryFailureReported(t *testing.T) {
+	// Arrange
+	deps := &Dependencies{
+		CountryService: &MockCountryService{countries: []CountryFlag{{Name: "Austria"}, {Name: "Chad"}}},
+		ImageService:   &MockImageService{downloadError: errors.New("404")},
+	}
+	var out strings.Builder
+
+	// Act
+	err := validateFlags(deps, 2, &out)
+
+	// Assert
+	if err == nil || !strings.Contains(out.String(), "❌ Austria: 404") || !strings.Contains(out.String(), "0 of 2 flags loaded") {
+		t.Errorf("Expected both flags to fail, got %v:\n%s", err, out.String())
+	}
+	deps.ImageService = &MockImageService{}
+	if err := validateFlags(deps, 2, &out); err != nil {
+		t.Errorf("Expected the flags to load, got %v", err)
+	}
+}
+
+// Test_GIVEN_FlagDirectory_WHEN_DownloadingThroughCache_THEN_ExpectFlagKept tests a local flag source with the cache
+func Test_GIVEN_FlagDirectory_WHEN_DownloadingThroughCache_THEN_ExpectFlagKept(t *testing.T) {
+	// Arrange
+	flags, cache := t.TempDir(), t.TempDir()
+	file, _ := os.Create(filepath.Join(flags, "Austria.png"))
+	png.Encode(file, image.NewRGBA(image.Rect(0, 0, 6, 4)))
+	file.Close()
+	countries := NewCountryService(flags)
+	austria := findCountry(countries.AllCountries(), "austria")
+	images := newCachingImageService(NewImageService(), cache)
+
+	// Act
+	img, err := images.DownloadFlag(austria.FlagURL)
+	os.Remove(filepath.Join(flags, "Austria.png"))
+	cached, cachedErr := images.DownloadFlag(austria.FlagURL)
+
+	// Assert
+	if !strings.HasPrefix(austria.FlagURL, "file://") || err != nil || img.Bounds().Dx() != 6 {
+		t.Fatalf("Expected the flag from the directory, got %s %v", austria.FlagURL, err)
+	}
+	if cachedErr != nil || cached.Bounds().Dy() != 4 {
+		t.Errorf("Expected the cached flag once the file is gone, got %v", cachedErr)
+	}
+}
+
+func TestBrowserURL(t *testing.T) {
+	tests := map[string]string{
+		"[::]:8080":      "http://localhost:8080",
+		"0.0.0.0:9000":   "http://localhost:9000",
+		"127.0.0.1:8081": "http://127.0.0.1:8081",
+	}
+	for addr, want := range tests {
+		tcp, _ := net.ResolveTCPAddr("tcp", addr)
+		if got := browserURL(tcp); got != want {
+			t.Errorf("browserURL(%s) = %s, expected %s", addr, got, want)
+		}
+	}
+}
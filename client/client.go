@@ -0,0 +1,240 @@
+// Package client plays flag quiz games through the server's JSON API. Its
+// types follow the OpenAPI document served at /api/openapi.json
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Game modes, as used in CreateGameRequest.Mode and Round.Mode
+const (
+	ModeTrueFalse = "truefalse"
+	ModeChoice    = "choice"
+	ModePickReal  = "pickreal"
+	ModeSpot      = "spot"
+)
+
+// Answers to a truefalse round
+const (
+	AnswerReal = "correct"
+	AnswerFake = "incorrect"
+)
+
+// CreateGameRequest sets up a game. Everything but the players is optional
+type CreateGameRequest struct {
+	Players     []string `json:"players"`
+	Rounds      int      `json:"rounds,omitempty"`
+	Mode        string   `json:"mode,omitempty"`
+	Difficulty  string   `json:"difficulty,omitempty"`
+	PickOptions int      `json:"pickOptions,omitempty"`
+	TimeLimit   int      `json:"timeLimit,omitempty"`
+	Lives       int      `json:"lives,omitempty"`
+	Selection   string   `json:"selection,omitempty"`
+	Exploration float64  `json:"exploration,omitempty"`
+	Regions     []string `json:"regions,omitempty"`
+	Seed        *int64   `json:"seed,omitempty"`
+}
+
+// GameSettings are the settings a game was created with, defaults filled in
+type GameSettings struct {
+	Mode        string   `json:"mode"`
+	Difficulty  string   `json:"difficulty"`
+	PickOptions int      `json:"pickOptions"`
+	TimeLimit   int      `json:"timeLimit"`
+	Lives       int      `json:"lives"`
+	Selection   string   `json:"selection,omitempty"`
+	Exploration float64  `json:"exploration,omitempty"`
+	Regions     []string `json:"regions,omitempty"`
+}
+
+// PlayerScore is one player's standing
+type PlayerScore struct {
+	Name       string `json:"name"`
+	Points     int    `json:"points"`
+	Correct    int    `json:"correct"`
+	Incorrect  int    `json:"incorrect"`
+	Streak     int    `json:"streak"`
+	BestStreak int    `json:"bestStreak"`
+	Lives      int    `json:"lives,omitempty"`
+	Eliminated bool   `json:"eliminated,omitempty"`
+}
+
+// Game is a game and its scores, best first
+type Game struct {
+	ID          string        `json:"id"`
+	Status      string        `json:"status"`
+	Settings    GameSettings  `json:"settings"`
+	Round       int           `json:"round"`
+	TotalRounds int           `json:"totalRounds"`
+	Seed        *int64        `json:"seed,omitempty"`
+	Scores      []PlayerScore `json:"scores"`
+	Replay      string        `json:"replay,omitempty"`
+}
+
+func (g Game) Finished() bool {
+	return g.Status == "finished"
+}
+
+// Round is the flag waiting for an answer
+type Round struct {
+	Round       int        `json:"round"`
+	TotalRounds int        `json:"totalRounds"`
+	Player      string     `json:"player"`
+	Mode        string     `json:"mode"`
+	Country     string     `json:"country,omitempty"`
+	FlagURL     string     `json:"flagUrl,omitempty"`
+	Choices     []string   `json:"choices,omitempty"`
+	Options     []string   `json:"options,omitempty"`
+	FlagWidth   int        `json:"flagWidth,omitempty"`
+	FlagHeight  int        `json:"flagHeight,omitempty"`
+	Deadline    *time.Time `json:"deadline,omitempty"`
+}
+
+// AnswerRequest is an answer to the current round
+type AnswerRequest struct {
+	Answer string `json:"answer"`
+}
+
+// AnswerResult is the revealed result of an answer
+type AnswerResult struct {
+	Correct           bool    `json:"correct"`
+	Expired           bool    `json:"expired"` // the answer came too late and was not counted
+	Points            int     `json:"points"`
+	Message           string  `json:"message"`
+	Country           string  `json:"country"`
+	Real              bool    `json:"real"`
+	TamperDescription string  `json:"tamperDescription,omitempty"`
+	DeltaE            float64 `json:"deltaE,omitempty"`
+	OriginalURL       string  `json:"originalUrl,omitempty"`
+	GameOver          bool    `json:"gameOver"`
+}
+
+// Error is a failed request as the API describes it
+type Error struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d %s)", e.Message, e.Status, e.Code)
+}
+
+// Client talks to one flag quiz server
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// New returns a client for the server at baseURL, such as
+// "http://localhost:8080"
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: http.DefaultClient}
+}
+
+func (c *Client) CreateGame(ctx context.Context, req CreateGameRequest) (*Game, error) {
+	var game Game
+	if err := c.do(ctx, http.MethodPost, "/api/v1/games", req, &game); err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+func (c *Client) Game(ctx context.Context, id string) (*Game, error) {
+	var game Game
+	if err := c.do(ctx, http.MethodGet, "/api/v1/games/"+url.PathEscape(id), nil, &game); err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+func (c *Client) Round(ctx context.Context, id string) (*Round, error) {
+	var round Round
+	if err := c.do(ctx, http.MethodGet, "/api/v1/games/"+url.PathEscape(id)+"/round", nil, &round); err != nil {
+		return nil, err
+	}
+	return &round, nil
+}
+
+func (c *Client) Answer(ctx context.Context, id, answer string) (*AnswerResult, error) {
+	var result AnswerResult
+	if err := c.do(ctx, http.MethodPost, "/api/v1/games/"+url.PathEscape(id)+"/answers", AnswerRequest{Answer: answer}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Image downloads one of the flag URLs the API hands out
+func (c *Client) Image(ctx context.Context, path string) (image.Image, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+	return png.Decode(resp.Body)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return responseError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// responseError turns a failed response into an *Error with its status,
+// taking the code and message from the API's error body when there is one
+func responseError(resp *http.Response) *Error {
+	var failure struct {
+		Error *Error `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&failure); err != nil || failure.Error == nil {
+		code := "unexpected_response"
+		if resp.StatusCode == http.StatusNotFound {
+			// image paths answer in plain text
+			code = "not_found"
+		}
+		return &Error{Status: resp.StatusCode, Code: code, Message: resp.Status}
+	}
+	failure.Error.Status = resp.StatusCode
+	return failure.Error
+}
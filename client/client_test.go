@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /flag/ok/shown.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		png.Encode(w, image.NewRGBA(image.Rect(0, 0, 6, 4)))
+	})
+	mux.HandleFunc("GET /flag/hidden/original.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error": {"code": "not_revealed", "message": "answer the round first"}}`))
+	})
+	mux.HandleFunc("GET /flag/broken/shown.png", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "disk on fire", http.StatusInternalServerError)
+	})
+	mux.HandleFunc("GET /api/v1/games/{id}/round", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error": {"code": "game_over", "message": "the game is over"}}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return New(server.URL)
+}
+
+// Test_GIVEN_ImagePaths_WHEN_FetchingImages_THEN_ExpectImageOrRealError tests that image errors keep the server's status and code
+func Test_GIVEN_ImagePaths_WHEN_FetchingImages_THEN_ExpectImageOrRealError(t *testing.T) {
+	// Arrange
+	client := newTestClient(t)
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"/flag/ok/shown.png", 0, ""},
+		{"/flag/hidden/original.png", http.StatusForbidden, "not_revealed"},
+		{"/flag/broken/shown.png", http.StatusInternalServerError, "unexpected_response"},
+		{"/flag/gone/shown.png", http.StatusNotFound, "not_found"},
+	}
+	for _, test := range tests {
+		// Act
+		img, err := client.Image(context.Background(), test.path)
+
+		// Assert
+		if test.wantCode == "" {
+			if err != nil || img.Bounds().Dx() != 6 {
+				t.Errorf("%s: expected the image, got %v", test.path, err)
+			}
+			continue
+		}
+		var apiErr *Error
+		if !errors.As(err, &apiErr) || apiErr.Status != test.wantStatus || apiErr.Code != test.wantCode {
+			t.Errorf("%s: expected %d %s, got %v", test.path, test.wantStatus, test.wantCode, err)
+		}
+	}
+}
+
+func TestAPIErrorsAreDecoded(t *testing.T) {
+	client := newTestClient(t)
+
+	_, err := client.Round(context.Background(), "abc")
+
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusConflict || apiErr.Code != "game_over" || apiErr.Message != "the game is over" {
+		t.Errorf("Expected the game_over error, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// configEnvPrefix starts the name of every environment variable the
+// settings can be given in, such as FLAGSGUI_ADDR
+const configEnvPrefix = "FLAGSGUI_"
+
+// Config holds the settings the commands start with. Defaults are
+// overridden by the config file, then by FLAGSGUI_* environment variables,
+// then by flags
+type Config struct {
+	Addr       string `json:"addr"`
+	NoBrowser  bool   `json:"noBrowser"`
+	FlagSource string `json:"flagSource"`
+	CacheDir   string `json:"cacheDir"`
+	Seed       *int64 `json:"seed,omitempty"`
+	LogLevel   string `json:"logLevel"`
+	MaxPlayers int    `json:"maxPlayers"`
+}
+
+func defaultConfig() Config {
+	return Config{Addr: ":8080", LogLevel: "info", MaxPlayers: defaultMaxPlayers}
+}
+
+func defaultConfigPath() string {
+	return filepath.Join(defaultDataDir(), "config.json")
+}
+
+// configPath finds the config file named with --config or FLAGSGUI_CONFIG.
+// The flag is looked for before the flags are parsed, because the file
+// supplies their defaults. explicit is false for the default path, which
+// does not have to exist
+func configPath(args []string, getenv func(string) string) (path string, explicit bool) {
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || name != "config" {
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			value = args[i+1]
+		}
+		return value, true
+	}
+	if path := getenv(configEnvPrefix + "CONFIG"); path != "" {
+		return path, true
+	}
+	return defaultConfigPath(), false
+}
+
+// loadConfig reads the config file and then the environment over cfg
+func loadConfig(cfg Config, path string, explicit bool, getenv func(string) string) (Config, error) {
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && !explicit:
+	case err != nil:
+		return cfg, err
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&cfg); err != nil {
+			return cfg, fmt.Errorf("config file %s: %w", path, err)
+		}
+	}
+
+	env := func(name string) (string, bool) {
+		value := getenv(configEnvPrefix + name)
+		return value, value != ""
+	}
+	if value, set := env("ADDR"); set {
+		cfg.Addr = value
+	}
+	if value, set := env("NO_BROWSER"); set {
+		if cfg.NoBrowser, err = strconv.ParseBool(value); err != nil {
+			return cfg, fmt.Errorf("%sNO_BROWSER: %q is not true or false", configEnvPrefix, value)
+		}
+	}
+	if value, set := env("FLAG_SOURCE"); set {
+		cfg.FlagSource = value
+	}
+	if value, set := env("CACHE_DIR"); set {
+		cfg.CacheDir = value
+	}
+	if value, set := env("SEED"); set {
+		if err := (seedValue{&cfg.Seed}).Set(value); err != nil {
+			return cfg, fmt.Errorf("%sSEED: %w", configEnvPrefix, err)
+		}
+	}
+	if value, set := env("LOG_LEVEL"); set {
+		cfg.LogLevel = value
+	}
+	if value, set := env("MAX_PLAYERS"); set {
+		if cfg.MaxPlayers, err = strconv.Atoi(value); err != nil {
+			return cfg, fmt.Errorf("%sMAX_PLAYERS: %q is not a whole number", configEnvPrefix, value)
+		}
+	}
+	return cfg, nil
+}
+
+// validate checks the settings that flags cannot check while parsing
+func (cfg Config) validate() error {
+	if _, err := parseLogLevel(cfg.LogLevel); err != nil {
+		return err
+	}
+	if cfg.MaxPlayers < 1 {
+		return fmt.Errorf("max players must be at least 1, got %d", cfg.MaxPlayers)
+	}
+	return nil
+}
+
+// bindFlags adds the settings as flags, the server's own ones only if
+// serving, with the loaded config as their defaults
+func (cfg *Config) bindFlags(fs *flag.FlagSet, serving bool) {
+	fs.String("config", defaultConfigPath(), "JSON config file (env "+configEnvPrefix+"CONFIG)")
+	if serving {
+		fs.StringVar(&cfg.Addr, "addr", cfg.Addr, "address to listen on (env "+configEnvPrefix+"ADDR)")
+		fs.BoolVar(&cfg.NoBrowser, "no-browser", cfg.NoBrowser, "don't open a browser on start (env "+configEnvPrefix+"NO_BROWSER)")
+	}
+	fs.StringVar(&cfg.FlagSource, "flag-source", cfg.FlagSource, "URL of the flags with {name} for the country, or a directory of {name}.png files, flagdownload.com if empty (env "+configEnvPrefix+"FLAG_SOURCE)")
+	fs.StringVar(&cfg.CacheDir, "cache-dir", cfg.CacheDir, "directory to keep downloaded flags in, none if empty (env "+configEnvPrefix+"CACHE_DIR)")
+	fs.Var(seedValue{&cfg.Seed}, "seed", "seed every new game for repeatable runs (env "+configEnvPrefix+"SEED)")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "debug, info, warn or error (env "+configEnvPrefix+"LOG_LEVEL)")
+	fs.IntVar(&cfg.MaxPlayers, "max-players", cfg.MaxPlayers, "most players or teams a game can have (env "+configEnvPrefix+"MAX_PLAYERS)")
+}
+
+// seedValue is an optional seed as a flag
+type seedValue struct {
+	seed **int64
+}
+
+func (v seedValue) String() string {
+	if v.seed == nil || *v.seed == nil {
+		return ""
+	}
+	return strconv.FormatInt(**v.seed, 10)
+}
+
+func (v seedValue) Set(value string) error {
+	seed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not a whole number", value)
+	}
+	*v.seed = &seed
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func envFrom(values map[string]string) func(string) string {
+	return func(name string) string { return values[name] }
+}
+
+// Test_GIVEN_FileEnvAndFlags_WHEN_LoadingConfig_THEN_ExpectLaterSourcesToWin tests the order settings are applied in
+func Test_GIVEN_FileEnvAndFlags_WHEN_LoadingConfig_THEN_ExpectLaterSourcesToWin(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"addr": ":9000", "cacheDir": "/tmp/flags", "seed": 4, "logLevel": "warn"}`), 0o644)
+	getenv := envFrom(map[string]string{"FLAGSGUI_ADDR": ":9100", "FLAGSGUI_NO_BROWSER": "true", "FLAGSGUI_MAX_PLAYERS": "6"})
+	args := []string{"--config", path, "--addr", ":9200"}
+
+	// Act
+	configFile, explicit := configPath(args, getenv)
+	cfg, err := loadConfig(defaultConfig(), configFile, explicit, getenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	cfg.bindFlags(fs, true)
+	err = fs.Parse(args)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != ":9200" || !cfg.NoBrowser || cfg.CacheDir != "/tmp/flags" || cfg.LogLevel != "warn" || cfg.MaxPlayers != 6 {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+	if cfg.Seed == nil || *cfg.Seed != 4 {
+		t.Errorf("Expected the seed from the file, got %v", cfg.Seed)
+	}
+}
+
+func TestConfigPath(t *testing.T) {
+	tests := []struct {
+		args         []string
+		env          map[string]string
+		want         string
+		wantExplicit bool
+	}{
+		{[]string{"-addr", ":1", "-config=a.json"}, nil, "a.json", true},
+		{[]string{"--config", "b.json", "Chad"}, nil, "b.json", true},
+		{[]string{"--", "--config", "c.json"}, map[string]string{"FLAGSGUI_CONFIG": "env.json"}, "env.json", true},
+		{nil, nil, defaultConfigPath(), false},
+	}
+	for _, test := range tests {
+		path, explicit := configPath(test.args, envFrom(test.env))
+		if path != test.want || explicit != test.wantExplicit {
+			t.Errorf("configPath(%v) = %q, %v, expected %q, %v", test.args, path, explicit, test.want, test.wantExplicit)
+		}
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	typo := filepath.Join(dir, "typo.json")
+	os.WriteFile(typo, []byte(`{"adress": ":9000"}`), 0o644)
+
+	tests := []struct {
+		name     string
+		path     string
+		explicit bool
+		env      map[string]string
+		wantErr  string
+	}{
+		{"missing default file", filepath.Join(dir, "none.json"), false, nil, ""},
+		{"missing explicit file", filepath.Join(dir, "none.json"), true, nil, "no such file"},
+		{"unknown setting", typo, true, nil, "adress"},
+		{"bad seed", filepath.Join(dir, "none.json"), false, map[string]string{"FLAGSGUI_SEED": "soon"}, "FLAGSGUI_SEED"},
+		{"bad bool", filepath.Join(dir, "none.json"), false, map[string]string{"FLAGSGUI_NO_BROWSER": "nah"}, "FLAGSGUI_NO_BROWSER"},
+		{"bad max players", filepath.Join(dir, "none.json"), false, map[string]string{"FLAGSGUI_MAX_PLAYERS": "many"}, "FLAGSGUI_MAX_PLAYERS"},
+	}
+	for _, test := range tests {
+		_, err := loadConfig(defaultConfig(), test.path, test.explicit, envFrom(test.env))
+		if test.wantErr == "" && err != nil || test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+			t.Errorf("%s: expected an error with %q, got %v", test.name, test.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	dailyRounds     = 10
+	dailyCookieName = "flagsgui_daily"
+	dailyDateFormat = "2006-01-02"
+)
+
+var errAlreadyPlayed = errors.New("you have already played today's challenge")
+
+// dailyDate is the challenge day a moment belongs to. Days follow UTC so
+// that everyone gets the same flags at the same time
+func dailyDate(now time.Time) string {
+	return now.UTC().Format(dailyDateFormat)
+}
+
+// dailySeed turns a challenge day into the seed every player's game uses
+func dailySeed(date string) int64 {
+	day, err := time.Parse(dailyDateFormat, date)
+	if err != nil {
+		return 0
+	}
+	return int64(day.Year()*10000 + int(day.Month())*100 + day.Day())
+}
+
+// DailyEntry is one player's finished daily challenge
+type DailyEntry struct {
+	Date     string    `json:"date"`
+	Player   string    `json:"player"`
+	Points   int       `json:"points"`
+	Correct  int       `json:"correct"`
+	Grid     []bool    `json:"grid"`
+	Finished time.Time `json:"finished"`
+}
+
+// dailyLine is one line of the daily file: an attempt as it is started,
+// then the entry once it is finished
+type dailyLine struct {
+	Date   string      `json:"date"`
+	Player string      `json:"player"` // the key attempts are counted by
+	Entry  *DailyEntry `json:"entry,omitempty"`
+}
+
+// dailyDeck deals the day's countries in an order drawn from the day's
+// seed before any flag is downloaded. Round n always gets the nth country,
+// and a flag that fails to download is replaced from the far end of the
+// deck, so a failed download never changes the later rounds
+type dailyDeck struct {
+	state     *GameState
+	countries []CountryFlag
+	round     int
+	spares    int
+}
+
+func newDailyDeck(state *GameState, countries []CountryFlag, seed int64) *dailyDeck {
+	deck := &dailyDeck{state: state}
+	for _, i := range rand.New(rand.NewSource(seed)).Perm(len(countries)) {
+		deck.countries = append(deck.countries, countries[i])
+	}
+	return deck
+}
+
+func (d *dailyDeck) Select(selection Selection) CountryFlag {
+	if len(d.countries) == 0 {
+		return selection.Countries[0]
+	}
+	if round := d.state.CurrentRound; round != d.round {
+		d.round = round
+		return d.countries[(round-1)%len(d.countries)]
+	}
+	// the same round asks again when its flag failed to download
+	d.spares++
+	return d.countries[len(d.countries)-1-(d.spares-1)%len(d.countries)]
+}
+
+// ShareText is the emoji summary players can paste into chat
+func (e DailyEntry) ShareText() string {
+	var grid strings.Builder
+	for _, correct := range e.Grid {
+		if correct {
+			grid.WriteString("🟩")
+		} else {
+			grid.WriteString("🟥")
+		}
+	}
+	return fmt.Sprintf("🏴 Flag Quiz Daily %s\n%d/%d - %d points\n%s", e.Date, e.Correct, len(e.Grid), e.Points, grid.String())
+}
+
+// dailyRun is a player's game of one day's challenge
+type dailyRun struct {
+	mu    sync.Mutex
+	date  string
+	key   string
+	deps  *Dependencies
+	grid  []bool
+	entry *DailyEntry
+}
+
+// DailyBoard hands out the daily challenge and keeps its leaderboards.
+// Attempts and results are appended to a JSON lines file, so a restart
+// neither hands out second attempts nor forgets the day's scores
+type DailyBoard struct {
+	mu      sync.Mutex
+	path    string
+	loaded  bool
+	started map[string]map[string]bool
+	entries map[string][]DailyEntry
+	runs    map[string]*dailyRun
+}
+
+// NewDailyBoard keeps the challenge in the file at path, or only in memory
+// if path is empty
+func NewDailyBoard(path string) *DailyBoard {
+	return &DailyBoard{
+		path:    path,
+		started: make(map[string]map[string]bool),
+		entries: make(map[string][]DailyEntry),
+		runs:    make(map[string]*dailyRun),
+	}
+}
+
+// dailyPlayerKey is who an attempt belongs to: the player's profile, or
+// their name if they have none
+func dailyPlayerKey(player Player) string {
+	if player.ProfileID != "" {
+		return "profile:" + player.ProfileID
+	}
+	return profileKey(player.Name)
+}
+
+// load reads the daily file the first time the board is used. The caller
+// must hold b.mu
+func (b *DailyBoard) load() {
+	if b.loaded || b.path == "" {
+		return
+	}
+	b.loaded = true
+	lines, err := readJSONLines[dailyLine](b.path)
+	if err != nil {
+		warnf("⚠️  Could not load the daily challenge: %v", err)
+		return
+	}
+	for _, line := range lines {
+		b.markStarted(line.Date, line.Player)
+		if line.Entry != nil {
+			b.entries[line.Date] = append(b.entries[line.Date], *line.Entry)
+		}
+	}
+}
+
+// markStarted records an attempt in memory. The caller must hold b.mu
+func (b *DailyBoard) markStarted(date, key string) {
+	if b.started[date] == nil {
+		b.started[date] = make(map[string]bool)
+	}
+	b.started[date][key] = true
+}
+
+// save appends a line to the daily file. A file that cannot be written to
+// must not stop anyone from playing
+func (b *DailyBoard) save(line dailyLine) {
+	if b.path == "" {
+		return
+	}
+	if err := appendJSONLines(b.path, line); err != nil {
+		warnf("⚠️  Could not save the daily challenge: %v", err)
+	}
+}
+
+// Start begins today's challenge for a player and returns the token of
+// their run. Each player gets one attempt per day
+func (b *DailyBoard) Start(deps *Dependencies, name string) (string, error) {
+	name = normalizeDisplayName(name)
+	if name == "" {
+		return "", errNameRequired
+	}
+	date := dailyDate(deps.now())
+	player := Player{Name: name}
+	linkProfile(deps, &player)
+	key := dailyPlayerKey(player)
+
+	b.mu.Lock()
+	b.load()
+	if b.started[date][key] {
+		b.mu.Unlock()
+		return "", errAlreadyPlayed
+	}
+	b.markStarted(date, key)
+	b.pruneRuns(date)
+	b.mu.Unlock()
+
+	runDeps := *deps
+	runDeps.GameState = &GameState{}
+	runDeps.Events = nil
+	runDeps.Images = deps.Images.ForGame()
+	runDeps.Snapshots = nil
+	state := runDeps.GameState
+	initializeGameState(state, []Player{player}, dailyRounds, GameSettings{Mode: ModeTrueFalse, Difficulty: DifficultyNormal})
+	state.GameID = "daily-" + date
+	state.SeedRandom(dailySeed(date))
+	state.selector = newDailyDeck(state, deps.CountryService.AllCountries(), dailySeed(date))
+
+	run := &dailyRun{date: date, key: key, deps: &runDeps}
+	if err := startRound(run.deps); err != nil {
+		// the player never saw a flag, so they keep their attempt
+		b.mu.Lock()
+		delete(b.started[date], key)
+		b.mu.Unlock()
+		runDeps.Images.Close()
+		return "", err
+	}
+
+	token := newToken()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.save(dailyLine{Date: date, Player: key})
+	b.runs[token] = run
+	return token, nil
+}
+
+// pruneRuns forgets the runs of earlier days. The caller must hold b.mu
+func (b *DailyBoard) pruneRuns(today string) {
+	for token, run := range b.runs {
+		if run.date != today {
+			delete(b.runs, token)
+			run.deps.Images.Close()
+		}
+	}
+	for date := range b.started {
+		if date != today {
+			delete(b.started, date)
+		}
+	}
+}
+
+func (b *DailyBoard) Run(token string) *dailyRun {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.runs[token]
+}
+
+// Leaderboard returns the day's results, best first
+func (b *DailyBoard) Leaderboard(date string) []DailyEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.load()
+
+	entries := append([]DailyEntry(nil), b.entries[date]...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Points != entries[j].Points {
+			return entries[i].Points > entries[j].Points
+		}
+		if entries[i].Correct != entries[j].Correct {
+			return entries[i].Correct > entries[j].Correct
+		}
+		return entries[i].Finished.Before(entries[j].Finished)
+	})
+	return entries
+}
+
+func (b *DailyBoard) submit(key string, entry DailyEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.load()
+	b.entries[entry.Date] = append(b.entries[entry.Date], entry)
+	b.save(dailyLine{Date: entry.Date, Player: key, Entry: &entry})
+}
+
+// answer scores the player's guess for the current flag
+func (run *dailyRun) answer(answer string) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	state := run.deps.GameState
+	if state.ShowResult || state.GameOver {
+		return
+	}
+	userCorrect := evaluateGuess(answer, state.IsCorrect)
+	player := &state.Players[0]
+	finishRound(run.deps, player, answer, fullCredit(userCorrect), generateResultMessage(player.Name, userCorrect, state.IsCorrect, state.CountryName))
+	run.grid = append(run.grid, userCorrect)
+}
+
+// next moves on to the next flag, or hands in the result after the last one
+func (run *dailyRun) next(board *DailyBoard) error {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	state := run.deps.GameState
+	if !state.ShowResult || state.GameOver {
+		return nil
+	}
+	if state.CurrentRound >= state.TotalRounds {
+		finishGame(run.deps)
+		player := state.Players[0]
+		run.entry = &DailyEntry{
+			Date:     run.date,
+			Player:   player.Name,
+			Points:   player.Points,
+			Correct:  player.Correct,
+			Grid:     run.grid,
+			Finished: run.deps.now(),
+		}
+		board.submit(run.key, *run.entry)
+		return nil
+	}
+	state.CurrentRound++
+	return startRound(run.deps)
+}
+
+// dailyView is what the daily challenge page shows
+type dailyView struct {
+	Date    string
+	Entries []DailyEntry
+	Mine    *DailyEntry
+	Name    string
+	Error   string
+}
+
+func dailyRunFromCookie(deps *Dependencies, r *http.Request) *dailyRun {
+	return deps.Daily.Run(cookieValue(r, dailyCookieName))
+}
+
+// dailyHandler shows today's leaderboard, the player's own result if they
+// have finished, and the form to take part
+func dailyHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		date := dailyDate(deps.now())
+		view := dailyView{Date: date, Name: r.FormValue("name")}
+
+		if r.Method == "POST" {
+			token, err := deps.Daily.Start(deps, view.Name)
+			if err == nil {
+				setRoomCookie(w, dailyCookieName, token)
+				http.Redirect(w, r, "/daily/play", http.StatusSeeOther)
+				return
+			}
+			view.Error = err.Error()
+		}
+
+		if run := dailyRunFromCookie(deps, r); run != nil {
+			run.mu.Lock()
+			view.Mine = run.entry
+			run.mu.Unlock()
+			if view.Mine == nil && run.date == date && r.Method != "POST" {
+				http.Redirect(w, r, "/daily/play", http.StatusSeeOther)
+				return
+			}
+		}
+		view.Entries = deps.Daily.Leaderboard(date)
+		renderPage(w, dailyTemplate, view)
+	}
+}
+
+// dailyPlayHandler shows the current flag of the player's run
+func dailyPlayHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		run := dailyRunFromCookie(deps, r)
+		if run == nil {
+			http.Redirect(w, r, "/daily", http.StatusSeeOther)
+			return
+		}
+
+		run.mu.Lock()
+		defer run.mu.Unlock()
+		if run.entry != nil {
+			http.Redirect(w, r, "/daily", http.StatusSeeOther)
+			return
+		}
+		renderPage(w, dailyPlayTemplate, run.deps.GameState)
+	}
+}
+
+func dailyGuessHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if run := dailyRunFromCookie(deps, r); run != nil {
+			run.answer(r.FormValue("answer"))
+		}
+		http.Redirect(w, r, "/daily/play", http.StatusSeeOther)
+	}
+}
+
+func dailyNextHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if run := dailyRunFromCookie(deps, r); run != nil {
+			if err := run.next(deps.Daily); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		http.Redirect(w, r, "/daily/play", http.StatusSeeOther)
+	}
+}
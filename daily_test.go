@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newDailyTestDeps(now time.Time) *Dependencies {
+	var countries []CountryFlag
+	for i := 0; i < 30; i++ {
+		countries = append(countries, CountryFlag{Name: fmt.Sprintf("Country %02d", i), FlagURL: fmt.Sprintf("flag-%02d", i)})
+	}
+	return &Dependencies{
+		GameState:      &GameState{},
+		CountryService: &MockCountryService{countries: countries},
+		ImageService:   &MockImageService{},
+		Images:         NewImageStore(64),
+		Now:            func() time.Time { return now },
+		Daily:          NewDailyBoard(""),
+	}
+}
+
+// playDaily answers every flag of a run with the given answer and returns
+// the flags that were shown
+func playDaily(t *testing.T, deps *Dependencies, name, answer string) []string {
+	t.Helper()
+	token, err := deps.Daily.Start(deps, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := deps.Daily.Run(token)
+
+	var flags []string
+	for run.entry == nil {
+		state := run.deps.GameState
+		flags = append(flags, fmt.Sprintf("%s/%v", state.CountryName, state.IsCorrect))
+		run.answer(answer)
+		if err := run.next(deps.Daily); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return flags
+}
+
+// Test_GIVEN_SameDay_WHEN_TwoPlayersPlayTheDaily_THEN_ExpectTheSameFlags tests the date-seeded generator
+func Test_GIVEN_SameDay_WHEN_TwoPlayersPlayTheDaily_THEN_ExpectTheSameFlags(t *testing.T) {
+	// Arrange
+	deps := newDailyTestDeps(time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC))
+
+	// Act
+	anna := playDaily(t, deps, "Anna", "correct")
+	ben := playDaily(t, deps, "Ben", "incorrect")
+
+	// Assert
+	if len(anna) != dailyRounds {
+		t.Fatalf("Expected %d flags, got %d", dailyRounds, len(anna))
+	}
+	if strings.Join(anna, ",") != strings.Join(ben, ",") {
+		t.Errorf("Expected both players to get the same flags\n%v\n%v", anna, ben)
+	}
+	entries := deps.Daily.Leaderboard("2025-03-14")
+	if len(entries) != 2 || entries[0].Correct+entries[1].Correct != dailyRounds {
+		t.Errorf("Expected two entries sharing every flag between them, got %+v", entries)
+	}
+}
+
+// flakyImageService fails the first download of the given flag
+type flakyImageService struct {
+	MockImageService
+	failing string
+}
+
+func (f *flakyImageService) DownloadFlag(url string) (image.Image, error) {
+	if url == f.failing {
+		f.failing = ""
+		return nil, errors.New("timeout")
+	}
+	return f.MockImageService.DownloadFlag(url)
+}
+
+// Test_GIVEN_FailedDownload_WHEN_PlayingTheDaily_THEN_ExpectOnlyThatFlagReplaced tests that retries do not shift the day's flags
+func Test_GIVEN_FailedDownload_WHEN_PlayingTheDaily_THEN_ExpectOnlyThatFlagReplaced(t *testing.T) {
+	// Arrange
+	deps := newDailyTestDeps(time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC))
+	anna := playDaily(t, deps, "Anna", "correct")
+	third := findCountry(deps.CountryService.AllCountries(), strings.Split(anna[2], "/")[0])
+	deps.ImageService = &flakyImageService{failing: third.FlagURL}
+
+	// Act
+	ben := playDaily(t, deps, "Ben", "correct")
+
+	// Assert
+	for i := range anna {
+		if i == 2 {
+			if ben[i] == anna[i] {
+				t.Errorf("Expected the flag that failed to be replaced, got %s", ben[i])
+			}
+			continue
+		}
+		if ben[i] != anna[i] {
+			t.Errorf("Round %d: expected %s, got %s", i+1, anna[i], ben[i])
+		}
+	}
+}
+
+// Test_GIVEN_Restart_WHEN_StartingTheDailyAgain_THEN_ExpectAttemptAndScoresKept tests the daily file
+func Test_GIVEN_Restart_WHEN_StartingTheDailyAgain_THEN_ExpectAttemptAndScoresKept(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "daily.jsonl")
+	deps := newDailyTestDeps(time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC))
+	deps.Daily = NewDailyBoard(path)
+	playDaily(t, deps, "Anna", "correct")
+
+	// Act
+	deps.Daily = NewDailyBoard(path)
+	_, err := deps.Daily.Start(deps, "ANNA")
+
+	// Assert
+	if err != errAlreadyPlayed {
+		t.Errorf("Expected errAlreadyPlayed after a restart, got %v", err)
+	}
+	if entries := deps.Daily.Leaderboard("2025-03-14"); len(entries) != 1 || entries[0].Player != "Anna" || len(entries[0].Grid) != dailyRounds {
+		t.Errorf("Expected Anna's result to be kept, got %+v", entries)
+	}
+}
+
+func TestDailyOneAttemptPerDay(t *testing.T) {
+	deps := newDailyTestDeps(time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC))
+	if _, err := deps.Daily.Start(deps, "Anna"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := deps.Daily.Start(deps, " anna "); err != errAlreadyPlayed {
+		t.Errorf("Expected errAlreadyPlayed, got %v", err)
+	}
+}
+
+func TestDailyShareText(t *testing.T) {
+	entry := DailyEntry{Date: "2025-03-14", Points: 300, Correct: 2, Grid: []bool{true, false, true}}
+
+	text := entry.ShareText()
+
+	if !strings.Contains(text, "2/3 - 300 points") || !strings.HasSuffix(text, "🟩🟥🟩") {
+		t.Errorf("Unexpected share text %q", text)
+	}
+}
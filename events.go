@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Types of events streamed to connected browsers
+const (
+	EventPlayerJoined    = "player-joined"
+	EventPlayerReady     = "player-ready"
+	EventPlayerKicked    = "player-kicked"
+	EventRoundStarted    = "round-started"
+	EventAnswerSubmitted = "answer-submitted"
+	EventResultRevealed  = "result-revealed"
+	EventGameOver        = "game-over"
+	EventChat            = "chat"
+)
+
+const (
+	eventBufferSize   = 16
+	eventHeartbeat    = 15 * time.Second
+	eventRetryMillis  = 2000
+	eventStreamFormat = "id: %d\nevent: %s\ndata: %s\n\n"
+)
+
+// EventScore is a player's standing at the time of an event
+type EventScore struct {
+	Name      string `json:"name"`
+	Points    int    `json:"points"`
+	Correct   int    `json:"correct"`
+	Incorrect int    `json:"incorrect"`
+	Streak    int    `json:"streak"`
+	Lives     int    `json:"lives,omitempty"`
+	Out       bool   `json:"out,omitempty"`
+}
+
+// GameEvent tells connected browsers that something visible has changed.
+// It never carries the answer of a round that is still open
+type GameEvent struct {
+	Type    string       `json:"type"`
+	Version int          `json:"version"`
+	Round   int          `json:"round"`
+	Player  string       `json:"player,omitempty"`
+	Text    string       `json:"text,omitempty"`
+	Scores  []EventScore `json:"scores,omitempty"`
+}
+
+// EventBus fans a game's events out to every subscribed stream
+type EventBus struct {
+	mu          sync.Mutex
+	subscribers map[chan GameEvent]struct{}
+	last        *GameEvent
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[chan GameEvent]struct{})}
+}
+
+// Subscribe returns a channel of events and a function that stops them. The
+// latest event is sent straight away, so a page that subscribes late can
+// still tell it has missed something
+func (b *EventBus) Subscribe() (<-chan GameEvent, func()) {
+	ch := make(chan GameEvent, eventBufferSize)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[ch] = struct{}{}
+	if b.last != nil {
+		ch <- *b.last
+	}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, ch)
+	}
+}
+
+// Publish sends an event to every subscriber. A subscriber that has fallen
+// behind misses the event rather than holding up the game
+func (b *EventBus) Publish(event GameEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if event.Type != EventChat {
+		// chat does not change the game, so late subscribers do not need it
+		b.last = &event
+	}
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// publishEvent bumps the state's version and tells the game's subscribers
+func publishEvent(deps *Dependencies, eventType, player string) {
+	state := deps.GameState
+	state.Version++
+	if deps.Events == nil {
+		return
+	}
+
+	event := GameEvent{
+		Type:    eventType,
+		Version: state.Version,
+		Round:   state.CurrentRound,
+		Player:  player,
+		Scores:  make([]EventScore, len(state.Players)),
+	}
+	for i, p := range state.Players {
+		event.Scores[i] = EventScore{
+			Name:      p.Name,
+			Points:    p.Points,
+			Correct:   p.Correct,
+			Incorrect: p.Incorrect,
+			Streak:    p.Streak,
+			Lives:     p.Lives,
+			Out:       p.Eliminated,
+		}
+	}
+	deps.Events.Publish(event)
+}
+
+// publishChat passes a chat message to the game's subscribers without
+// changing the state's version
+func publishChat(deps *Dependencies, from, text string) {
+	if deps.Events == nil {
+		return
+	}
+	deps.Events.Publish(GameEvent{
+		Type:    EventChat,
+		Version: deps.GameState.Version,
+		Round:   deps.GameState.CurrentRound,
+		Player:  from,
+		Text:    text,
+	})
+}
+
+// streamEvents writes the bus's events as Server-Sent Events until the
+// client goes away
+func streamEvents(w http.ResponseWriter, r *http.Request, bus *EventBus) {
+	flusher, ok := w.(http.Flusher)
+	if !ok || bus == nil {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, cancel := bus.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	fmt.Fprintf(w, "retry: %d\n\n", eventRetryMillis)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			// comments keep proxies from closing an idle stream
+			fmt.Fprint(w, ": ping\n\n")
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, eventStreamFormat, event.Version, event.Type, data)
+		}
+		flusher.Flush()
+	}
+}
+
+// eventsHandler streams the events of the local game
+func eventsHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		streamEvents(w, r, deps.Events)
+	}
+}
+
+// roomEventsHandler streams the events of a party room
+func roomEventsHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		room := deps.Rooms.Get(r.PathValue("code"))
+		if room == nil {
+			http.NotFound(w, r)
+			return
+		}
+		streamEvents(w, r, room.deps.Events)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEventBusSendsLatestEventToLateSubscribers(t *testing.T) {
+	bus := NewEventBus()
+	bus.Publish(GameEvent{Type: EventRoundStarted, Version: 1})
+	bus.Publish(GameEvent{Type: EventResultRevealed, Version: 2})
+
+	events, cancel := bus.Subscribe()
+	defer cancel()
+
+	if event := <-events; event.Version != 2 {
+		t.Errorf("Expected the latest event first, got version %d", event.Version)
+	}
+}
+
+// Test_GIVEN_ConnectedStream_WHEN_AnsweringARound_THEN_ExpectEventsStreamed tests the SSE endpoint end to end
+func Test_GIVEN_ConnectedStream_WHEN_AnsweringARound_THEN_ExpectEventsStreamed(t *testing.T) {
+	// Arrange
+	gameState := &GameState{
+		Players:     []Player{{Name: "Anna"}},
+		CountryName: "TestCountry",
+		IsCorrect:   true,
+	}
+	deps := &Dependencies{
+		GameState: gameState,
+		Images:    NewImageStore(8),
+		Events:    NewEventBus(),
+	}
+	server := httptest.NewServer(eventsHandler(deps))
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %q", contentType)
+	}
+
+	// Act
+	guessHandler(deps).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/guess?answer=correct", nil))
+
+	// Assert
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	var types []string
+	timeout := time.After(2 * time.Second)
+	for len(types) < 2 {
+		select {
+		case line := <-lines:
+			if eventType, found := strings.CutPrefix(line, "event: "); found {
+				types = append(types, eventType)
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for events, got %v", types)
+		}
+	}
+	if types[0] != EventAnswerSubmitted || types[1] != EventResultRevealed {
+		t.Errorf("Expected answer-submitted then result-revealed, got %v", types)
+	}
+	if gameState.Version != 2 {
+		t.Errorf("Expected the state version to be 2, got %d", gameState.Version)
+	}
+}
+
+// Test_GIVEN_ConcurrentRequests_WHEN_AnsweringLocalGame_THEN_ExpectEveryEventVersioned tests the local game's lock, run it with -race
+func Test_GIVEN_ConcurrentRequests_WHEN_AnsweringLocalGame_THEN_ExpectEveryEventVersioned(t *testing.T) {
+	// Arrange
+	deps := &Dependencies{
+		GameState: &GameState{Players: []Player{{Name: "Anna"}}, CountryName: "TestCountry"},
+		Images:    NewImageStore(8),
+		Events:    NewEventBus(),
+		GameLock:  &sync.Mutex{},
+	}
+	guess := withGameLock(deps, guessHandler(deps))
+
+	// Act
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			guess.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/guess?answer=correct", nil))
+		}()
+	}
+	wg.Wait()
+
+	// Assert
+	if version := deps.GameState.Version; version != 2 {
+		t.Errorf("Expected only the first guess to count, got version %d", version)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"image"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// galleryDifficulties and galleryModes are the rows and columns of the
+// tamper gallery
+var (
+	galleryDifficulties = []Difficulty{DifficultyEasy, DifficultyNormal, DifficultyHard}
+	galleryModes        = []string{TamperDrastic, TamperShade}
+)
+
+// TamperVariant is one tampered version of a flag in the gallery
+type TamperVariant struct {
+	Mode        string
+	PNG         []byte
+	Description string
+	DeltaE      float64
+	Affected    float64 // share of the flag's pixels that changed, in percent
+	Dealt       bool    // whether games at this difficulty can deal it
+}
+
+// galleryRow holds the variants of one difficulty
+type galleryRow struct {
+	Difficulty Difficulty
+	Variants   []TamperVariant
+}
+
+// tamperGallery is what the /debug/flags page shows
+type tamperGallery struct {
+	Countries []CountryFlag
+	Country   string
+	Seed      int64
+	Original  []byte
+	Rows      []galleryRow
+	Error     string
+}
+
+func (g tamperGallery) NextSeed() int64 {
+	return g.Seed + 1
+}
+
+// newGalleryRows tampers img in every mode at every difficulty. Each
+// variant starts from a generator seeded with seed, so they change the
+// same color and differ only in how
+func newGalleryRows(img image.Image, seed int64) ([]galleryRow, error) {
+	bounds := img.Bounds()
+	totalPixels := bounds.Dx() * bounds.Dy()
+
+	var rows []galleryRow
+	for _, difficulty := range galleryDifficulties {
+		row := galleryRow{Difficulty: difficulty}
+		for _, mode := range galleryModes {
+			tamper := tamperFlagAs(img, rand.New(rand.NewSource(seed)), difficulty, mode)
+			data, err := encodePNG(tamper.Image)
+			if err != nil {
+				return nil, err
+			}
+			variant := TamperVariant{
+				Mode:        mode,
+				PNG:         data,
+				Description: tamper.Description(),
+				DeltaE:      tamper.DeltaE,
+				Dealt:       slices.Contains(tamperModesAt(difficulty), mode),
+			}
+			if totalPixels > 0 {
+				variant.Affected = float64(tamper.ModifiedPixels) / float64(totalPixels) * 100
+			}
+			row.Variants = append(row.Variants, variant)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// debugFlagsHandler shows a country's flag next to every way it can be
+// tampered with, for tuning the color changes. The country defaults to the
+// one debug-flag was started with
+func debugFlagsHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		view := tamperGallery{Countries: deps.CountryService.AllCountries(), Country: debugCountry, Seed: 1}
+		if country := r.FormValue("country"); country != "" {
+			view.Country = country
+		}
+		if seed, err := strconv.ParseInt(r.FormValue("seed"), 10, 64); err == nil {
+			view.Seed = seed
+		}
+		if view.Country == "" {
+			renderPage(w, debugFlagsTemplate, view)
+			return
+		}
+
+		country := findCountry(view.Countries, view.Country)
+		if country.Name == "" {
+			w.WriteHeader(http.StatusNotFound)
+			view.Error = "There is no country called " + view.Country
+			renderPage(w, debugFlagsTemplate, view)
+			return
+		}
+		view.Country = country.Name
+
+		img, err := deps.ImageService.DownloadFlag(country.FlagURL)
+		if err == nil {
+			view.Original, err = encodePNG(img)
+		}
+		if err == nil {
+			view.Rows, err = newGalleryRows(img, view.Seed)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			view.Error = "Could not load the flag of " + country.Name + ": " + err.Error()
+		}
+		renderPage(w, debugFlagsTemplate, view)
+	}
+}
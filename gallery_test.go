@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newStripedFlag() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 6, 4))
+	stripes := []color.RGBA{{200, 30, 40, 255}, {40, 90, 180, 255}}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 6; x++ {
+			img.Set(x, y, stripes[y/2])
+		}
+	}
+	return img
+}
+
+// Test_GIVEN_Seed_WHEN_BuildingGallery_THEN_ExpectOneColorChangedEveryWay tests the gallery's grid of variants
+func Test_GIVEN_Seed_WHEN_BuildingGallery_THEN_ExpectOneColorChangedEveryWay(t *testing.T) {
+	// Arrange
+	img := newStripedFlag()
+	from := tamperFlagWith(img, rand.New(rand.NewSource(7)), DifficultyNormal).From
+	changed := fmt.Sprintf("rgb(%d, %d, %d) →", from.R, from.G, from.B)
+
+	// Act
+	rows, err := newGalleryRows(img, 7)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(galleryDifficulties) {
+		t.Fatalf("Expected a row per difficulty, got %d", len(rows))
+	}
+	for _, row := range rows {
+		for _, variant := range row.Variants {
+			if variant.Affected != 50 || len(variant.PNG) == 0 {
+				t.Errorf("%s %s: expected half the pixels changed, got %.1f%%", row.Difficulty, variant.Mode, variant.Affected)
+			}
+			if !strings.Contains(variant.Description, changed) {
+				t.Errorf("%s %s: expected the same color changed, got %q", row.Difficulty, variant.Mode, variant.Description)
+			}
+			wantDealt := row.Difficulty == DifficultyNormal ||
+				row.Difficulty == DifficultyEasy && variant.Mode == TamperDrastic ||
+				row.Difficulty == DifficultyHard && variant.Mode == TamperShade
+			if variant.Dealt != wantDealt {
+				t.Errorf("%s %s: expected dealt to be %v", row.Difficulty, variant.Mode, wantDealt)
+			}
+		}
+	}
+	if hard, normal := rows[2].Variants[1].DeltaE, rows[1].Variants[1].DeltaE; hard >= normal {
+		t.Errorf("Expected hard shades to be subtler than normal ones, got ΔE %.1f and %.1f", hard, normal)
+	}
+}
+
+// Test_GIVEN_Country_WHEN_OpeningDebugFlags_THEN_ExpectOriginalAndVariants tests the /debug/flags page
+func Test_GIVEN_Country_WHEN_OpeningDebugFlags_THEN_ExpectOriginalAndVariants(t *testing.T) {
+	// Arrange
+	deps := &Dependencies{
+		CountryService: &MockCountryService{countries: []CountryFlag{{Name: "Austria"}, {Name: "Chad"}}},
+		ImageService:   &MockImageService{},
+	}
+
+	tests := []struct {
+		query      string
+		wantStatus int
+		want       []string
+	}{
+		{"", 200, []string{`<option value="Chad">`}},
+		{"?country=austria&seed=3", 200, []string{"Original", "never dealt at this difficulty", "of pixels changed", "seed=4"}},
+		{"?country=Atlantis", 404, []string{"There is no country called Atlantis"}},
+	}
+	for _, test := range tests {
+		// Act
+		response := httptest.NewRecorder()
+		debugFlagsHandler(deps).ServeHTTP(response, httptest.NewRequest("GET", "/debug/flags"+test.query, nil))
+
+		// Assert
+		body := response.Body.String()
+		if response.Code != test.wantStatus {
+			t.Errorf("%q: expected %d, got %d", test.query, test.wantStatus, response.Code)
+		}
+		for _, want := range test.want {
+			if !strings.Contains(body, want) {
+				t.Errorf("%q: expected %q in the page", test.query, want)
+			}
+		}
+	}
+
+	response := httptest.NewRecorder()
+	debugFlagsHandler(deps).ServeHTTP(response, httptest.NewRequest("GET", "/debug/flags?country=Austria", nil))
+	if variants := strings.Count(response.Body.String(), "variant\" class=\"flag-thumbnail\""); variants != len(galleryDifficulties)*len(galleryModes) {
+		t.Errorf("Expected a variant for every mode and difficulty, got %d", variants)
+	}
+}
+
+func TestDebugFlagsOnlyInDebugMode(t *testing.T) {
+	defer func(country string) { debugCountry = country }(debugCountry)
+	for _, country := range []string{"", "Chad"} {
+		debugCountry = country
+		mux := http.NewServeMux()
+		registerRoutes(mux, &Dependencies{})
+
+		_, pattern := mux.Handler(httptest.NewRequest("GET", "/debug/flags", nil))
+
+		if registered := pattern == "GET /debug/flags"; registered != (country != "") {
+			t.Errorf("debug country %q: expected the gallery registered to be %v, got pattern %q", country, country != "", pattern)
+		}
+	}
+}
@@ -1,14 +1,18 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"html/template"
 	"image"
-	"log"
+	"math"
 	"math/rand"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -16,16 +20,99 @@ type Dependencies struct {
 	GameState      *GameState
 	CountryService CountryService
 	ImageService   ImageService
+	Images         *ImageStore
+	Palettes       *PaletteIndex
+	Now            func() time.Time
+	MaxPlayers     int
+	Rooms          *RoomManager
+	Events         *EventBus
+	Daily          *DailyBoard
+	Practice       *Practice
+	Games          GameStore
+	Profiles       *ProfileStore
+	Guesses        GuessStore
+	Replays        ReplayStore
+	Snapshots      *SnapshotStore
+	API            *APIGames
+	Seed           *int64      // seeds every new game when set, for repeatable runs
+	GameLock       *sync.Mutex // guards GameState while a local game handler runs
+}
+
+// lockGame takes the local game's lock and returns the function that
+// releases it. Without a lock it does nothing
+func (deps *Dependencies) lockGame() (unlock func()) {
+	if deps.GameLock == nil {
+		return func() {}
+	}
+	deps.GameLock.Lock()
+	return deps.GameLock.Unlock
+}
+
+// withGameLock runs a handler of the local game with its lock held, so
+// concurrent requests never see or publish a half-updated state. The
+// response is buffered and only sent once the lock is released, so a slow
+// client does not hold up everyone else
+func withGameLock(deps *Dependencies, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := &bufferedResponse{header: make(http.Header)}
+		unlock := deps.lockGame()
+		handler(response, r)
+		unlock()
+		response.WriteTo(w)
+	}
+}
+
+// bufferedResponse keeps a handler's whole response in memory
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+// WriteHeader keeps the first status written, as a real connection would
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedResponse) Write(data []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(data)
+}
+
+// WriteTo sends the buffered response to the client
+func (b *bufferedResponse) WriteTo(w http.ResponseWriter) {
+	for key, values := range b.header {
+		w.Header()[key] = values
+	}
+	b.WriteHeader(http.StatusOK)
+	w.WriteHeader(b.status)
+	b.body.WriteTo(w)
+}
+
+// now returns the current time from the injected clock, if any
+func (deps *Dependencies) now() time.Time {
+	if deps.Now != nil {
+		return deps.Now()
+	}
+	return time.Now()
 }
 
 type CountryService interface {
 	GetRandomCountry() CountryFlag
+	AllCountries() []CountryFlag
+	SimilarCountries(country CountryFlag, n int, rng *rand.Rand) []string
 }
 
 type ImageService interface {
 	DownloadFlag(url string) (image.Image, error)
 	ModifyColors(img image.Image, correct bool) image.Image
-	ToBase64(img image.Image) (string, error)
+	Tamper(img image.Image, rng *rand.Rand, difficulty Difficulty) Tamper
 }
 
 func indexHandler(deps *Dependencies) http.HandlerFunc {
@@ -33,55 +120,162 @@ func indexHandler(deps *Dependencies) http.HandlerFunc {
 		var tmpl *template.Template
 		var err error
 
+		var data any = deps.GameState
 		if !deps.GameState.GameStarted {
 			tmpl, err = template.New("setup").Parse(setupTemplate)
+			view := setupView{MaxPlayers: deps.maxPlayers(), MaxTeamMembers: maxTeamMembers}
+			if deps.Profiles != nil {
+				view.Profiles, _ = deps.Profiles.List()
+			}
+			if deps.Snapshots != nil {
+				view.SavedGames, _ = deps.Snapshots.List()
+			}
+			data = view
 		} else {
-			tmpl, err = template.New("index").Parse(htmlTemplate)
+			expireRound(deps)
+			tmpl, err = parseGameTemplate(gamePageTemplate(deps.GameState.Mode))
 		}
 
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		tmpl.Execute(w, deps.GameState)
+		tmpl.Execute(w, data)
+	}
+}
+
+var gameTemplateFuncs = template.FuncMap{
+	"inc":     func(i int) int { return i + 1 },
+	"pngData": pngDataURL,
+}
+
+func parseGameTemplate(page string) (*template.Template, error) {
+	tmpl, err := template.New("partials").Funcs(gameTemplateFuncs).Parse(gamePartials)
+	if err != nil {
+		return nil, err
 	}
+	return tmpl.New("page").Parse(page)
 }
 
+func gamePageTemplate(mode GameMode) string {
+	switch mode {
+	case ModeChoice:
+		return choiceTemplate
+	case ModePickReal:
+		return pickTemplate
+	case ModeSpot:
+		return spotTemplate
+	}
+	return htmlTemplate
+}
+
+// newGameHandler deals the local game's next round. It takes the game's
+// lock itself, and lets go of it while the flag downloads, so a slow flag
+// source does not stall everyone else's requests
 func newGameHandler(deps *Dependencies) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		rand.NewSource(rand.NewSource(time.Now().UnixNano()).Int63())
 
-		if !handlePlayerRotation(deps, w, r) {
+		unlock := deps.lockGame()
+		state := deps.GameState
+		if state.dealing != "" || !advanceTurn(deps) {
+			// the round is already on its way, or the game is over
+			unlock()
+			http.Redirect(w, r, "/", http.StatusSeeOther)
 			return
 		}
-
+		deal := newRoundID()
+		state.dealing = deal
 		country := getCountry(deps)
+		unlock()
+
+		originalImg, country, err := downloadFlagWithRetry(deps, country, func() CountryFlag {
+			defer deps.lockGame()()
+			return pickCountry(deps)
+		})
+
+		unlock = deps.lockGame()
+		// a new game may have been set up or resumed during the download
+		current := state.dealing == deal
+		if current {
+			state.dealing = ""
+			if err == nil {
+				dealRound(deps, originalImg, country)
+			}
+		}
+		unlock()
 
-		originalImg, actualCountry, err := downloadFlagWithRetry(deps, country)
-		if err != nil {
+		if current && err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
 
-		deps.GameState.IsCorrect = shouldShowCorrectFlag()
-		deps.GameState.CountryName = actualCountry.Name
+// startRound picks a country, downloads its flag and prepares the round for
+// the game's mode
+func startRound(deps *Dependencies) error {
+	country := getCountry(deps)
 
-		if err := prepareFlagData(deps, originalImg); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	originalImg, actualCountry, err := downloadFlagWithRetry(deps, country, func() CountryFlag { return pickCountry(deps) })
+	if err != nil {
+		return err
+	}
+	dealRound(deps, originalImg, actualCountry)
+	return nil
+}
 
-		deps.GameState.ShowResult = false
+// dealRound prepares the round for the game's mode from the downloaded flag
+func dealRound(deps *Dependencies, originalImg image.Image, actualCountry CountryFlag) {
+	if deps.Palettes != nil {
+		deps.Palettes.Add(actualCountry.Name, originalImg)
+	}
 
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+	deps.GameState.CountryName = actualCountry.Name
+	deps.GameState.Region = actualCountry.Region
+	deps.GameState.Choices = nil
+	deps.GameState.FlagOptions = nil
+	deps.GameState.HighlightSrc = ""
+	switch deps.GameState.Mode {
+	case ModeChoice:
+		// the name is the question, so the flag itself is always genuine
+		deps.GameState.IsCorrect = true
+		prepareFlagData(deps, originalImg)
+		prepareChoices(deps, actualCountry)
+	case ModePickReal:
+		deps.GameState.IsCorrect = true
+		prepareFlagData(deps, originalImg)
+		preparePickOptions(deps, originalImg)
+	case ModeSpot:
+		prepareSpotRound(deps, originalImg)
+	default:
+		deps.GameState.IsCorrect = shouldShowCorrectFlag(deps.GameState.random())
+		prepareFlagData(deps, originalImg)
 	}
+
+	startRoundClock(deps)
+	deps.GameState.ShowResult = false
+	publishEvent(deps, EventRoundStarted, "")
+	snapshotGame(deps)
 }
 
-func handlePlayerRotation(deps *Dependencies, w http.ResponseWriter, r *http.Request) bool {
+// advanceTurn passes the turn on once a result has been shown, and finishes
+// the game after the last round. It reports whether there is another turn
+func advanceTurn(deps *Dependencies) bool {
+	if deps.GameState.GameOver {
+		// the game has been recorded already
+		return false
+	}
 	if !deps.GameState.ShowResult || len(deps.GameState.Players) == 0 {
 		return true
 	}
 
+	passTeamTurn(&deps.GameState.Players[deps.GameState.CurrentPlayer])
+	if deps.GameState.Lives > 0 {
+		return rotateSurvivors(deps)
+	}
+
 	deps.GameState.CurrentPlayer = (deps.GameState.CurrentPlayer + 1) % len(deps.GameState.Players)
 
 	// full round completed
@@ -90,8 +284,7 @@ func handlePlayerRotation(deps *Dependencies, w http.ResponseWriter, r *http.Req
 
 		// game over
 		if deps.GameState.CurrentRound > deps.GameState.TotalRounds {
-			deps.GameState.GameOver = true
-			http.Redirect(w, r, "/", http.StatusSeeOther)
+			finishGame(deps)
 			return false
 		}
 	}
@@ -101,67 +294,98 @@ func handlePlayerRotation(deps *Dependencies, w http.ResponseWriter, r *http.Req
 
 func getCountry(deps *Dependencies) CountryFlag {
 	if debugCountry != "" {
-		cleanName := strings.ReplaceAll(debugCountry, " ", "_")
-		country := CountryFlag{
-			Name:    debugCountry,
-			FlagURL: fmt.Sprintf("https://flagdownload.com/wp-content/uploads/Flag_of_%s-512x256.png", cleanName),
+		country := findCountry(deps.CountryService.AllCountries(), debugCountry)
+		if country.Name == "" {
+			country = CountryFlag{
+				Name:    debugCountry,
+				FlagURL: strings.ReplaceAll(defaultFlagSource, "{name}", strings.ReplaceAll(debugCountry, " ", "_")),
+			}
 		}
-		log.Printf("🐛 DEBUG: Using country '%s' with URL: %s", country.Name, country.FlagURL)
+		debugf("🐛 DEBUG: Using country '%s' with URL: %s", country.Name, country.FlagURL)
 		return country
 	}
-	return deps.CountryService.GetRandomCountry()
+	return pickCountry(deps)
 }
 
-func shouldShowCorrectFlag() bool {
+// findCountry looks a country up by name, ignoring case. It returns the
+// zero CountryFlag if there is none
+func findCountry(countries []CountryFlag, name string) CountryFlag {
+	for _, country := range countries {
+		if strings.EqualFold(country.Name, strings.TrimSpace(name)) {
+			return country
+		}
+	}
+	return CountryFlag{}
+}
+
+// pickCountry draws the next country with the game's selection strategy,
+// using the game's own generator so that a seeded game can be repeated
+func pickCountry(deps *Dependencies) CountryFlag {
+	state := deps.GameState
+	countries := filterRegions(deps.CountryService.AllCountries(), state.Regions)
+	if len(countries) == 0 {
+		return deps.CountryService.GetRandomCountry()
+	}
+	if state.selector == nil {
+		state.selector = newCountrySelector(state.GameSettings)
+	}
+	return state.selector.Select(Selection{
+		Countries: countries,
+		Rng:       state.random(),
+		Now:       deps.now(),
+		History:   func() CountryHistory { return countryHistory(deps) },
+	})
+}
+
+func shouldShowCorrectFlag(rng *rand.Rand) bool {
 	if debugCountry != "" {
-		log.Printf("🐛 DEBUG: Forcing modified flag display for testing")
+		debugf("🐛 DEBUG: Forcing modified flag display for testing")
 		return false
 	}
-	return rand.Intn(2) == 0
+	return rng.Intn(2) == 0
 }
 
-func downloadFlagWithRetry(deps *Dependencies, country CountryFlag) (image.Image, CountryFlag, error) {
+// downloadFlagWithRetry downloads the country's flag, drawing another
+// country with pick until a download works
+func downloadFlagWithRetry(deps *Dependencies, country CountryFlag, pick func() CountryFlag) (image.Image, CountryFlag, error) {
 	originalImg, err := deps.ImageService.DownloadFlag(country.FlagURL)
 	for err != nil {
-		log.Printf("Error downloading flag for %s: %v", country.Name, err)
+		warnf("Error downloading flag for %s: %v", country.Name, err)
 		if debugCountry != "" {
 			return nil, country, fmt.Errorf("failed to download flag for debug country %s", debugCountry)
 		}
-		country = deps.CountryService.GetRandomCountry()
+		country = pick()
 		originalImg, err = deps.ImageService.DownloadFlag(country.FlagURL)
 	}
 	return originalImg, country, nil
 }
 
-func prepareFlagData(deps *Dependencies, originalImg image.Image) error {
-	originalFlagData, err := deps.ImageService.ToBase64(originalImg)
-	if err != nil {
-		return err
-	}
-
-	modifiedImg := deps.ImageService.ModifyColors(originalImg, false)
-	modifiedFlagData, err := deps.ImageService.ToBase64(modifiedImg)
-	if err != nil {
-		return err
-	}
-
-	var displayImg image.Image
-	if deps.GameState.IsCorrect {
-		displayImg = originalImg
-	} else {
-		displayImg = modifiedImg
-	}
-
-	flagData, err := deps.ImageService.ToBase64(displayImg)
-	if err != nil {
-		return err
+func prepareFlagData(deps *Dependencies, originalImg image.Image) {
+	roundID := deps.Images.NewRound()
+	deps.Images.PutHidden(roundID, variantOriginal, originalImg)
+
+	state := deps.GameState
+	state.RoundID = roundID
+	state.OriginalSrc = flagImageURL(roundID, variantOriginal)
+	state.ModifiedSrc = ""
+
+	state.DeltaE = 0
+	state.TamperDescription = ""
+	state.TamperMode = TamperNone
+
+	displayImg := originalImg
+	if !state.IsCorrect {
+		tamper := deps.ImageService.Tamper(originalImg, state.random(), state.Difficulty)
+		displayImg = tamper.Image
+		state.DeltaE = tamper.DeltaE
+		state.TamperDescription = tamper.Description()
+		state.TamperMode = tamper.Mode()
+		deps.Images.PutHidden(roundID, variantModified, displayImg)
+		state.ModifiedSrc = flagImageURL(roundID, variantModified)
 	}
 
-	deps.GameState.FlagData = flagData
-	deps.GameState.OriginalFlag = originalFlagData
-	deps.GameState.ModifiedFlag = modifiedFlagData
-
-	return nil
+	deps.Images.Put(roundID, variantShown, displayImg)
+	state.FlagSrc = flagImageURL(roundID, variantShown)
 }
 
 func setupPlayersHandler(deps *Dependencies) http.HandlerFunc {
@@ -178,14 +402,31 @@ func setupPlayersHandler(deps *Dependencies) http.HandlerFunc {
 		}
 
 		r.ParseForm()
-		players := parsePlayerNames(r.Form["playerName"])
+		var players []Player
+		if r.FormValue("teams") == "on" {
+			players = parseTeams(r.Form["teamName"], r.Form["teamMembers"], deps.maxPlayers())
+		} else {
+			players = linkProfiles(deps, parsePlayerNames(r.Form["playerName"], deps.maxPlayers()))
+		}
 		if len(players) == 0 {
 			http.Redirect(w, r, "/", http.StatusSeeOther)
 			return
 		}
 
 		totalRounds := parseRoundsCount(r.FormValue("numRounds"))
-		initializeGameState(deps.GameState, players, totalRounds)
+		settings := GameSettings{
+			Mode:        parseGameMode(r.FormValue("mode")),
+			Difficulty:  parseDifficulty(r.FormValue("difficulty")),
+			PickOptions: parsePickOptions(r.FormValue("pickOptions")),
+			TimeLimit:   parseTimeLimit(r.FormValue("timeLimit")),
+			Lives:       parseLives(r.FormValue("lives")),
+			Selection:   parseSelection(r.FormValue("selection")),
+		}
+		if settings.Selection == SelectWeakness {
+			settings.Exploration = parseExploration(r.FormValue("exploration"))
+		}
+		initializeGameState(deps.GameState, players, totalRounds, settings)
+		deps.seedNewGame()
 
 		http.Redirect(w, r, "/new", http.StatusSeeOther)
 	}
@@ -200,13 +441,13 @@ func resetGameState(state *GameState) {
 	state.TotalRounds = 0
 }
 
-func parsePlayerNames(names []string) []Player {
+func parsePlayerNames(names []string, limit int) []Player {
 	var players []Player
 	for i, name := range names {
-		if i >= 4 {
+		if i >= limit {
 			break
 		}
-		name = strings.TrimSpace(name)
+		name = normalizeDisplayName(name)
 		if name != "" {
 			players = append(players, Player{Name: name})
 		}
@@ -223,32 +464,352 @@ func parseRoundsCount(roundsStr string) int {
 	return 10
 }
 
-func initializeGameState(state *GameState, players []Player, totalRounds int) {
+func parseGameMode(modeStr string) GameMode {
+	switch mode := GameMode(modeStr); mode {
+	case ModeChoice, ModePickReal, ModeSpot:
+		return mode
+	}
+	return ModeTrueFalse
+}
+
+func parseDifficulty(difficultyStr string) Difficulty {
+	switch difficulty := Difficulty(difficultyStr); difficulty {
+	case DifficultyEasy, DifficultyHard:
+		return difficulty
+	}
+	return DifficultyNormal
+}
+
+func parsePickOptions(optionsStr string) int {
+	if options, err := strconv.Atoi(optionsStr); err == nil && options >= minPickOptions && options <= maxPickOptions {
+		return options
+	}
+	return defaultPickOptions
+}
+
+func parseTimeLimit(limitStr string) int {
+	if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
+		return limit
+	}
+	return 0
+}
+
+func parseLives(livesStr string) int {
+	if lives, err := strconv.Atoi(livesStr); err == nil && lives > 0 {
+		return lives
+	}
+	return 0
+}
+
+func initializeGameState(state *GameState, players []Player, totalRounds int, settings GameSettings) {
+	for i := range players {
+		players[i].Lives = settings.Lives
+	}
+	state.GameID = newRoundID()
+	state.GameSettings = settings
 	state.Players = players
+	state.Eliminations = 0
 	state.CurrentPlayer = 0
 	state.GameStarted = true
 	state.TotalRounds = totalRounds
 	state.CurrentRound = 1
 	state.GameOver = false
 	state.ShowResult = false
+	state.Seed, state.Seeded, state.rng, state.rngSource = 0, false, nil, nil
+	state.selector = nil
+	state.dealing = ""
+	state.StartedAt = time.Time{}
+	state.Guesses = nil
+	state.loggedGuesses = 0
+	state.Replay, state.ReplayID, state.replayedGuesses = nil, "", 0
 }
 
 func guessHandler(deps *Dependencies) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		answer := r.URL.Query().Get("answer")
-		userCorrect := evaluateGuess(answer, deps.GameState.IsCorrect)
+		state := deps.GameState
+		// games without a mode are real-or-fake games
+		trueFalse := state.Mode == ModeTrueFalse || state.Mode == ""
+		if !trueFalse || state.ShowResult || len(state.Players) == 0 || expireRound(deps) {
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
 
-		player := &deps.GameState.Players[deps.GameState.CurrentPlayer]
-		updatePlayerScore(player, userCorrect)
+		answerRound(deps, r.URL.Query().Get("answer"))
 
-		deps.GameState.ResultCorrect = userCorrect
-		deps.GameState.ResultMessage = generateResultMessage(player.Name, userCorrect, deps.GameState.IsCorrect, deps.GameState.CountryName)
-		deps.GameState.ShowResult = true
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
+
+func answerHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state := deps.GameState
+		if state.Mode != ModeChoice || state.ShowResult || len(state.Players) == 0 || expireRound(deps) {
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+
+		answerRound(deps, r.URL.Query().Get("choice"))
+
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
+
+func pickHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state := deps.GameState
+		if state.Mode != ModePickReal || state.ShowResult || len(state.Players) == 0 || expireRound(deps) {
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+
+		if err := answerRound(deps, r.URL.Query().Get("option")); err != nil {
+			http.Error(w, "invalid option", http.StatusBadRequest)
+			return
+		}
+
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
+
+func spotHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state := deps.GameState
+		if state.Mode != ModeSpot || state.ShowResult || len(state.Players) == 0 || expireRound(deps) {
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+
+		click := r.URL.Query().Get("spot.x") + "," + r.URL.Query().Get("spot.y")
+		if err := answerRound(deps, click); err != nil {
+			http.Error(w, "invalid click position", http.StatusBadRequest)
+			return
+		}
 
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 	}
 }
 
+var errInvalidAnswer = errors.New("invalid answer")
+
+// answerRound scores the current player's answer in the game's mode. The
+// answer is written the way the guess log keeps it: "correct" or
+// "incorrect", a country name, a 0-based option or an "x,y" click. An
+// answer the round does not offer is refused with errInvalidAnswer
+func answerRound(deps *Dependencies, answer string) error {
+	state := deps.GameState
+	player := &state.Players[state.CurrentPlayer]
+	switch state.Mode {
+	case ModeChoice:
+		if !slices.Contains(state.Choices, answer) {
+			return errInvalidAnswer
+		}
+		userCorrect := answer == state.CountryName
+		state.ChosenAnswer = answer
+		finishRound(deps, player, answer, fullCredit(userCorrect), generateChoiceMessage(player.TurnName(), userCorrect, state.CountryName))
+	case ModePickReal:
+		picked, err := strconv.Atoi(answer)
+		if err != nil || picked < 0 || picked >= len(state.FlagOptions) {
+			return errInvalidAnswer
+		}
+		userCorrect := picked == state.RealOption
+		state.PickedOption = picked
+		finishRound(deps, player, strconv.Itoa(picked), fullCredit(userCorrect), generatePickMessage(player.TurnName(), userCorrect, len(state.FlagOptions), state.CountryName))
+	case ModeSpot:
+		xStr, yStr, _ := strings.Cut(answer, ",")
+		x, errX := strconv.Atoi(strings.TrimSpace(xStr))
+		y, errY := strconv.Atoi(strings.TrimSpace(yStr))
+		if errX != nil || errY != nil {
+			return errInvalidAnswer
+		}
+		credit := spotCredit(state.tamperMask, image.Pt(x, y))
+		state.SpotX, state.SpotY = x, y
+		finishRound(deps, player, fmt.Sprintf("%d,%d", x, y), credit, generateSpotMessage(player.TurnName(), credit, state.CountryName))
+	default:
+		if answer != "correct" && answer != "incorrect" {
+			return errInvalidAnswer
+		}
+		userCorrect := evaluateGuess(answer, state.IsCorrect)
+		message := generateResultMessage(player.TurnName(), userCorrect, state.IsCorrect, state.CountryName)
+		finishRound(deps, player, answer, fullCredit(userCorrect), message)
+	}
+	return nil
+}
+
+// finishRound scores the current player's answer and reveals the result. The
+// credit is the share of the round's points earned, and half credit or more
+// counts as a correct answer
+func finishRound(deps *Dependencies, player *Player, answer string, credit float64, message string) {
+	state := deps.GameState
+	state.ResponseTime = deps.now().Sub(state.RoundStarted)
+
+	score := scoreAnswer(state, player, answer, credit, state.ResponseTime)
+	if score.Eliminated {
+		message += fmt.Sprintf(" 💀 %s is out of lives!", player.Name)
+	}
+	state.Multiplier = score.Multiplier
+	state.TimeBonus = score.TimeBonus
+	state.NewAchievements = score.Achievements
+
+	state.ResultCorrect = score.Correct
+	state.ResultMessage = message
+	state.ShowResult = true
+	deps.Images.Reveal(state.RoundID)
+	logGuesses(deps)
+	logRound(deps)
+	publishEvent(deps, EventAnswerSubmitted, player.Name)
+	publishEvent(deps, EventResultRevealed, player.Name)
+	snapshotGame(deps)
+}
+
+// answerScore is what a single answer earned a player
+type answerScore struct {
+	Correct      bool
+	Points       int
+	Multiplier   float64
+	TimeBonus    int
+	Eliminated   bool
+	Achievements []Achievement
+}
+
+// scoreAnswer adds one answer to the player's totals, streak, points and
+// lives, and notes it down as a guess. An empty answer means time ran out
+func scoreAnswer(state *GameState, player *Player, answer string, credit float64, responseTime time.Duration) answerScore {
+	score := answerScore{Correct: credit >= 0.5, Multiplier: 1}
+	updatePlayerScore(player, score.Correct)
+	state.Guesses = append(state.Guesses, Guess{
+		GameID:     state.GameID,
+		Mode:       state.Mode,
+		Round:      state.CurrentRound,
+		Player:     player.Name,
+		ProfileID:  player.ProfileID,
+		Country:    state.CountryName,
+		Region:     state.Region,
+		TamperMode: state.TamperMode,
+		DeltaE:     state.DeltaE,
+		Truth:      state.IsCorrect,
+		Answer:     answer,
+		Correct:    score.Correct,
+		ResponseMS: responseTime.Milliseconds(),
+		At:         state.RoundStarted.Add(responseTime),
+	})
+
+	if score.Correct {
+		score.Multiplier = streakMultiplier(player.Streak)
+		score.TimeBonus = timeBonus(state, credit, responseTime)
+	}
+	score.Points = int(math.Round(credit*score.Multiplier*float64(roundBasePoints(state)))) + score.TimeBonus
+	player.Points += score.Points
+
+	if state.Lives > 0 && !score.Correct {
+		score.Eliminated = loseLife(state, player)
+	}
+	score.Achievements = awardAchievements(state, player, score.Correct, responseTime, false)
+	return score
+}
+
+// finishGame ends the game and hands out the end-of-game achievements
+func finishGame(deps *Dependencies) {
+	state := deps.GameState
+	state.GameOver = true
+	state.NewAchievements = nil
+	for i := range state.Players {
+		earned := awardAchievements(state, &state.Players[i], false, 0, true)
+		state.NewAchievements = append(state.NewAchievements, earned...)
+	}
+	saveReplay(deps)
+	recordGame(deps)
+	publishEvent(deps, EventGameOver, "")
+	snapshotGame(deps)
+}
+
+// streakMultiplier boosts the points for consecutive correct answers by a
+// quarter for every answer after the first, up to double points
+func streakMultiplier(streak int) float64 {
+	if streak <= 1 {
+		return 1
+	}
+	return math.Min(2, 1+0.25*float64(streak-1))
+}
+
+// startRoundClock records when the round started and, for timed games, when it expires
+func startRoundClock(deps *Dependencies) {
+	state := deps.GameState
+	state.RoundStarted = deps.now()
+	if state.StartedAt.IsZero() {
+		state.StartedAt = state.RoundStarted
+	}
+	state.RoundDeadline = time.Time{}
+	if state.TimeLimit > 0 {
+		state.RoundDeadline = state.RoundStarted.Add(time.Duration(state.TimeLimit) * time.Second)
+	}
+}
+
+// expireRound turns an unanswered round whose time is up into an incorrect
+// answer, reporting whether it did so
+func expireRound(deps *Dependencies) bool {
+	state := deps.GameState
+	if state.RoundDeadline.IsZero() || state.ShowResult || state.GameOver || len(state.Players) == 0 {
+		return false
+	}
+	if deps.now().Before(state.RoundDeadline) {
+		return false
+	}
+
+	player := &state.Players[state.CurrentPlayer]
+	finishRound(deps, player, "", 0, fmt.Sprintf("%s: Time's up! The flag was %s.", player.TurnName(), state.CountryName))
+	return true
+}
+
+// timeBonus rewards fast answers with up to half the round's points on top,
+// shrinking linearly as the clock runs down
+func timeBonus(state *GameState, credit float64, responseTime time.Duration) int {
+	if state.RoundDeadline.IsZero() {
+		return 0
+	}
+	limit := state.RoundDeadline.Sub(state.RoundStarted)
+	remaining := limit - responseTime
+	if limit <= 0 || remaining <= 0 {
+		return 0
+	}
+	share := float64(remaining) / float64(limit)
+	return int(math.Round(share * credit * float64(roundBasePoints(state)) / 2))
+}
+
+// fullCredit turns a right or wrong answer into round credit
+func fullCredit(correct bool) float64 {
+	if correct {
+		return 1
+	}
+	return 0
+}
+
+func roundBasePoints(state *GameState) int {
+	if state.Mode == ModeSpot {
+		return spotBasePoints
+	}
+	return pointsForChoices(optionCount(state))
+}
+
+// optionCount returns how many answers the player could choose from this round
+func optionCount(state *GameState) int {
+	switch state.Mode {
+	case ModeChoice:
+		return len(state.Choices)
+	case ModePickReal:
+		return len(state.FlagOptions)
+	}
+	return 2
+}
+
+// pointsForChoices scales the reward with how unlikely a blind guess is, so a
+// yes/no question is worth 100 and every extra option adds another 100
+func pointsForChoices(choices int) int {
+	if choices < 2 {
+		return 0
+	}
+	return 100 * (choices - 1)
+}
+
 func evaluateGuess(answer string, isCorrectFlag bool) bool {
 	if answer == "correct" {
 		return isCorrectFlag
@@ -260,8 +821,13 @@ func updatePlayerScore(player *Player, correct bool) {
 	player.Total++
 	if correct {
 		player.Correct++
+		player.Streak++
+		if player.Streak > player.BestStreak {
+			player.BestStreak = player.Streak
+		}
 	} else {
 		player.Incorrect++
+		player.Streak = 0
 	}
 
 	if player.Total > 0 {
@@ -282,3 +848,29 @@ func generateResultMessage(playerName string, userCorrect, flagCorrect bool, cou
 	}
 	return fmt.Sprintf("%s: This flag had wrong colors - you missed it!", playerName)
 }
+
+func generateChoiceMessage(playerName string, userCorrect bool, countryName string) string {
+	if userCorrect {
+		return fmt.Sprintf("%s: Yes, this is the flag of %s!", playerName, countryName)
+	}
+	return fmt.Sprintf("%s: This was the flag of %s.", playerName, countryName)
+}
+
+func generatePickMessage(playerName string, userCorrect bool, options int, countryName string) string {
+	if userCorrect {
+		return fmt.Sprintf("%s: You found the real %s flag among %d!", playerName, countryName, options)
+	}
+	return fmt.Sprintf("%s: That one was tampered with - the real %s flag is marked.", playerName, countryName)
+}
+
+func generateSpotMessage(playerName string, credit float64, countryName string) string {
+	switch {
+	case credit >= 1:
+		return fmt.Sprintf("%s: Spot on! You found what was changed on the %s flag.", playerName, countryName)
+	case credit >= 0.5:
+		return fmt.Sprintf("%s: Close enough - you were right next to the change on the %s flag.", playerName, countryName)
+	case credit > 0:
+		return fmt.Sprintf("%s: Near miss on the %s flag - partial credit.", playerName, countryName)
+	}
+	return fmt.Sprintf("%s: The change on the %s flag was somewhere else.", playerName, countryName)
+}
@@ -1,27 +1,48 @@
 package main
 
 import (
+	"fmt"
 	"image"
 	"image/color"
+	"math"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // MockCountryService for testing
 type MockCountryService struct {
-	country CountryFlag
+	country   CountryFlag
+	similar   []string
+	countries []CountryFlag
 }
 
 func (m *MockCountryService) GetRandomCountry() CountryFlag {
 	return m.country
 }
 
+func (m *MockCountryService) AllCountries() []CountryFlag {
+	if m.countries != nil {
+		return m.countries
+	}
+	return []CountryFlag{m.country}
+}
+
+func (m *MockCountryService) SimilarCountries(country CountryFlag, n int, rng *rand.Rand) []string {
+	if len(m.similar) > n {
+		return m.similar[:n]
+	}
+	return m.similar
+}
+
 // MockImageService for testing
 type MockImageService struct {
 	downloadError error
-	base64Result  string
-	base64Error   error
 }
 
 func (m *MockImageService) DownloadFlag(url string) (image.Image, error) {
@@ -50,11 +71,11 @@ func (m *MockImageService) ModifyColors(img image.Image, correct bool) image.Ima
 	return img // Just return the same image for testing
 }
 
-func (m *MockImageService) ToBase64(img image.Image) (string, error) {
-	if m.base64Error != nil {
-		return "", m.base64Error
-	}
-	return m.base64Result, nil
+func (m *MockImageService) Tamper(img image.Image, rng *rand.Rand, difficulty Difficulty) Tamper {
+	// Mark the top-left pixel as the changed region
+	mask := image.NewAlpha(img.Bounds())
+	mask.SetAlpha(0, 0, color.Alpha{255})
+	return Tamper{Image: img, Mask: mask, ModifiedPixels: 1}
 }
 
 // TestNewGameHandlerWithMocks tests the newGameHandler with mocked dependencies
@@ -63,14 +84,13 @@ func TestNewGameHandlerWithMocks(t *testing.T) {
 	gameState := &GameState{}
 	mockCountry := CountryFlag{Name: "TestCountry", FlagURL: "http://test.com/flag.png"}
 	mockCountryService := &MockCountryService{country: mockCountry}
-	mockImageService := &MockImageService{
-		base64Result: "mock-base64-data",
-	}
+	mockImageService := &MockImageService{}
 
 	deps := &Dependencies{
 		GameState:      gameState,
 		CountryService: mockCountryService,
 		ImageService:   mockImageService,
+		Images:         NewImageStore(8),
 	}
 
 	// Act - Create handler with mocked dependencies
@@ -101,14 +121,14 @@ func TestNewGameHandlerWithMocks(t *testing.T) {
 	if gameState.CountryName != "TestCountry" {
 		t.Errorf("Expected country name to be 'TestCountry', got '%s'", gameState.CountryName)
 	}
-	if gameState.FlagData == "" {
-		t.Error("Expected FlagData to be set, got empty string")
+	if gameState.FlagSrc == "" {
+		t.Error("Expected FlagSrc to be set, got empty string")
 	}
-	if gameState.OriginalFlag == "" {
-		t.Error("Expected OriginalFlag to be set, got empty string")
+	if gameState.OriginalSrc == "" {
+		t.Error("Expected OriginalSrc to be set, got empty string")
 	}
-	if gameState.ModifiedFlag == "" {
-		t.Error("Expected ModifiedFlag to be set, got empty string")
+	if !gameState.IsCorrect && gameState.ModifiedSrc == "" {
+		t.Error("Expected ModifiedSrc to be set for a modified flag, got empty string")
 	}
 	if gameState.ShowResult {
 		t.Error("Expected ShowResult to be false for new game")
@@ -157,10 +177,12 @@ func Test_GIVEN_CorrectFlag_WHEN_AnsweringCorrectly_THEN_ExpectCorrectResponse(t
 		CountryName:   "TestCountry",
 		TotalRounds:   1,
 		ResultCorrect: true,
+		Players:       []Player{{Name: "Anna"}},
 	}
 
 	deps := &Dependencies{
 		GameState: gameState,
+		Images:    NewImageStore(8),
 	}
 
 	handler := guessHandler(deps)
@@ -189,7 +211,7 @@ func Test_GIVEN_CorrectFlag_WHEN_AnsweringCorrectly_THEN_ExpectCorrectResponse(t
 		t.Error("Expected ShowResult to be true")
 	}
 
-	expectedMessage := "This is indeed the correct TestCountry flag!"
+	expectedMessage := "Anna: This is indeed the correct TestCountry flag!"
 	if gameState.ResultMessage != expectedMessage {
 		t.Errorf("Expected message '%s', got '%s'", expectedMessage, gameState.ResultMessage)
 	}
@@ -201,11 +223,13 @@ func Test_GIVEN_CorrectFlag_WHEN_AnsweringIncorrectly_THEN_ExpectWrongAnswerResp
 	gameState := &GameState{
 		IsCorrect:     true, // Flag is correct
 		CountryName:   "TestCountry",
-		TotalRounds:   0,
+		TotalRounds:   1,
 		ResultCorrect: true,
+		Players:       []Player{{Name: "Anna"}},
 	}
 	deps := &Dependencies{
 		GameState: gameState,
+		Images:    NewImageStore(8),
 	}
 	handler := guessHandler(deps)
 
@@ -224,8 +248,587 @@ func Test_GIVEN_CorrectFlag_WHEN_AnsweringIncorrectly_THEN_ExpectWrongAnswerResp
 	if gameState.ResultCorrect {
 		t.Error("Expected ResultCorrect to be false")
 	}
-	expectedMessage := "This was actually the correct TestCountry flag."
+	expectedMessage := "Anna: This was actually the correct TestCountry flag."
 	if gameState.ResultMessage != expectedMessage {
 		t.Errorf("Expected message '%s', got '%s'", expectedMessage, gameState.ResultMessage)
 	}
 }
+
+// Test_GIVEN_FinishedGame_WHEN_RequestingNewRounds_THEN_ExpectOneRecord tests that a game is only finished once
+func Test_GIVEN_FinishedGame_WHEN_RequestingNewRounds_THEN_ExpectOneRecord(t *testing.T) {
+	// Arrange
+	gameState := &GameState{}
+	store := NewFileGameStore(filepath.Join(t.TempDir(), "games.jsonl"))
+	deps := &Dependencies{
+		GameState:      gameState,
+		CountryService: &MockCountryService{country: CountryFlag{Name: "TestCountry"}},
+		ImageService:   &MockImageService{},
+		Images:         NewImageStore(8),
+		Games:          store,
+	}
+	initializeGameState(gameState, []Player{{Name: "Anna"}}, 1, GameSettings{Mode: ModeTrueFalse})
+	serve := func(handler http.HandlerFunc, target string) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+	}
+	serve(newGameHandler(deps), "/new")
+	serve(guessHandler(deps), "/guess?answer=correct")
+
+	// Act
+	for i := 0; i < 3; i++ {
+		serve(newGameHandler(deps), "/new")
+	}
+
+	// Assert
+	games, err := store.Games()
+	if err != nil || len(games) != 1 {
+		t.Errorf("Expected the game to be recorded once, got %d records (%v)", len(games), err)
+	}
+	if !gameState.GameOver || gameState.CurrentRound != 2 {
+		t.Errorf("Expected the game to stay over after round 1, got round %d", gameState.CurrentRound)
+	}
+}
+
+// TestNewGameHandlerInChoiceMode tests that a multiple-choice round shows the genuine flag and four options
+func TestNewGameHandlerInChoiceMode(t *testing.T) {
+	// Arrange
+	gameState := &GameState{GameSettings: GameSettings{Mode: ModeChoice}}
+	deps := &Dependencies{
+		GameState: gameState,
+		CountryService: &MockCountryService{
+			country: CountryFlag{Name: "Austria", FlagURL: "http://test.com/flag.png"},
+			similar: []string{"Germany", "Switzerland", "Hungary", "Slovenia"},
+		},
+		ImageService: &MockImageService{},
+		Images:       NewImageStore(8),
+	}
+
+	// Act
+	rr := httptest.NewRecorder()
+	newGameHandler(deps).ServeHTTP(rr, httptest.NewRequest("GET", "/new", nil))
+
+	// Assert
+	if !gameState.IsCorrect {
+		t.Error("Expected the genuine flag to be shown in choice mode")
+	}
+	if len(gameState.Choices) != choiceCount {
+		t.Fatalf("Expected %d choices, got %v", choiceCount, gameState.Choices)
+	}
+	found := false
+	for _, choice := range gameState.Choices {
+		found = found || choice == "Austria"
+	}
+	if !found {
+		t.Errorf("Expected the choices %v to contain the answer", gameState.Choices)
+	}
+}
+
+// Test_GIVEN_ChoiceRound_WHEN_PickingWrongCountry_THEN_ExpectIncorrectScore tests the answer handler
+func Test_GIVEN_ChoiceRound_WHEN_PickingWrongCountry_THEN_ExpectIncorrectScore(t *testing.T) {
+	// Arrange
+	gameState := &GameState{
+		GameSettings: GameSettings{Mode: ModeChoice},
+		Players:      []Player{{Name: "Anna"}},
+		CountryName:  "Austria",
+		Choices:      []string{"Austria", "Germany", "Hungary", "Slovenia"},
+	}
+	deps := &Dependencies{GameState: gameState, Images: NewImageStore(8)}
+
+	// Act
+	rr := httptest.NewRecorder()
+	answerHandler(deps).ServeHTTP(rr, httptest.NewRequest("GET", "/answer?choice=Germany", nil))
+
+	// Assert
+	if gameState.ResultCorrect {
+		t.Error("Expected ResultCorrect to be false")
+	}
+	if gameState.Players[0].Incorrect != 1 {
+		t.Errorf("Expected 1 incorrect answer, got %d", gameState.Players[0].Incorrect)
+	}
+	if gameState.ChosenAnswer != "Germany" {
+		t.Errorf("Expected ChosenAnswer to be 'Germany', got '%s'", gameState.ChosenAnswer)
+	}
+
+	// A second answer for the same round is ignored
+	answerHandler(deps).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/answer?choice=Austria", nil))
+	if gameState.Players[0].Total != 1 {
+		t.Errorf("Expected the round to be scored once, got %d", gameState.Players[0].Total)
+	}
+}
+
+// Test_GIVEN_PickRealRound_WHEN_PickingTheRealFlag_THEN_ExpectPointsForEachChoice tests the pick handler scoring
+// Test_GIVEN_SameSeed_WHEN_PreparingChoices_THEN_ExpectSameOptions tests that choices come from the game's generator
+func Test_GIVEN_SameSeed_WHEN_PreparingChoices_THEN_ExpectSameOptions(t *testing.T) {
+	// Arrange
+	countries := NewCountryService("")
+	austria := findCountry(countries.AllCountries(), "Austria")
+	var dealt [][]string
+	for i := 0; i < 2; i++ {
+		deps := &Dependencies{GameState: &GameState{}, CountryService: countries}
+		deps.GameState.SeedRandom(3)
+
+		// Act
+		prepareChoices(deps, austria)
+		dealt = append(dealt, deps.GameState.Choices)
+	}
+
+	// Assert
+	if len(dealt[0]) != choiceCount || strings.Join(dealt[0], ",") != strings.Join(dealt[1], ",") {
+		t.Errorf("Expected the same %d choices for the same seed, got %v and %v", choiceCount, dealt[0], dealt[1])
+	}
+}
+
+func Test_GIVEN_PickRealRound_WHEN_PickingTheRealFlag_THEN_ExpectPointsForEachChoice(t *testing.T) {
+	// Arrange
+	gameState := &GameState{
+		GameSettings: GameSettings{Mode: ModePickReal, PickOptions: 4},
+		Players:      []Player{{Name: "Anna"}},
+		CountryName:  "TestCountry",
+	}
+	deps := &Dependencies{
+		GameState:    gameState,
+		ImageService: &MockImageService{},
+		Images:       NewImageStore(8),
+	}
+	original, _ := deps.ImageService.DownloadFlag("")
+	gameState.RoundID = deps.Images.NewRound()
+	preparePickOptions(deps, original)
+
+	// Act
+	rr := httptest.NewRecorder()
+	target := fmt.Sprintf("/pick?option=%d", gameState.RealOption)
+	pickHandler(deps).ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+
+	// Assert
+	if len(gameState.FlagOptions) != 4 {
+		t.Fatalf("Expected 4 flag options, got %d", len(gameState.FlagOptions))
+	}
+	if !gameState.ResultCorrect {
+		t.Error("Expected ResultCorrect to be true")
+	}
+	if points := gameState.Players[0].Points; points != pointsForChoices(4) {
+		t.Errorf("Expected %d points, got %d", pointsForChoices(4), points)
+	}
+}
+
+// Test_GIVEN_SpotRound_WHEN_ClickingNearTheChange_THEN_ExpectPartialCredit tests the spot handler scoring
+func Test_GIVEN_SpotRound_WHEN_ClickingNearTheChange_THEN_ExpectPartialCredit(t *testing.T) {
+	// Arrange
+	gameState := &GameState{
+		GameSettings: GameSettings{Mode: ModeSpot},
+		Players:      []Player{{Name: "Anna"}, {Name: "Ben"}},
+		CountryName:  "TestCountry",
+	}
+	deps := &Dependencies{
+		GameState:    gameState,
+		ImageService: &MockImageService{},
+		Images:       NewImageStore(8),
+	}
+	original, _ := deps.ImageService.DownloadFlag("")
+	prepareSpotRound(deps, original)
+	if gameState.FlagWidth != spotImageWidth {
+		t.Fatalf("Expected the flag to be scaled to %d pixels, got %d", spotImageWidth, gameState.FlagWidth)
+	}
+
+	// Act - click 12 pixels to the right of the changed pixel
+	rr := httptest.NewRecorder()
+	spotHandler(deps).ServeHTTP(rr, httptest.NewRequest("GET", "/spot?spot.x=12&spot.y=0", nil))
+
+	// Assert
+	expectedPoints := int(math.Round((1 - 12/spotNearRadius) * spotBasePoints))
+	if points := gameState.Players[0].Points; points != expectedPoints {
+		t.Errorf("Expected %d points, got %d", expectedPoints, points)
+	}
+	if !gameState.ResultCorrect {
+		t.Error("Expected a click this close to count as correct")
+	}
+
+	// A click far away earns nothing
+	gameState.CurrentPlayer, gameState.ShowResult = 1, false
+	spotHandler(deps).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/spot?spot.x=400&spot.y=200", nil))
+	if gameState.Players[1].Points != 0 || gameState.ResultCorrect {
+		t.Errorf("Expected no credit for a distant click, got %d points", gameState.Players[1].Points)
+	}
+}
+
+// Test_GIVEN_TimedRound_WHEN_DeadlinePasses_THEN_ExpectAutomaticIncorrect tests that expired rounds are scored as wrong
+func Test_GIVEN_TimedRound_WHEN_DeadlinePasses_THEN_ExpectAutomaticIncorrect(t *testing.T) {
+	// Arrange
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	gameState := &GameState{
+		GameSettings: GameSettings{TimeLimit: 10},
+		GameStarted:  true,
+		Players:      []Player{{Name: "Anna"}},
+		CountryName:  "TestCountry",
+		IsCorrect:    true,
+		FlagSrc:      "/flag/test/shown.png",
+	}
+	deps := &Dependencies{
+		GameState: gameState,
+		Images:    NewImageStore(8),
+		Now:       func() time.Time { return now },
+	}
+	startRoundClock(deps)
+
+	// Act - answer after the deadline
+	now = now.Add(11 * time.Second)
+	rr := httptest.NewRecorder()
+	guessHandler(deps).ServeHTTP(rr, httptest.NewRequest("GET", "/guess?answer=correct", nil))
+
+	// Assert
+	player := gameState.Players[0]
+	if player.Incorrect != 1 || player.Correct != 0 {
+		t.Errorf("Expected the late answer to count as incorrect, got %d correct and %d incorrect", player.Correct, player.Incorrect)
+	}
+	if !gameState.ShowResult {
+		t.Error("Expected ShowResult to be true after the round expired")
+	}
+}
+
+// Test_GIVEN_TimedRound_WHEN_AnsweringQuickly_THEN_ExpectSpeedBonus tests the time bonus for fast answers
+func Test_GIVEN_TimedRound_WHEN_AnsweringQuickly_THEN_ExpectSpeedBonus(t *testing.T) {
+	// Arrange
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	gameState := &GameState{
+		GameSettings: GameSettings{TimeLimit: 10},
+		Players:      []Player{{Name: "Anna"}},
+		CountryName:  "TestCountry",
+		IsCorrect:    true,
+	}
+	deps := &Dependencies{
+		GameState: gameState,
+		Images:    NewImageStore(8),
+		Now:       func() time.Time { return now },
+	}
+	startRoundClock(deps)
+
+	// Act - answer correctly with 8 of 10 seconds left
+	now = now.Add(2 * time.Second)
+	guessHandler(deps).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/guess?answer=correct", nil))
+
+	// Assert - 100 points for the answer plus 80% of the 50 point maximum bonus
+	if gameState.TimeBonus != 40 {
+		t.Errorf("Expected a time bonus of 40, got %d", gameState.TimeBonus)
+	}
+	if points := gameState.Players[0].Points; points != 140 {
+		t.Errorf("Expected 140 points, got %d", points)
+	}
+}
+
+// Test_GIVEN_SurvivalGame_WHEN_PlayerLosesLastLife_THEN_ExpectEliminationAndGameOver tests survival rotation
+func Test_GIVEN_SurvivalGame_WHEN_PlayerLosesLastLife_THEN_ExpectEliminationAndGameOver(t *testing.T) {
+	// Arrange - Ben is on his last life, so a wrong answer leaves Anna alone
+	gameState := &GameState{}
+	initializeGameState(gameState, []Player{{Name: "Anna"}, {Name: "Ben"}, {Name: "Cleo"}}, 10, GameSettings{Lives: 2})
+	gameState.Players[1].Lives = 1
+	gameState.Players[2].Lives = 0
+	gameState.Players[2].Eliminated = true
+	gameState.CurrentPlayer = 1
+	gameState.IsCorrect = true
+	deps := &Dependencies{GameState: gameState, Images: NewImageStore(8)}
+
+	// Act
+	guessHandler(deps).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/guess?answer=incorrect", nil))
+	continues := advanceTurn(deps)
+
+	// Assert
+	if !gameState.Players[1].Eliminated {
+		t.Error("Expected Ben to be eliminated")
+	}
+	if continues || !gameState.GameOver {
+		t.Error("Expected the game to be over with one player left")
+	}
+	standings := gameState.Standings()
+	if standings[0].Name != "Anna" || standings[1].Name != "Ben" || standings[2].Name != "Cleo" {
+		t.Errorf("Expected standings Anna, Ben, Cleo, got %s, %s, %s", standings[0].Name, standings[1].Name, standings[2].Name)
+	}
+}
+
+// TestRotateSurvivorsSkipsEliminatedPlayers tests that eliminated players lose their turns
+func TestRotateSurvivorsSkipsEliminatedPlayers(t *testing.T) {
+	gameState := &GameState{}
+	initializeGameState(gameState, []Player{{Name: "Anna"}, {Name: "Ben"}, {Name: "Cleo"}}, 10, GameSettings{Lives: 3})
+	gameState.Players[1].Eliminated = true
+	gameState.ShowResult = true
+	deps := &Dependencies{GameState: gameState}
+
+	advanceTurn(deps)
+	if gameState.CurrentPlayer != 2 {
+		t.Errorf("Expected Cleo's turn, got player %d", gameState.CurrentPlayer)
+	}
+
+	advanceTurn(deps)
+	if gameState.CurrentPlayer != 0 || gameState.CurrentRound != 2 {
+		t.Errorf("Expected Anna's turn in round 2, got player %d in round %d", gameState.CurrentPlayer, gameState.CurrentRound)
+	}
+}
+
+// Test_GIVEN_AStreak_WHEN_AnsweringCorrectly_THEN_ExpectMultiplierAndAchievement tests streak scoring
+func Test_GIVEN_AStreak_WHEN_AnsweringCorrectly_THEN_ExpectMultiplierAndAchievement(t *testing.T) {
+	// Arrange - two right answers already
+	gameState := &GameState{
+		Players:     []Player{{Name: "Anna", Correct: 2, Total: 2, Streak: 2, BestStreak: 2, Achievements: []string{"first-flag"}}},
+		CountryName: "TestCountry",
+		IsCorrect:   true,
+	}
+	deps := &Dependencies{GameState: gameState, Images: NewImageStore(8)}
+
+	// Act
+	guessHandler(deps).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/guess?answer=correct", nil))
+
+	// Assert - third in a row is worth 1.5x and earns the hat trick
+	player := gameState.Players[0]
+	if player.Streak != 3 || player.BestStreak != 3 {
+		t.Errorf("Expected streak and best streak of 3, got %d and %d", player.Streak, player.BestStreak)
+	}
+	if player.Points != 150 {
+		t.Errorf("Expected 150 points, got %d", player.Points)
+	}
+	if len(gameState.NewAchievements) != 1 || gameState.NewAchievements[0].ID != "hat-trick" {
+		t.Errorf("Expected only the hat trick to be newly earned, got %v", gameState.NewAchievements)
+	}
+
+	// A wrong answer resets the streak but keeps the best
+	gameState.ShowResult = false
+	guessHandler(deps).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/guess?answer=incorrect", nil))
+	if player := gameState.Players[0]; player.Streak != 0 || player.BestStreak != 3 {
+		t.Errorf("Expected streak 0 and best streak 3, got %d and %d", player.Streak, player.BestStreak)
+	}
+}
+
+// TestFinishGameAwardsPerfectGame tests the end-of-game achievements
+func TestFinishGameAwardsPerfectGame(t *testing.T) {
+	gameState := &GameState{Players: []Player{
+		{Name: "Anna", Correct: 5, Total: 5},
+		{Name: "Ben", Correct: 4, Incorrect: 1, Total: 5},
+	}}
+
+	finishGame(&Dependencies{GameState: gameState})
+
+	if !gameState.GameOver {
+		t.Error("Expected GameOver to be true")
+	}
+	if !gameState.Players[0].HasAchievement("perfect-game") {
+		t.Error("Expected Anna to earn the perfect game achievement")
+	}
+	if gameState.Players[1].HasAchievement("perfect-game") {
+		t.Error("Expected Ben not to earn the perfect game achievement")
+	}
+}
+
+// TestParseTeams tests building teams from the setup form
+func TestParseTeams(t *testing.T) {
+	teams := parseTeams(
+		[]string{"Red", "", "Empty", "Blue"},
+		[]string{"Anna, Ben", "Cleo", " , ", "Dan"},
+		2,
+	)
+
+	if len(teams) != 2 {
+		t.Fatalf("Expected 2 teams within the limit, got %d", len(teams))
+	}
+	if teams[0].Name != "Red" || len(teams[0].Team.Members) != 2 {
+		t.Errorf("Expected team Red with 2 members, got %s with %v", teams[0].Name, teams[0].Team.Members)
+	}
+	if teams[1].Name != "Cleo's team" {
+		t.Errorf("Expected an unnamed team to be named after its first member, got '%s'", teams[1].Name)
+	}
+}
+
+// Test_GIVEN_TeamGame_WHEN_RotatingTurns_THEN_ExpectMembersToTakeTurns tests rotation inside and across teams
+func Test_GIVEN_TeamGame_WHEN_RotatingTurns_THEN_ExpectMembersToTakeTurns(t *testing.T) {
+	// Arrange
+	gameState := &GameState{}
+	teams := parseTeams([]string{"Red", "Blue"}, []string{"Anna, Ben", "Cleo"}, defaultMaxPlayers)
+	initializeGameState(gameState, teams, 5, GameSettings{})
+	deps := &Dependencies{GameState: gameState}
+
+	// Act - play three turns
+	var turns []string
+	for i := 0; i < 4; i++ {
+		turns = append(turns, gameState.Players[gameState.CurrentPlayer].TurnName())
+		gameState.ShowResult = true
+		advanceTurn(deps)
+	}
+
+	// Assert
+	expected := []string{"Anna (Red)", "Cleo (Blue)", "Ben (Red)", "Cleo (Blue)"}
+	for i := range expected {
+		if turns[i] != expected[i] {
+			t.Errorf("Expected turn %d to be %s, got %s", i+1, expected[i], turns[i])
+		}
+	}
+}
+
+// Test_GIVEN_RunningGame_WHEN_Spectating_THEN_ExpectReadOnlyView tests the spectator page
+func Test_GIVEN_RunningGame_WHEN_Spectating_THEN_ExpectReadOnlyView(t *testing.T) {
+	// Arrange
+	gameState := &GameState{}
+	deps := &Dependencies{
+		GameState:      gameState,
+		CountryService: &MockCountryService{country: CountryFlag{Name: "TestCountry"}},
+		ImageService:   &MockImageService{},
+		Images:         NewImageStore(8),
+	}
+	initializeGameState(gameState, []Player{{Name: "Anna"}}, 3, GameSettings{Mode: ModeTrueFalse})
+	if err := startRound(deps); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/spectate/"+gameState.GameID, nil)
+	req.SetPathValue("gameID", gameState.GameID)
+	spectateHandler(deps).ServeHTTP(rr, req)
+
+	// Assert
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, gameState.FlagSrc) || !strings.Contains(body, "Anna's turn") {
+		t.Error("Expected the current flag and whose turn it is")
+	}
+	if strings.Contains(body, "/guess") || strings.Contains(body, "<button") {
+		t.Error("Expected no controls on the spectator page")
+	}
+}
+
+// Test_GIVEN_PlayersAnswering_WHEN_Spectating_THEN_ExpectNoRace tests that spectators read the game under its lock, run it with -race
+func Test_GIVEN_PlayersAnswering_WHEN_Spectating_THEN_ExpectNoRace(t *testing.T) {
+	// Arrange
+	gameState := &GameState{}
+	deps := &Dependencies{
+		GameState:      gameState,
+		CountryService: &MockCountryService{country: CountryFlag{Name: "TestCountry"}},
+		ImageService:   &MockImageService{},
+		Images:         NewImageStore(8),
+		GameLock:       &sync.Mutex{},
+	}
+	initializeGameState(gameState, []Player{{Name: "Anna"}}, 3, GameSettings{Mode: ModeTrueFalse})
+	if err := startRound(deps); err != nil {
+		t.Fatal(err)
+	}
+	gameID := gameState.GameID
+
+	// Act
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		withGameLock(deps, guessHandler(deps)).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/guess?answer=correct", nil))
+	}()
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/spectate/"+gameID, nil)
+	req.SetPathValue("gameID", gameID)
+	spectateHandler(deps).ServeHTTP(rr, req)
+	<-done
+
+	// Assert
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+}
+
+// slowImageService holds every download until release is closed
+type slowImageService struct {
+	MockImageService
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *slowImageService) DownloadFlag(url string) (image.Image, error) {
+	s.started <- struct{}{}
+	<-s.release
+	return s.MockImageService.DownloadFlag(url)
+}
+
+// Test_GIVEN_SlowFlagDownload_WHEN_DealingNextRound_THEN_ExpectOtherRequestsServed tests that /new downloads without the game's lock, run it with -race
+func Test_GIVEN_SlowFlagDownload_WHEN_DealingNextRound_THEN_ExpectOtherRequestsServed(t *testing.T) {
+	// Arrange
+	gameState := &GameState{}
+	images := &slowImageService{started: make(chan struct{}, 1), release: make(chan struct{})}
+	deps := &Dependencies{
+		GameState:      gameState,
+		CountryService: &MockCountryService{country: CountryFlag{Name: "TestCountry"}},
+		ImageService:   &MockImageService{},
+		Images:         NewImageStore(8),
+		GameLock:       &sync.Mutex{},
+	}
+	initializeGameState(gameState, []Player{{Name: "Anna"}, {Name: "Ben"}}, 3, GameSettings{Mode: ModeTrueFalse})
+	if err := startRound(deps); err != nil {
+		t.Fatal(err)
+	}
+	gameState.ShowResult = true
+	deps.ImageService = images
+
+	// Act
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		newGameHandler(deps).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/new", nil))
+	}()
+	<-images.started
+	page := httptest.NewRecorder()
+	withGameLock(deps, indexHandler(deps)).ServeHTTP(page, httptest.NewRequest("GET", "/", nil))
+	again := httptest.NewRecorder()
+	newGameHandler(deps).ServeHTTP(again, httptest.NewRequest("GET", "/new", nil))
+	close(images.release)
+	<-done
+
+	// Assert
+	if page.Code != http.StatusOK || again.Code != http.StatusSeeOther {
+		t.Errorf("Expected the page and a redirect during the download, got %d and %d", page.Code, again.Code)
+	}
+	if len(images.started) != 0 || gameState.CurrentPlayer != 1 || gameState.ShowResult {
+		t.Errorf("Expected one new round for Ben, got player %d with ShowResult %v", gameState.CurrentPlayer, gameState.ShowResult)
+	}
+}
+
+func TestSpectateUnknownGame(t *testing.T) {
+	deps := &Dependencies{GameState: &GameState{}, Rooms: NewRoomManager()}
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/spectate/nope", nil)
+	req.SetPathValue("gameID", "nope")
+
+	spectateHandler(deps).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rr.Code)
+	}
+}
+
+// Test_GIVEN_RoundsGuessCannotAnswer_WHEN_Guessing_THEN_ExpectNothingScored tests that /guess only answers open real-or-fake rounds
+func Test_GIVEN_RoundsGuessCannotAnswer_WHEN_Guessing_THEN_ExpectNothingScored(t *testing.T) {
+	tests := []struct {
+		name  string
+		state GameState
+	}{
+		{"choice round", GameState{GameSettings: GameSettings{Mode: ModeChoice}, Players: []Player{{Name: "Anna"}}}},
+		{"pick the real one round", GameState{GameSettings: GameSettings{Mode: ModePickReal}, Players: []Player{{Name: "Anna"}}}},
+		{"spot round", GameState{GameSettings: GameSettings{Mode: ModeSpot}, Players: []Player{{Name: "Anna"}}}},
+		{"result already shown", GameState{GameSettings: GameSettings{Mode: ModeTrueFalse}, Players: []Player{{Name: "Anna"}}, ShowResult: true}},
+		{"no players", GameState{GameSettings: GameSettings{Mode: ModeTrueFalse}}},
+	}
+	for _, test := range tests {
+		// Arrange
+		state := test.state
+		state.CountryName, state.IsCorrect = "TestCountry", true
+		deps := &Dependencies{GameState: &state, Images: NewImageStore(8)}
+
+		// Act
+		rr := httptest.NewRecorder()
+		guessHandler(deps).ServeHTTP(rr, httptest.NewRequest("GET", "/guess?answer=correct", nil))
+
+		// Assert
+		if rr.Code != http.StatusSeeOther {
+			t.Errorf("%s: expected a redirect, got %d", test.name, rr.Code)
+		}
+		if state.ShowResult != test.state.ShowResult || len(state.Guesses) != 0 {
+			t.Errorf("%s: expected the round to be left alone", test.name)
+		}
+		for _, player := range state.Players {
+			if player.Correct != 0 || player.Incorrect != 0 || player.Points != 0 {
+				t.Errorf("%s: expected nothing scored, got %+v", test.name, player)
+			}
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const leaderboardWeek = 7 * 24 * time.Hour
+
+// GameStore keeps the finished games so scores outlive the process
+type GameStore interface {
+	Record(game GameRecord) error
+	Games() ([]GameRecord, error)
+}
+
+// PlayerRecord is one scoreboard entry of a finished game
+type PlayerRecord struct {
+	Name       string   `json:"name"`
+	ProfileID  string   `json:"profileId,omitempty"`
+	Members    []string `json:"members,omitempty"`
+	Points     int      `json:"points"`
+	Correct    int      `json:"correct"`
+	Incorrect  int      `json:"incorrect"`
+	BestStreak int      `json:"bestStreak"`
+	Eliminated bool     `json:"eliminated,omitempty"`
+}
+
+// GameRecord is a finished game as it is written to the store
+type GameRecord struct {
+	ID          string         `json:"id"`
+	Settings    GameSettings   `json:"settings"`
+	Rounds      int            `json:"rounds"`
+	TotalRounds int            `json:"totalRounds"`
+	Seed        int64          `json:"seed,omitempty"`
+	Seeded      bool           `json:"seeded,omitempty"`
+	Players     []PlayerRecord `json:"players"`
+	Started     time.Time      `json:"started"`
+	Finished    time.Time      `json:"finished"`
+	Replay      string         `json:"replay,omitempty"`
+}
+
+// newGameRecord captures the game that has just ended
+func newGameRecord(state *GameState, finished time.Time) GameRecord {
+	record := GameRecord{
+		ID:          state.GameID,
+		Settings:    state.GameSettings,
+		Rounds:      state.CurrentRound,
+		TotalRounds: state.TotalRounds,
+		Seed:        state.Seed,
+		Seeded:      state.Seeded,
+		Started:     state.StartedAt,
+		Finished:    finished,
+		Replay:      state.ReplayID,
+	}
+	if state.Lives == 0 && record.Rounds > state.TotalRounds {
+		// local games count one round past the last once everyone has played
+		record.Rounds = state.TotalRounds
+	}
+	if record.Settings.Difficulty == "" {
+		record.Settings.Difficulty = DifficultyNormal
+	}
+	if record.Started.IsZero() {
+		record.Started = finished
+	}
+	for _, player := range state.Players {
+		entry := PlayerRecord{
+			Name:       player.Name,
+			ProfileID:  player.ProfileID,
+			Points:     player.Points,
+			Correct:    player.Correct,
+			Incorrect:  player.Incorrect,
+			BestStreak: player.BestStreak,
+			Eliminated: player.Eliminated,
+		}
+		if player.Team != nil {
+			entry.Members = player.Team.Members
+		}
+		record.Players = append(record.Players, entry)
+	}
+	return record
+}
+
+// recordGame hands a finished game to the game store and the players'
+// profiles, where there are any. A store that cannot be written to must
+// not stop the game from ending
+func recordGame(deps *Dependencies) {
+	state := deps.GameState
+	if len(state.Players) == 0 {
+		return
+	}
+	record := newGameRecord(state, deps.now())
+	if deps.Games != nil {
+		if err := deps.Games.Record(record); err != nil {
+			warnf("⚠️  Could not record game %s: %v", state.GameID, err)
+		}
+	}
+	if deps.Profiles != nil {
+		if err := deps.Profiles.RecordGame(record, state.Guesses); err != nil {
+			warnf("⚠️  Could not update profiles for game %s: %v", state.GameID, err)
+		}
+	}
+}
+
+// FileGameStore appends every finished game to a JSON lines file
+type FileGameStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileGameStore(path string) *FileGameStore {
+	return &FileGameStore{path: path}
+}
+
+func (s *FileGameStore) Record(game GameRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return appendJSONLines(s.path, game)
+}
+
+// Games reads back every recorded game, oldest first
+func (s *FileGameStore) Games() ([]GameRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return readJSONLines[GameRecord](s.path)
+}
+
+// appendJSONLines adds one line of JSON to the file for each value
+func appendJSONLines[T any](path string, values ...T) error {
+	var data []byte
+	for _, value := range values {
+		line, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// readJSONLines parses a JSON lines file. A missing file holds nothing, and
+// a line that cannot be parsed, such as one cut short by a crash, is skipped
+func readJSONLines[T any](path string) ([]T, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var values []T
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64<<10), 4<<20)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var value T
+		if err := json.Unmarshal(line, &value); err != nil {
+			warnf("⚠️  Skipping unreadable line in %s: %v", path, err)
+			continue
+		}
+		values = append(values, value)
+	}
+	return values, scanner.Err()
+}
+
+// LeaderboardFilter narrows the games a leaderboard is built from. Zero
+// values match everything
+type LeaderboardFilter struct {
+	Since      time.Time
+	Mode       GameMode
+	Difficulty Difficulty
+}
+
+func (f LeaderboardFilter) matches(game GameRecord) bool {
+	return (f.Since.IsZero() || !game.Finished.Before(f.Since)) &&
+		(f.Mode == "" || game.Settings.Mode == f.Mode) &&
+		(f.Difficulty == "" || game.Settings.Difficulty == f.Difficulty)
+}
+
+// LeaderboardEntry is a player's record across the games on a leaderboard
+type LeaderboardEntry struct {
+	Player      string
+	Games       int
+	Wins        int
+	BestScore   int
+	TotalPoints int
+	Correct     int
+	Answered    int
+	LastPlayed  time.Time
+}
+
+func (e LeaderboardEntry) Accuracy() int {
+	if e.Answered == 0 {
+		return 0
+	}
+	return e.Correct * 100 / e.Answered
+}
+
+// buildLeaderboard ranks players by their best game, then by the points
+// they have scored in total. Players are matched by profile, or by name
+// without regard to case, and shown under the name they last played as
+func buildLeaderboard(games []GameRecord, filter LeaderboardFilter) []LeaderboardEntry {
+	byName := make(map[string]*LeaderboardEntry)
+	for _, game := range games {
+		if !filter.matches(game) {
+			continue
+		}
+		best := 0
+		for _, player := range game.Players {
+			best = max(best, player.Points)
+		}
+		for _, player := range game.Players {
+			key := profileKey(player.Name)
+			if player.ProfileID != "" {
+				key = player.ProfileID
+			}
+			entry, exists := byName[key]
+			if !exists {
+				entry = &LeaderboardEntry{}
+				byName[key] = entry
+			}
+			if !game.Finished.Before(entry.LastPlayed) {
+				entry.Player = player.Name
+				entry.LastPlayed = game.Finished
+			}
+			entry.Games++
+			if len(game.Players) > 1 && player.Points == best {
+				entry.Wins++
+			}
+			entry.BestScore = max(entry.BestScore, player.Points)
+			entry.TotalPoints += player.Points
+			entry.Correct += player.Correct
+			entry.Answered += player.Correct + player.Incorrect
+		}
+	}
+
+	entries := make([]LeaderboardEntry, 0, len(byName))
+	for _, entry := range byName {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.BestScore != b.BestScore {
+			return a.BestScore > b.BestScore
+		}
+		if a.TotalPoints != b.TotalPoints {
+			return a.TotalPoints > b.TotalPoints
+		}
+		return strings.ToLower(a.Player) < strings.ToLower(b.Player)
+	})
+	return entries
+}
+
+// leaderboardView is what the leaderboard page shows
+type leaderboardView struct {
+	Period       string
+	Mode         GameMode
+	Difficulty   Difficulty
+	Modes        []GameMode
+	Difficulties []Difficulty
+	Entries      []LeaderboardEntry
+}
+
+// leaderboardHandler ranks players all-time or over the last week,
+// optionally for a single mode and difficulty
+func leaderboardHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		view := leaderboardView{
+			Period:       "all",
+			Modes:        []GameMode{ModeTrueFalse, ModeChoice, ModePickReal, ModeSpot},
+			Difficulties: []Difficulty{DifficultyEasy, DifficultyNormal, DifficultyHard},
+		}
+		var filter LeaderboardFilter
+		if r.FormValue("period") == "week" {
+			view.Period = "week"
+			filter.Since = deps.now().Add(-leaderboardWeek)
+		}
+		if mode := r.FormValue("mode"); mode != "" {
+			view.Mode = parseGameMode(mode)
+			filter.Mode = view.Mode
+		}
+		if difficulty := r.FormValue("difficulty"); difficulty != "" {
+			view.Difficulty = parseDifficulty(difficulty)
+			filter.Difficulty = view.Difficulty
+		}
+
+		var games []GameRecord
+		if deps.Games != nil {
+			var err error
+			if games, err = deps.Games.Games(); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		view.Entries = buildLeaderboard(games, filter)
+		renderPage(w, leaderboardTemplate, view)
+	}
+}
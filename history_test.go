@@ -0,0 +1,169 @@
+package main
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileGameStoreRoundTrip(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "history", "games.jsonl")
+	store := NewFileGameStore(path)
+	finished := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	game := GameRecord{
+		ID:       "abc",
+		Settings: GameSettings{Mode: ModeChoice, Difficulty: DifficultyHard, TimeLimit: 10},
+		Rounds:   5,
+		Seed:     42,
+		Seeded:   true,
+		Players:  []PlayerRecord{{Name: "Anna", Points: 300, Correct: 3, Incorrect: 2}},
+		Started:  finished.Add(-time.Minute),
+		Finished: finished,
+	}
+
+	// Act
+	if err := store.Record(game); err != nil {
+		t.Fatal(err)
+	}
+	games, err := store.Games()
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 1 {
+		t.Fatalf("Expected 1 game, got %d", len(games))
+	}
+	got := games[0]
+	if got.ID != "abc" || !reflect.DeepEqual(got.Settings, game.Settings) || got.Seed != 42 || !got.Finished.Equal(finished) || got.Players[0].Points != 300 {
+		t.Errorf("Expected the recorded game back, got %+v", got)
+	}
+}
+
+func TestFileGameStoreSkipsBrokenLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "games.jsonl")
+	store := NewFileGameStore(path)
+	store.Record(GameRecord{ID: "first"})
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	file.WriteString(`{"id": "cut sho` + "\n")
+	file.Close()
+	store.Record(GameRecord{ID: "second"})
+
+	games, err := store.Games()
+
+	if err != nil || len(games) != 2 || games[0].ID != "first" || games[1].ID != "second" {
+		t.Errorf("Expected the two whole records, got %+v (%v)", games, err)
+	}
+}
+
+func TestFileGameStoreWithoutFile(t *testing.T) {
+	games, err := NewFileGameStore(filepath.Join(t.TempDir(), "missing.jsonl")).Games()
+
+	if err != nil || len(games) != 0 {
+		t.Errorf("Expected no games and no error, got %v and %v", games, err)
+	}
+}
+
+// Test_GIVEN_GameHistory_WHEN_BuildingLeaderboards_THEN_ExpectFilteredRankings tests the leaderboard filters
+func Test_GIVEN_GameHistory_WHEN_BuildingLeaderboards_THEN_ExpectFilteredRankings(t *testing.T) {
+	// Arrange
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	games := []GameRecord{
+		{
+			Settings: GameSettings{Mode: ModeTrueFalse, Difficulty: DifficultyNormal},
+			Players:  []PlayerRecord{{Name: "anna", Points: 900, Correct: 9, Incorrect: 1}, {Name: "Ben", Points: 400, Correct: 4, Incorrect: 6}},
+			Finished: now.AddDate(0, 0, -30),
+		},
+		{
+			Settings: GameSettings{Mode: ModeChoice, Difficulty: DifficultyHard},
+			Players:  []PlayerRecord{{Name: "Ben", Points: 700, Correct: 7, Incorrect: 3}, {Name: "Anna", Points: 500, Correct: 5, Incorrect: 5}},
+			Finished: now.AddDate(0, 0, -1),
+		},
+	}
+
+	// Act
+	allTime := buildLeaderboard(games, LeaderboardFilter{})
+	weekly := buildLeaderboard(games, LeaderboardFilter{Since: now.Add(-leaderboardWeek)})
+	trueFalse := buildLeaderboard(games, LeaderboardFilter{Mode: ModeTrueFalse})
+	easy := buildLeaderboard(games, LeaderboardFilter{Difficulty: DifficultyEasy})
+
+	// Assert
+	if len(allTime) != 2 || allTime[0].Player != "Anna" || allTime[0].Games != 2 || allTime[0].BestScore != 900 || allTime[0].Wins != 1 {
+		t.Errorf("Expected Anna first with both games merged, got %+v", allTime)
+	}
+	if allTime[0].Accuracy() != 70 {
+		t.Errorf("Expected Anna's accuracy to be 70%%, got %d%%", allTime[0].Accuracy())
+	}
+	if len(weekly) != 2 || weekly[0].Player != "Ben" || weekly[0].BestScore != 700 {
+		t.Errorf("Expected Ben to lead the last week, got %+v", weekly)
+	}
+	if len(trueFalse) != 2 || trueFalse[0].Player != "anna" || trueFalse[0].Games != 1 {
+		t.Errorf("Expected only the true/false game, got %+v", trueFalse)
+	}
+	if len(easy) != 0 {
+		t.Errorf("Expected no easy games, got %+v", easy)
+	}
+}
+
+// Test_GIVEN_FinishedGame_WHEN_GameEnds_THEN_ExpectGameRecorded tests that finished games are stored
+func Test_GIVEN_FinishedGame_WHEN_GameEnds_THEN_ExpectGameRecorded(t *testing.T) {
+	// Arrange
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	store := NewFileGameStore(filepath.Join(t.TempDir(), "games.jsonl"))
+	state := &GameState{}
+	initializeGameState(state, []Player{{Name: "Anna", Points: 250, Correct: 2, Incorrect: 1}}, 3, GameSettings{Mode: ModeSpot})
+	state.SeedRandom(7)
+	state.StartedAt = now.Add(-2 * time.Minute)
+	state.CurrentRound = 4
+	deps := &Dependencies{GameState: state, Games: store, Now: func() time.Time { return now }}
+
+	// Act
+	finishGame(deps)
+
+	// Assert
+	games, _ := store.Games()
+	if len(games) != 1 {
+		t.Fatalf("Expected 1 recorded game, got %d", len(games))
+	}
+	game := games[0]
+	if game.ID != state.GameID || game.Settings.Mode != ModeSpot || game.Settings.Difficulty != DifficultyNormal {
+		t.Errorf("Unexpected game settings %+v", game)
+	}
+	if game.Rounds != 3 || !game.Seeded || game.Seed != 7 || !game.Started.Equal(state.StartedAt) || !game.Finished.Equal(now) {
+		t.Errorf("Unexpected rounds, seed or timestamps %+v", game)
+	}
+	if len(game.Players) != 1 || game.Players[0].Name != "Anna" || game.Players[0].Points != 250 {
+		t.Errorf("Unexpected players %+v", game.Players)
+	}
+}
+
+func TestLeaderboardHandler(t *testing.T) {
+	// Arrange
+	store := NewFileGameStore(filepath.Join(t.TempDir(), "games.jsonl"))
+	store.Record(GameRecord{
+		Settings: GameSettings{Mode: ModeChoice, Difficulty: DifficultyHard},
+		Players:  []PlayerRecord{{Name: "Anna", Points: 640, Correct: 6, Incorrect: 4}},
+		Finished: time.Now(),
+	})
+	deps := &Dependencies{GameState: &GameState{}, Games: store}
+
+	for _, query := range []string{"", "?period=week", "?mode=choice&difficulty=hard", "?mode=spot"} {
+		// Act
+		w := httptest.NewRecorder()
+		leaderboardHandler(deps).ServeHTTP(w, httptest.NewRequest("GET", "/leaderboard"+query, nil))
+
+		// Assert
+		body := w.Body.String()
+		if w.Code != 200 {
+			t.Fatalf("%q: expected 200, got %d", query, w.Code)
+		}
+		if listed := strings.Contains(body, "640 points"); listed == (query == "?mode=spot") {
+			t.Errorf("%q: unexpected leaderboard %s", query, body)
+		}
+	}
+}
@@ -2,34 +2,47 @@ package main
 
 import (
 	"bytes"
-	"encoding/base64"
+	"fmt"
 	"image"
 	"image/color"
 	"image/png"
 	"io"
-	"log"
+	"math"
 	"math/rand"
 	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
 func downloadFlagImage(url string) (image.Image, error) {
-	resp, err := http.Get(url)
+	data, err := readFlagSource(url)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	data, err := io.ReadAll(resp.Body)
+	img, _, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
 		return nil, err
 	}
 
-	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, nil
+}
+
+// readFlagSource fetches a flag over HTTP, or reads it from disk for a
+// file:// URL
+func readFlagSource(url string) ([]byte, error) {
+	if path, isFile := strings.CutPrefix(url, "file://"); isFile {
+		return os.ReadFile(filepath.FromSlash(path))
+	}
+
+	resp, err := http.Get(url)
 	if err != nil {
 		return nil, err
 	}
-
-	return img, nil
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
 }
 
 type ColorInfo struct {
@@ -37,7 +50,21 @@ type ColorInfo struct {
 	Count int
 }
 
+func colorKey(c color.RGBA) uint32 {
+	return uint32(c.R)<<16 | uint32(c.G)<<8 | uint32(c.B)
+}
+
 func getDistinctColors(img image.Image) []color.RGBA {
+	var colors []color.RGBA
+	for _, info := range distinctColorInfos(img) {
+		colors = append(colors, info.Color)
+	}
+	return colors
+}
+
+// distinctColorInfos returns the flag's main colors, bucketed to steps of
+// 16, with how many pixels each covers
+func distinctColorInfos(img image.Image) []ColorInfo {
 	bounds := img.Bounds()
 	colorMap := make(map[[3]uint8]int)
 
@@ -82,21 +109,17 @@ func getDistinctColors(img image.Image) []color.RGBA {
 		}
 	}
 
-	// Sort by pixel count (most prominent first)
-	for i := 0; i < len(colorInfos); i++ {
-		for j := i + 1; j < len(colorInfos); j++ {
-			if colorInfos[j].Count > colorInfos[i].Count {
-				colorInfos[i], colorInfos[j] = colorInfos[j], colorInfos[i]
-			}
+	// Sort by pixel count (most prominent first). Ties are broken by the color
+	// itself so that seeded games pick the same color every time
+	sort.Slice(colorInfos, func(i, j int) bool {
+		a, b := colorInfos[i], colorInfos[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
 		}
-	}
+		return colorKey(a.Color) < colorKey(b.Color)
+	})
 
-	var colors []color.RGBA
-	for _, info := range colorInfos {
-		colors = append(colors, info.Color)
-	}
-
-	return colors
+	return colorInfos
 }
 
 func colorsAreSimilar(c1, c2 color.RGBA, tolerance uint8) bool {
@@ -224,33 +247,33 @@ func drasticColorChange(originalColor color.RGBA) color.RGBA {
 	return color.RGBA{255 - originalColor.R, 255 - originalColor.G, 255 - originalColor.B, originalColor.A}
 }
 
-func adjustColorShade(originalColor color.RGBA) color.RGBA {
+func adjustColorShade(originalColor color.RGBA, rng *rand.Rand) color.RGBA {
 	h, s, v := rgbToHSV(originalColor.R, originalColor.G, originalColor.B)
 
 	if s < 0.1 {
-		s = 0.4 + rand.Float64()*0.4
-		h = float64(rand.Intn(360))
+		s = 0.4 + rng.Float64()*0.4
+		h = float64(rng.Intn(360))
 	}
 
-	adjustBrightness := rand.Float64() < 0.5
+	adjustBrightness := rng.Float64() < 0.5
 
 	if adjustBrightness {
-		if rand.Float64() < 0.5 {
-			v = v * (0.10 + rand.Float64()*0.15)
+		if rng.Float64() < 0.5 {
+			v = v * (0.10 + rng.Float64()*0.15)
 		} else {
 			v = min(1.0, v+0.35)
 			s = s * 0.3
 		}
 	} else {
-		if rand.Float64() < 0.5 {
-			s = 0.08 + rand.Float64()*0.15
+		if rng.Float64() < 0.5 {
+			s = 0.08 + rng.Float64()*0.15
 		} else {
 			if v >= 0.8 {
-				v = v * (0.18 + rand.Float64()*0.22)
+				v = v * (0.18 + rng.Float64()*0.22)
 			} else if s >= 0.7 {
 				v = min(1.0, v+0.45)
 			} else {
-				s = min(1.0, s*(1.9+rand.Float64()*0.8))
+				s = min(1.0, s*(1.9+rng.Float64()*0.8))
 			}
 		}
 	}
@@ -266,18 +289,98 @@ func min(a, b float64) float64 {
 	return b
 }
 
+// Tamper describes the color change applied to a flag and which pixels it touched
+type Tamper struct {
+	Image          image.Image
+	Mask           *image.Alpha
+	From           color.RGBA
+	To             color.RGBA
+	Drastic        bool
+	ModifiedPixels int
+	DeltaE         float64
+}
+
+// Tamper modes, as recorded with every guess
+const (
+	TamperNone    = "none"
+	TamperDrastic = "drastic"
+	TamperShade   = "shade"
+)
+
+// Mode names the kind of change that was made
+func (t Tamper) Mode() string {
+	if t.Drastic {
+		return TamperDrastic
+	}
+	return TamperShade
+}
+
+// Description explains the change in words for result screens
+func (t Tamper) Description() string {
+	kind := "Shade adjustment"
+	if t.Drastic {
+		kind = "Drastic change"
+	}
+	return fmt.Sprintf("%s: rgb(%d, %d, %d) → rgb(%d, %d, %d)", kind,
+		t.From.R, t.From.G, t.From.B, t.To.R, t.To.G, t.To.B)
+}
+
 func modifyFlagColors(img image.Image, correct bool) image.Image {
 	if correct {
 		return img
 	}
+	return tamperFlag(img).Image
+}
+
+func tamperFlag(img image.Image) Tamper {
+	return tamperFlagWith(img, rand.New(rand.NewSource(rand.Int63())), DifficultyNormal)
+}
+
+// hardTamperBlend is how much of a shade adjustment survives on hard
+// difficulty; the rest of the new color is blended back to the original
+const hardTamperBlend = 0.6
+
+// blendColors moves from towards to by the given fraction
+func blendColors(from, to color.RGBA, fraction float64) color.RGBA {
+	blend := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + (float64(b)-float64(a))*fraction))
+	}
+	return color.RGBA{blend(from.R, to.R), blend(from.G, to.G), blend(from.B, to.B), to.A}
+}
+
+// tamperModesAt lists the kinds of change flags get at a difficulty. Easy
+// games always get a drastic change, hard games only a toned-down shade
+// adjustment
+func tamperModesAt(difficulty Difficulty) []string {
+	switch difficulty {
+	case DifficultyEasy:
+		return []string{TamperDrastic}
+	case DifficultyHard:
+		return []string{TamperShade}
+	}
+	return []string{TamperDrastic, TamperShade}
+}
+
+// tamperFlagWith changes one of the flag's colors, making every random
+// choice from rng
+func tamperFlagWith(img image.Image, rng *rand.Rand, difficulty Difficulty) Tamper {
+	return tamperFlagAs(img, rng, difficulty, "")
+}
+
+// tamperFlagAs is tamperFlagWith with the kind of change forced to mode,
+// unless mode is empty. rng is drawn from the same either way, so one seed
+// picks the same color for every mode
+func tamperFlagAs(img image.Image, rng *rand.Rand, difficulty Difficulty, mode string) Tamper {
+	bounds := img.Bounds()
+	mask := image.NewAlpha(bounds)
 
 	var allColors []color.RGBA = getDistinctColors(img)
 	if len(allColors) == 0 {
-		log.Printf("⚠️  No distinct colors found in image")
-		return img
+		warnf("⚠️  No distinct colors found in image")
+		return Tamper{Image: img, Mask: mask}
 	}
 
-	log.Printf("🎨 Found %d distinct colors to potentially modify", len(allColors))
+	debugf("🎨 Found %d distinct colors to potentially modify", len(allColors))
 
 	var suitableColors []color.RGBA
 	for _, c := range allColors {
@@ -289,35 +392,43 @@ func modifyFlagColors(img image.Image, correct bool) image.Image {
 
 	var colorToBeModified color.RGBA
 	if len(suitableColors) > 0 {
-		randomIndex := rand.Intn(len(suitableColors))
+		randomIndex := rng.Intn(len(suitableColors))
 		colorToBeModified = suitableColors[randomIndex]
-		log.Printf("🎲 Randomly selected color %d out of %d suitable colors", randomIndex+1, len(suitableColors))
+		debugf("🎲 Randomly selected color %d out of %d suitable colors", randomIndex+1, len(suitableColors))
 	}
 
 	if colorToBeModified.R == 0 && colorToBeModified.G == 0 && colorToBeModified.B == 0 && len(allColors) > 0 {
 		colorToBeModified = allColors[0]
-		log.Printf("🎲 Fallback - using most prominent color")
+		debugf("🎲 Fallback - using most prominent color")
 	}
 
-	log.Printf("🎯 Selected color to modify: R=%d, G=%d, B=%d",
+	debugf("🎯 Selected color to modify: R=%d, G=%d, B=%d",
 		colorToBeModified.R, colorToBeModified.G, colorToBeModified.B)
 
-	useDrasticChange := rand.Float64() < 0.5
+	useDrasticChange := rng.Float64() < 0.5
+	if modes := tamperModesAt(difficulty); len(modes) == 1 {
+		useDrasticChange = modes[0] == TamperDrastic
+	}
+	if mode != "" {
+		useDrasticChange = mode == TamperDrastic
+	}
 
 	var newColor color.RGBA
 	if useDrasticChange {
 		newColor = drasticColorChange(colorToBeModified)
-		log.Printf("💥 Applied DRASTIC change: R=%d, G=%d, B=%d -> R=%d, G=%d, B=%d",
+		debugf("💥 Applied DRASTIC change: R=%d, G=%d, B=%d -> R=%d, G=%d, B=%d",
 			colorToBeModified.R, colorToBeModified.G, colorToBeModified.B,
 			newColor.R, newColor.G, newColor.B)
 	} else {
-		newColor = adjustColorShade(colorToBeModified)
-		log.Printf("🎨 Applied SHADE ADJUSTMENT: R=%d, G=%d, B=%d -> R=%d, G=%d, B=%d",
+		newColor = adjustColorShade(colorToBeModified, rng)
+		if difficulty == DifficultyHard {
+			newColor = blendColors(colorToBeModified, newColor, hardTamperBlend)
+		}
+		debugf("🎨 Applied SHADE ADJUSTMENT: R=%d, G=%d, B=%d -> R=%d, G=%d, B=%d",
 			colorToBeModified.R, colorToBeModified.G, colorToBeModified.B,
 			newColor.R, newColor.G, newColor.B)
 	}
 
-	bounds := img.Bounds()
 	modified := image.NewRGBA(bounds)
 	modifiedPixels := 0
 	totalPixels := (bounds.Max.X - bounds.Min.X) * (bounds.Max.Y - bounds.Min.Y)
@@ -351,25 +462,130 @@ func modifyFlagColors(img image.Image, correct bool) image.Image {
 			}
 
 			modified.Set(x, y, newColor)
+			mask.SetAlpha(x, y, color.Alpha{255})
 			modifiedPixels++
 		}
 	}
 
-	log.Printf("✏️  Modified %d out of %d pixels (%.2f%%)",
+	debugf("✏️  Modified %d out of %d pixels (%.2f%%)",
 		modifiedPixels, totalPixels, float64(modifiedPixels)/float64(totalPixels)*100)
 
 	if modifiedPixels == 0 {
-		log.Printf("⚠️  WARNING: No pixels were modified!")
+		warnf("⚠️  WARNING: No pixels were modified!")
 	}
 
-	return modified
+	return Tamper{
+		Image:          modified,
+		Mask:           mask,
+		From:           colorToBeModified,
+		To:             newColor,
+		Drastic:        useDrasticChange,
+		ModifiedPixels: modifiedPixels,
+		DeltaE:         deltaE(colorToBeModified, newColor),
+	}
 }
 
-func imageToBase64(img image.Image) (string, error) {
+func encodePNG(img image.Image) ([]byte, error) {
 	var buf bytes.Buffer
 	err := png.Encode(&buf, img)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// rgbToLab converts an sRGB color to CIE L*a*b* under the D65 white point
+func rgbToLab(c color.RGBA) (l, a, b float64) {
+	linear := func(v uint8) float64 {
+		f := float64(v) / 255.0
+		if f <= 0.04045 {
+			return f / 12.92
+		}
+		return math.Pow((f+0.055)/1.055, 2.4)
+	}
+	r, g, bl := linear(c.R), linear(c.G), linear(c.B)
+
+	x := (0.4124*r + 0.3576*g + 0.1805*bl) / 0.95047
+	y := (0.2126*r + 0.7152*g + 0.0722*bl) / 1.0
+	z := (0.0193*r + 0.1192*g + 0.9505*bl) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 0.008856 {
+			return math.Cbrt(t)
+		}
+		return 7.787*t + 16.0/116.0
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
+
+// deltaE is the CIE76 color difference; around 1 is the smallest change
+// most people can notice
+func deltaE(c1, c2 color.RGBA) float64 {
+	l1, a1, b1 := rgbToLab(c1)
+	l2, a2, b2 := rgbToLab(c2)
+	return math.Sqrt((l1-l2)*(l1-l2) + (a1-a2)*(a1-a2) + (b1-b2)*(b1-b2))
+}
+
+// scaleToWidth resizes an image with nearest-neighbor sampling so that
+// click coordinates can be mapped back onto a known grid
+func scaleToWidth(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() == width || bounds.Dx() == 0 {
+		return img
+	}
+
+	height := bounds.Dy() * width / bounds.Dx()
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			srcX := bounds.Min.X + x*bounds.Dx()/width
+			srcY := bounds.Min.Y + y*bounds.Dy()/height
+			scaled.Set(x, y, img.At(srcX, srcY))
+		}
+	}
+	return scaled
+}
+
+// highlightMask fades everything outside the mask so the changed region stands out
+func highlightMask(img image.Image, mask *image.Alpha) image.Image {
+	bounds := img.Bounds()
+	highlighted := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			c := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+			if mask.AlphaAt(x, y).A == 0 {
+				c.R = uint8((int(c.R) + 3*255) / 4)
+				c.G = uint8((int(c.G) + 3*255) / 4)
+				c.B = uint8((int(c.B) + 3*255) / 4)
+			}
+			highlighted.Set(x, y, c)
+		}
+	}
+	return highlighted
+}
+
+// distanceToMask returns how many pixels the point is from the closest
+// masked pixel, or -1 when the mask is empty
+func distanceToMask(mask *image.Alpha, p image.Point) float64 {
+	if p.In(mask.Rect) && mask.AlphaAt(p.X, p.Y).A > 0 {
+		return 0
+	}
+
+	closest := -1.0
+	for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+		for x := mask.Rect.Min.X; x < mask.Rect.Max.X; x++ {
+			if mask.AlphaAt(x, y).A == 0 {
+				continue
+			}
+			dx := float64(x - p.X)
+			dy := float64(y - p.Y)
+			if d := math.Sqrt(dx*dx + dy*dy); closest < 0 || d < closest {
+				closest = d
+			}
+		}
 	}
-	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+	return closest
 }
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestTamperFlagMaskMatchesChangedPixels tests that the tamper mask covers exactly the recolored pixels
+func TestTamperFlagMaskMatchesChangedPixels(t *testing.T) {
+	// Arrange - a two-striped flag
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			if y < 10 {
+				img.Set(x, y, color.RGBA{200, 16, 46, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 82, 147, 255})
+			}
+		}
+	}
+
+	// Act
+	tamper := tamperFlag(img)
+
+	// Assert
+	changed := 0
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			masked := tamper.Mask.AlphaAt(x, y).A > 0
+			differs := tamper.Image.At(x, y) != img.At(x, y)
+			if masked {
+				changed++
+			}
+			if differs && !masked {
+				t.Fatalf("Pixel (%d, %d) changed but is not in the mask", x, y)
+			}
+		}
+	}
+	if changed != tamper.ModifiedPixels || changed == 0 {
+		t.Errorf("Expected mask to cover %d modified pixels, got %d", tamper.ModifiedPixels, changed)
+	}
+}
+
+// TestDeltaE tests the color difference against known values
+func TestDeltaE(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	if d := deltaE(red, red); d != 0 {
+		t.Errorf("Expected identical colors to have ΔE 0, got %f", d)
+	}
+	if d := deltaE(color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}); math.Abs(d-100) > 0.5 {
+		t.Errorf("Expected black and white to be about 100 ΔE apart, got %f", d)
+	}
+	if d := deltaE(color.RGBA{200, 16, 46, 255}, color.RGBA{201, 16, 46, 255}); d > 1 {
+		t.Errorf("Expected a one-step change to be under 1 ΔE, got %f", d)
+	}
+}
+
+// TestTamperFlagWithSameSeed tests that a seeded tamper can be repeated exactly
+func TestTamperFlagWithSameSeed(t *testing.T) {
+	// Arrange - three equally sized stripes, so only the tie-break orders the colors
+	img := image.NewRGBA(image.Rect(0, 0, 30, 30))
+	stripes := []color.RGBA{{200, 16, 46, 255}, {0, 82, 147, 255}, {0, 150, 57, 255}}
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 30; x++ {
+			img.Set(x, y, stripes[x/10])
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		// Act
+		first := tamperFlagWith(img, rand.New(rand.NewSource(42)), DifficultyNormal)
+		second := tamperFlagWith(img, rand.New(rand.NewSource(42)), DifficultyNormal)
+
+		// Assert
+		if first.From != second.From || first.To != second.To {
+			t.Fatalf("Expected the same tamper for the same seed, got %v->%v and %v->%v", first.From, first.To, second.From, second.To)
+		}
+	}
+}
+
+// Test_GIVEN_Difficulty_WHEN_TamperingFlag_THEN_ExpectMatchingChange tests how difficulty shapes the tamper
+func Test_GIVEN_Difficulty_WHEN_TamperingFlag_THEN_ExpectMatchingChange(t *testing.T) {
+	// Arrange
+	img := image.NewRGBA(image.Rect(0, 0, 30, 30))
+	stripes := []color.RGBA{{200, 16, 46, 255}, {0, 82, 147, 255}, {0, 150, 57, 255}}
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 30; x++ {
+			img.Set(x, y, stripes[x/10])
+		}
+	}
+
+	for seed := int64(0); seed < 10; seed++ {
+		// Act
+		easy := tamperFlagWith(img, rand.New(rand.NewSource(seed)), DifficultyEasy)
+		hard := tamperFlagWith(img, rand.New(rand.NewSource(seed)), DifficultyHard)
+
+		// Assert
+		if !easy.Drastic {
+			t.Errorf("Seed %d: expected easy flags to get a drastic change", seed)
+		}
+		if hard.Drastic {
+			t.Errorf("Seed %d: expected hard flags to get a shade adjustment", seed)
+		}
+	}
+}
+
+func TestBlendColors(t *testing.T) {
+	from, to := color.RGBA{200, 0, 100, 255}, color.RGBA{100, 200, 100, 255}
+
+	if got := blendColors(from, to, hardTamperBlend); got != (color.RGBA{140, 120, 100, 255}) {
+		t.Errorf("Expected rgb(140, 120, 100), got %v", got)
+	}
+	if got := blendColors(from, to, 1); got != to {
+		t.Errorf("Expected a full blend to reach %v, got %v", to, got)
+	}
+}
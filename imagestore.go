@@ -0,0 +1,262 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Variants of a round's flag that can be served from the image store
+const (
+	variantShown     = "shown"
+	variantOriginal  = "original"
+	variantModified  = "modified"
+	variantHighlight = "highlight"
+)
+
+var errImageNotFound = errors.New("image not found")
+
+type storedImage struct {
+	img    image.Image
+	hidden bool
+	png    []byte
+	etag   string
+}
+
+type storedRound struct {
+	images   map[string]*storedImage
+	revealed bool
+}
+
+// imageShelf holds the rounds of every game, so one handler can serve them
+// all
+type imageShelf struct {
+	mu     sync.Mutex
+	rounds map[string]*storedRound
+	games  map[string][]string
+}
+
+// ImageStore keeps the images of recent rounds in memory and encodes them
+// to PNG the first time they are requested. Every game gets its own store
+// from ForGame, and a store only evicts its own rounds, so a busy game
+// cannot drop the flag another game is showing
+type ImageStore struct {
+	*imageShelf
+	game  string
+	limit int
+}
+
+// NewImageStore returns the store of the local game. It keeps the last
+// limit rounds of each game
+func NewImageStore(limit int) *ImageStore {
+	return &ImageStore{
+		imageShelf: &imageShelf{
+			rounds: make(map[string]*storedRound),
+			games:  make(map[string][]string),
+		},
+		limit: limit,
+	}
+}
+
+// ForGame returns a store for another game, sharing the images the flag
+// handler serves but evicting its rounds on their own
+func (s *ImageStore) ForGame() *ImageStore {
+	return &ImageStore{imageShelf: s.imageShelf, game: newRoundID(), limit: s.limit}
+}
+
+// Close drops every round of the game, once nobody can play it any more
+func (s *ImageStore) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, roundID := range s.games[s.game] {
+		delete(s.rounds, roundID)
+	}
+	delete(s.games, s.game)
+}
+
+// NewRound registers a new round and returns its ID, evicting the game's
+// oldest round once it has limit of them
+func (s *ImageStore) NewRound() string {
+	roundID := newRoundID()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.add(roundID, &storedRound{images: make(map[string]*storedImage)})
+	return roundID
+}
+
+// add files a round under the game. The caller must hold s.mu
+func (s *ImageStore) add(roundID string, round *storedRound) {
+	if _, exists := s.rounds[roundID]; !exists {
+		s.games[s.game] = append(s.games[s.game], roundID)
+	}
+	s.rounds[roundID] = round
+	order := s.games[s.game]
+	for s.limit > 0 && len(order) > s.limit {
+		delete(s.rounds, order[0])
+		order = order[1:]
+	}
+	s.games[s.game] = order
+}
+
+// Put stores an image that can be fetched straight away
+func (s *ImageStore) Put(roundID, variant string, img image.Image) {
+	s.put(roundID, variant, img, false)
+}
+
+// PutHidden stores an image that is only served once the round is revealed
+func (s *ImageStore) PutHidden(roundID, variant string, img image.Image) {
+	s.put(roundID, variant, img, true)
+}
+
+func (s *ImageStore) put(roundID, variant string, img image.Image, hidden bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	round, exists := s.rounds[roundID]
+	if !exists {
+		return
+	}
+	round.images[variant] = &storedImage{img: img, hidden: hidden}
+}
+
+// Reveal makes the hidden images of a round available
+func (s *ImageStore) Reveal(roundID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if round, exists := s.rounds[roundID]; exists {
+		round.revealed = true
+	}
+}
+
+// PNG returns the encoded image and its ETag
+func (s *ImageStore) PNG(roundID, variant string) ([]byte, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	round, exists := s.rounds[roundID]
+	if !exists {
+		return nil, "", errImageNotFound
+	}
+	stored, exists := round.images[variant]
+	if !exists || (stored.hidden && !round.revealed) {
+		return nil, "", errImageNotFound
+	}
+
+	if err := stored.encode(); err != nil {
+		return nil, "", err
+	}
+	return stored.png, stored.etag, nil
+}
+
+// encode turns the image into a PNG the first time it is needed
+func (stored *storedImage) encode() error {
+	if stored.png != nil {
+		return nil
+	}
+	data, err := encodePNG(stored.img)
+	if err != nil {
+		return err
+	}
+	stored.setPNG(data)
+	return nil
+}
+
+func (stored *storedImage) setPNG(data []byte) {
+	sum := sha256.Sum256(data)
+	stored.png = data
+	stored.etag = `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// SavedImage is one encoded image of a round, as kept in a snapshot
+type SavedImage struct {
+	Variant string `json:"variant"`
+	Hidden  bool   `json:"hidden,omitempty"`
+	PNG     []byte `json:"png"`
+}
+
+// SaveRound encodes every image of a round, hidden ones included
+func (s *ImageStore) SaveRound(roundID string) ([]SavedImage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	round, exists := s.rounds[roundID]
+	if !exists {
+		return nil, errImageNotFound
+	}
+	saved := make([]SavedImage, 0, len(round.images))
+	for variant, stored := range round.images {
+		if err := stored.encode(); err != nil {
+			return nil, err
+		}
+		saved = append(saved, SavedImage{Variant: variant, Hidden: stored.hidden, PNG: stored.png})
+	}
+	return saved, nil
+}
+
+// RestoreRound puts the images of a saved round back under the same ID, so
+// that the image URLs of a resumed game still work
+func (s *ImageStore) RestoreRound(roundID string, revealed bool, images []SavedImage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	round := &storedRound{images: make(map[string]*storedImage), revealed: revealed}
+	for _, saved := range images {
+		stored := &storedImage{hidden: saved.Hidden}
+		stored.setPNG(saved.PNG)
+		round.images[saved.Variant] = stored
+	}
+	s.add(roundID, round)
+}
+
+func newRoundID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func flagImageURL(roundID, variant string) string {
+	return fmt.Sprintf("/flag/%s/%s.png", roundID, variant)
+}
+
+func flagImageHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		variant, isPNG := strings.CutSuffix(r.PathValue("file"), ".png")
+		if !isPNG {
+			http.NotFound(w, r)
+			return
+		}
+
+		data, etag, err := deps.Images.PNG(r.PathValue("roundID"), variant)
+		if errors.Is(err, errImageNotFound) {
+			w.Header().Set("Cache-Control", "no-store")
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// A round's images never change, so browsers can keep them
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", "private, max-age=86400, immutable")
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Write(data)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serveFlagImage(deps *Dependencies, target string, header http.Header) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /flag/{roundID}/{file}", flagImageHandler(deps))
+
+	req := httptest.NewRequest("GET", target, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	return rr
+}
+
+// TestFlagImageHandlerServesPNG tests that stored flags are served with caching headers
+func TestFlagImageHandlerServesPNG(t *testing.T) {
+	// Arrange
+	deps := &Dependencies{Images: NewImageStore(8)}
+	roundID := deps.Images.NewRound()
+	deps.Images.Put(roundID, variantShown, image.NewRGBA(image.Rect(0, 0, 3, 2)))
+
+	// Act
+	rr := serveFlagImage(deps, flagImageURL(roundID, variantShown), nil)
+
+	// Assert
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	if contentType := rr.Header().Get("Content-Type"); contentType != "image/png" {
+		t.Errorf("Expected Content-Type 'image/png', got '%s'", contentType)
+	}
+	if rr.Header().Get("Cache-Control") == "" {
+		t.Error("Expected Cache-Control header to be set")
+	}
+	etag := rr.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected ETag header to be set")
+	}
+
+	// A second request with the ETag should not resend the image
+	rr = serveFlagImage(deps, flagImageURL(roundID, variantShown), http.Header{"If-None-Match": {etag}})
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("Expected status %d, got %d", http.StatusNotModified, rr.Code)
+	}
+}
+
+// TestFlagImageHandlerHidesOriginalUntilRevealed tests that the answer is not served before the guess
+func TestFlagImageHandlerHidesOriginalUntilRevealed(t *testing.T) {
+	// Arrange
+	deps := &Dependencies{Images: NewImageStore(8)}
+	roundID := deps.Images.NewRound()
+	deps.Images.PutHidden(roundID, variantOriginal, image.NewRGBA(image.Rect(0, 0, 3, 2)))
+
+	// Act & Assert
+	if rr := serveFlagImage(deps, flagImageURL(roundID, variantOriginal), nil); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d before reveal, got %d", http.StatusNotFound, rr.Code)
+	}
+
+	deps.Images.Reveal(roundID)
+	if rr := serveFlagImage(deps, flagImageURL(roundID, variantOriginal), nil); rr.Code != http.StatusOK {
+		t.Errorf("Expected status %d after reveal, got %d", http.StatusOK, rr.Code)
+	}
+}
+
+// TestImageStoreEvictsOldRounds tests that the store only keeps the most recent rounds
+func TestImageStoreEvictsOldRounds(t *testing.T) {
+	store := NewImageStore(2)
+	first := store.NewRound()
+	store.Put(first, variantShown, image.NewRGBA(image.Rect(0, 0, 1, 1)))
+	store.NewRound()
+	store.NewRound()
+
+	if _, _, err := store.PNG(first, variantShown); err != errImageNotFound {
+		t.Errorf("Expected oldest round to be evicted, got err=%v", err)
+	}
+}
+
+// Test_GIVEN_BusyGames_WHEN_StartingRounds_THEN_ExpectOtherGamesKeepTheirFlag tests that games only evict their own rounds
+func Test_GIVEN_BusyGames_WHEN_StartingRounds_THEN_ExpectOtherGamesKeepTheirFlag(t *testing.T) {
+	// Arrange
+	deps := &Dependencies{Images: NewImageStore(2)}
+	quiet := deps.Images.ForGame()
+	live := quiet.NewRound()
+	quiet.Put(live, variantShown, image.NewRGBA(image.Rect(0, 0, 1, 1)))
+
+	// Act
+	for i := 0; i < 10; i++ {
+		busy := deps.Images.ForGame()
+		for j := 0; j < 5; j++ {
+			busy.NewRound()
+		}
+		deps.Images.NewRound()
+	}
+
+	// Assert
+	if rr := serveFlagImage(deps, flagImageURL(live, variantShown), nil); rr.Code != http.StatusOK {
+		t.Errorf("Expected the quiet game's flag to be served, got status %d", rr.Code)
+	}
+	quiet.Close()
+	if rr := serveFlagImage(deps, flagImageURL(live, variantShown), nil); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected the flag to be gone once its game closed, got status %d", rr.Code)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// leitnerIntervals is how many reviews pass before a card in each box comes
+// back. A missed card drops to box 1 and returns almost straight away
+var leitnerIntervals = []int{0, 2, 5, 12, 30, 80}
+
+const masteredBox = 4
+
+// FlashCard is what a player has learned about one flag
+type FlashCard struct {
+	Country      string    `json:"country"`
+	Box          int       `json:"box"`
+	Due          int       `json:"due"`
+	Reviews      int       `json:"reviews"`
+	Lapses       int       `json:"lapses"`
+	LastReviewed time.Time `json:"lastReviewed"`
+}
+
+// LearningState is a player's spaced-repetition progress. Step counts the
+// reviews done so far and is the clock that cards fall due by
+type LearningState struct {
+	Player string                `json:"player"`
+	Step   int                   `json:"step"`
+	Cards  map[string]*FlashCard `json:"cards"`
+}
+
+func NewLearningState(player string) *LearningState {
+	return &LearningState{Player: player, Cards: make(map[string]*FlashCard)}
+}
+
+// Introduce adds a flag the player has just studied to their deck
+func (l *LearningState) Introduce(country string, now time.Time) {
+	if _, exists := l.Cards[country]; exists {
+		return
+	}
+	l.Step++
+	l.Cards[country] = &FlashCard{Country: country, Box: 1, Due: l.Step + leitnerIntervals[1], LastReviewed: now}
+}
+
+// Review moves a card up a box when it was answered correctly, and back to
+// the first box when it was missed
+func (l *LearningState) Review(country string, correct bool, now time.Time) {
+	card, exists := l.Cards[country]
+	if !exists {
+		card = &FlashCard{Country: country}
+		l.Cards[country] = card
+	}
+
+	l.Step++
+	card.Reviews++
+	card.LastReviewed = now
+	if correct {
+		if card.Box < len(leitnerIntervals)-1 {
+			card.Box++
+		}
+	} else {
+		card.Box = 1
+		card.Lapses++
+	}
+	card.Due = l.Step + leitnerIntervals[card.Box]
+}
+
+// Next picks the flag to show next: the most overdue card if any is due,
+// otherwise a flag the player has not seen yet, otherwise the card that
+// comes back soonest. isNew reports whether the flag should be studied
+// before it is quizzed
+func (l *LearningState) Next(countries []string, rng *rand.Rand) (country string, isNew bool) {
+	cards := make([]*FlashCard, 0, len(l.Cards))
+	for _, card := range l.Cards {
+		cards = append(cards, card)
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		if cards[i].Due != cards[j].Due {
+			return cards[i].Due < cards[j].Due
+		}
+		return cards[i].Country < cards[j].Country
+	})
+	if len(cards) > 0 && cards[0].Due <= l.Step {
+		return cards[0].Country, false
+	}
+
+	var unseen []string
+	for _, name := range countries {
+		if _, seen := l.Cards[name]; !seen {
+			unseen = append(unseen, name)
+		}
+	}
+	if len(unseen) > 0 {
+		return unseen[rng.Intn(len(unseen))], true
+	}
+	if len(cards) > 0 {
+		return cards[0].Country, false
+	}
+	return "", false
+}
+
+// Mastered counts the flags that have climbed to the upper boxes
+func (l *LearningState) Mastered() int {
+	mastered := 0
+	for _, card := range l.Cards {
+		if card.Box >= masteredBox {
+			mastered++
+		}
+	}
+	return mastered
+}
+
+// LearningStore keeps every player's learning state as a JSON file
+type LearningStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+func NewLearningStore(dir string) *LearningStore {
+	return &LearningStore{dir: dir}
+}
+
+// learningFileName turns a player name into a safe file name. Names that
+// only differ in letters outside a-z would read the same once made safe, so
+// a short hash of the profile key tells them apart
+func learningFileName(player string) string {
+	key := profileKey(player)
+	var name strings.Builder
+	for _, r := range key {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			name.WriteRune(r)
+		} else {
+			name.WriteRune('_')
+		}
+	}
+	sum := sha256.Sum256([]byte(key))
+	return name.String() + "-" + hex.EncodeToString(sum[:4]) + ".json"
+}
+
+// Load returns the player's saved state, or a fresh one if there is none
+func (s *LearningStore) Load(player string) (*LearningState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(filepath.Join(s.dir, learningFileName(player)))
+	if errors.Is(err, os.ErrNotExist) {
+		return NewLearningState(player), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := NewLearningState(player)
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	if state.Cards == nil {
+		state.Cards = make(map[string]*FlashCard)
+	}
+	return state, nil
+}
+
+// Save writes the state to a temporary file first, so a crash never leaves
+// a half-written file behind
+func (s *LearningStore) Save(state *LearningState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(s.dir, learningFileName(state.Player))
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
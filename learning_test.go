@@ -0,0 +1,171 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// Test_GIVEN_TwoStudiedFlags_WHEN_OneIsMissed_THEN_ExpectItBackFirst tests the spaced repetition schedule
+func Test_GIVEN_TwoStudiedFlags_WHEN_OneIsMissed_THEN_ExpectItBackFirst(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	learning := NewLearningState("Anna")
+	learning.Introduce("Chad", now)
+	learning.Introduce("Romania", now)
+
+	// Act
+	learning.Review("Chad", false, now)
+	learning.Review("Romania", true, now)
+
+	// Assert
+	if chad, romania := learning.Cards["Chad"], learning.Cards["Romania"]; chad.Due >= romania.Due {
+		t.Errorf("Expected the missed flag to be due first, got Chad at %d and Romania at %d", chad.Due, romania.Due)
+	}
+	if learning.Cards["Chad"].Lapses != 1 || learning.Cards["Romania"].Box != 2 {
+		t.Errorf("Unexpected cards %+v %+v", learning.Cards["Chad"], learning.Cards["Romania"])
+	}
+}
+
+func TestLearningNextPrefersDueCardsOverNewOnes(t *testing.T) {
+	learning := NewLearningState("Anna")
+	learning.Introduce("Chad", time.Now())
+	learning.Step += leitnerIntervals[1]
+
+	country, isNew := learning.Next([]string{"Chad", "Romania"}, rand.New(rand.NewSource(1)))
+
+	if country != "Chad" || isNew {
+		t.Errorf("Expected the due card Chad, got %q (new: %v)", country, isNew)
+	}
+}
+
+func TestLearningStoreRoundTrip(t *testing.T) {
+	// Arrange
+	store := NewLearningStore(t.TempDir())
+	learning := NewLearningState("Anna Svensson")
+	learning.Introduce("Sweden", time.Now())
+
+	// Act
+	if err := store.Save(learning); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := store.Load("anna svensson")
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card := loaded.Cards["Sweden"]; card == nil || card.Box != 1 || loaded.Step != 1 {
+		t.Errorf("Expected the saved card to come back, got %+v", loaded)
+	}
+}
+
+// Test_GIVEN_PracticeSession_WHEN_StudyingThenQuizzing_THEN_ExpectProgressSaved tests the study mode end to end
+func Test_GIVEN_PracticeSession_WHEN_StudyingThenQuizzing_THEN_ExpectProgressSaved(t *testing.T) {
+	// Arrange
+	store := NewLearningStore(t.TempDir())
+	deps := &Dependencies{
+		GameState:      &GameState{},
+		CountryService: &MockCountryService{country: CountryFlag{Name: "Austria", Capital: "Vienna"}, similar: []string{"Latvia", "Peru", "Canada"}},
+		ImageService:   &MockImageService{},
+		Images:         NewImageStore(8),
+		Practice:       NewPractice(store),
+	}
+	token, err := deps.Practice.Start(deps, "Anna")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := deps.Practice.Session(token, deps.now())
+
+	// Act - study the only flag, then get quizzed on it
+	if session.card.Phase != practiceStudy {
+		t.Fatalf("Expected a new flag to be studied first, got %q", session.card.Phase)
+	}
+	if err := session.next(deps, store); err != nil {
+		t.Fatal(err)
+	}
+	if session.card.Phase != practiceQuiz || len(session.card.Choices) != choiceCount {
+		t.Fatalf("Expected a quiz with %d choices, got %+v", choiceCount, session.card)
+	}
+	if err := session.answer(deps, store, "Austria"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	saved, _ := store.Load("Anna")
+	if card := saved.Cards["Austria"]; card == nil || card.Box != 2 || card.Reviews != 1 {
+		t.Errorf("Expected the correct answer to be saved, got %+v", card)
+	}
+}
+
+func TestLearningFileNameKeepsNamesApart(t *testing.T) {
+	if learningFileName("Zoë") == learningFileName("Zoé") {
+		t.Error("Expected names that differ outside a-z to get their own files")
+	}
+	if learningFileName(" Anna  Svensson") != learningFileName("anna svensson") {
+		t.Error("Expected the same profile to get the same file")
+	}
+}
+
+// Test_GIVEN_IdleSession_WHEN_AnotherPlayerStarts_THEN_ExpectIdleSessionForgotten tests that practice sessions are cleaned up
+func Test_GIVEN_IdleSession_WHEN_AnotherPlayerStarts_THEN_ExpectIdleSessionForgotten(t *testing.T) {
+	// Arrange
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	deps := &Dependencies{
+		GameState:      &GameState{},
+		CountryService: &MockCountryService{country: CountryFlag{Name: "Austria"}},
+		ImageService:   &MockImageService{},
+		Images:         NewImageStore(8),
+		Practice:       NewPractice(NewLearningStore(t.TempDir())),
+		Now:            func() time.Time { return now },
+	}
+	idle, _ := deps.Practice.Start(deps, "Anna")
+	finished, _ := deps.Practice.Start(deps, "Ben")
+	deps.Practice.End(finished)
+
+	// Act
+	now = now.Add(practiceIdleTimeout + time.Minute)
+	active, err := deps.Practice.Start(deps, "Chloé")
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deps.Practice.Session(idle, now) != nil || deps.Practice.Session(finished, now) != nil {
+		t.Error("Expected the idle and finished sessions to be forgotten")
+	}
+	if len(deps.Practice.sessions) != 1 || deps.Practice.Session(active, now) == nil {
+		t.Errorf("Expected only the new session to be left, got %d", len(deps.Practice.sessions))
+	}
+}
+
+func TestPaletteBreakdownCoversTheFlag(t *testing.T) {
+	// Arrange - a flag that is one quarter blue and three quarters yellow
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			c := color.RGBA{255, 208, 0, 255}
+			if x < 10 {
+				c = color.RGBA{0, 96, 160, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+
+	// Act
+	palette := paletteBreakdown(img)
+
+	// Assert
+	if len(palette) != 2 {
+		t.Fatalf("Expected two colors, got %+v", palette)
+	}
+	if math.Abs(palette[0].Percent-75) > 0.01 || math.Abs(palette[1].Percent-25) > 0.01 {
+		t.Errorf("Expected 75%% and 25%%, got %+v", palette)
+	}
+	if palette[1].Hex() != "#0060a0" {
+		t.Errorf("Expected the blue swatch #0060a0, got %s", palette[1].Hex())
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// LogLevel is how chatty the server's log is
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+var logLevelNames = map[string]LogLevel{
+	"debug": LogDebug,
+	"info":  LogInfo,
+	"warn":  LogWarn,
+	"error": LogError,
+}
+
+// logLevel is set once at startup from --log-level
+var logLevel = LogInfo
+
+func parseLogLevel(name string) (LogLevel, error) {
+	level, exists := logLevelNames[strings.ToLower(strings.TrimSpace(name))]
+	if !exists {
+		return LogInfo, fmt.Errorf("unknown log level %q, expected debug, info, warn or error", name)
+	}
+	return level, nil
+}
+
+func logf(level LogLevel, format string, args ...any) {
+	if level >= logLevel {
+		log.Printf(format, args...)
+	}
+}
+
+func debugf(format string, args ...any) { logf(LogDebug, format, args...) }
+func warnf(format string, args ...any)  { logf(LogWarn, format, args...) }
+func errorf(format string, args ...any) { logf(LogError, format, args...) }
@@ -1,51 +1,97 @@
 package main
 
 import (
-	"fmt"
-	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"sync"
 )
 
-var debugCountry string // Global debug country variable
+var debugCountry string // set by the debug-flag command
 
 func main() {
-	// Check for debug mode with specific country
-	if len(os.Args) > 1 {
-		debugCountry = os.Args[1]
-		fmt.Printf("🐛 DEBUG MODE: Testing with country '%s'\n", debugCountry)
+	os.Exit(runCLI(os.Args[1:], &cli{in: os.Stdin, out: os.Stdout, errOut: os.Stderr, getenv: os.Getenv}))
+}
+
+// registerRoutes adds every page and endpoint of the server to mux
+func registerRoutes(mux *http.ServeMux, deps *Dependencies) {
+	mux.HandleFunc("/", withGameLock(deps, indexHandler(deps)))
+	mux.HandleFunc("/setup", withGameLock(deps, setupPlayersHandler(deps)))
+	mux.HandleFunc("POST /resume", withGameLock(deps, resumeHandler(deps)))
+	mux.HandleFunc("/new", newGameHandler(deps))
+	mux.HandleFunc("/guess", withGameLock(deps, guessHandler(deps)))
+	mux.HandleFunc("/answer", withGameLock(deps, answerHandler(deps)))
+	mux.HandleFunc("/pick", withGameLock(deps, pickHandler(deps)))
+	mux.HandleFunc("/spot", withGameLock(deps, spotHandler(deps)))
+	mux.HandleFunc("GET /flag/{roundID}/{file}", flagImageHandler(deps))
+	mux.HandleFunc("GET /events", eventsHandler(deps))
+	mux.HandleFunc("GET /spectate/{gameID}", spectateHandler(deps))
+	mux.HandleFunc("GET /leaderboard", leaderboardHandler(deps))
+	mux.HandleFunc("GET /stats", statsHandler(deps))
+	mux.HandleFunc("GET /stats/export", statsExportHandler(deps))
+	mux.HandleFunc("GET /games/{id}/review", reviewHandler(deps))
+	mux.HandleFunc("GET /games/{id}/export", replayExportHandler(deps))
+	mux.HandleFunc("/profiles", profilesHandler(deps))
+	mux.HandleFunc("/profiles/{id}", profileHandler(deps))
+	mux.HandleFunc("/daily", dailyHandler(deps))
+	mux.HandleFunc("GET /daily/play", dailyPlayHandler(deps))
+	mux.HandleFunc("POST /daily/guess", dailyGuessHandler(deps))
+	mux.HandleFunc("POST /daily/next", dailyNextHandler(deps))
+	mux.HandleFunc("/practice", practiceHandler(deps))
+	mux.HandleFunc("GET /practice/card", practiceCardHandler(deps))
+	mux.HandleFunc("POST /practice/answer", practiceAnswerHandler(deps))
+	mux.HandleFunc("POST /practice/next", practiceNextHandler(deps))
+	mux.HandleFunc("GET /party", partyHandler(deps))
+	mux.HandleFunc("POST /party", createRoomHandler(deps))
+	mux.HandleFunc("GET /party/{code}", roomHostHandler(deps))
+	mux.HandleFunc("POST /party/{code}/next", roomAdvanceHandler(deps))
+	mux.HandleFunc("GET /party/{code}/events", roomEventsHandler(deps))
+	mux.HandleFunc("GET /party/{code}/ws", roomSocketHandler(deps))
+	registerAPIRoutes(mux, deps)
+	mux.HandleFunc("/join", joinHandler(deps))
+	mux.HandleFunc("GET /play/{code}", playHandler(deps))
+	mux.HandleFunc("POST /play/{code}/answer", playAnswerHandler(deps))
+	if debugCountry != "" {
+		// the tamper gallery is for tuning, so only debug-flag serves it
+		mux.HandleFunc("GET /debug/flags", debugFlagsHandler(deps))
 	}
+}
 
-	// Create dependencies
+// newDependencies wires the services and stores the game runs on
+func newDependencies(cfg Config) *Dependencies {
 	gameState := &GameState{}
-	countryService := NewCountryService()
+	countryService := NewCountryService(cfg.FlagSource)
 	imageService := NewImageService()
+	if cfg.CacheDir != "" {
+		imageService = newCachingImageService(imageService, cfg.CacheDir)
+	}
 
-	deps := &Dependencies{
+	return &Dependencies{
 		GameState:      gameState,
 		CountryService: countryService,
 		ImageService:   imageService,
+		Images:         NewImageStore(4),
+		Palettes:       NewPaletteIndex(),
+		MaxPlayers:     cfg.MaxPlayers,
+		Rooms:          NewRoomManager(),
+		Events:         NewEventBus(),
+		Daily:          NewDailyBoard(filepath.Join(defaultDataDir(), "daily.jsonl")),
+		Practice:       NewPractice(NewLearningStore(filepath.Join(defaultDataDir(), "practice"))),
+		Games:          NewFileGameStore(filepath.Join(defaultDataDir(), "games.jsonl")),
+		Profiles:       NewProfileStore(filepath.Join(defaultDataDir(), "profiles.json")),
+		Guesses:        NewFileGuessStore(filepath.Join(defaultDataDir(), "guesses.jsonl")),
+		Replays:        NewFileReplayStore(filepath.Join(defaultDataDir(), "replays")),
+		Snapshots:      NewSnapshotStore(filepath.Join(defaultDataDir(), "saved-games")),
+		API:            NewAPIGames(),
+		Seed:           cfg.Seed,
+		GameLock:       &sync.Mutex{},
 	}
+}
 
-	// Register handlers with dependency injection
-	http.HandleFunc("/", indexHandler(deps))
-	http.HandleFunc("/setup", setupPlayersHandler(deps))
-	http.HandleFunc("/new", newGameHandler(deps))
-	http.HandleFunc("/guess", guessHandler(deps))
-
-	port := ":8080"
-	url := "http://localhost" + port
-
-	fmt.Printf("Starting Flag Quiz Game server (with DI) on %s\n", url)
-	fmt.Println("Opening browser...")
-
-	// Open browser after a short delay
-	go func() {
-		openBrowser(url)
-	}()
-
-	fmt.Printf("Server running on %s\n", url)
-	fmt.Println("Press Ctrl+C to stop")
-
-	log.Fatal(http.ListenAndServe(port, nil))
+// defaultDataDir is where players' saved progress lives
+func defaultDataDir() string {
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "flagsgui")
+	}
+	return "flagsgui-data"
 }
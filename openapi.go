@@ -0,0 +1,217 @@
+package main
+
+import "net/http"
+
+// openAPISpec describes the JSON API. openapi_test.go checks it against the
+// routes and the structs the handlers write, so the two cannot drift apart
+const openAPISpec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "Flag Quiz API",
+    "description": "Create flag quiz games, answer their rounds and read the scores.",
+    "version": "1.0.0"
+  },
+  "paths": {
+    "/api/v1/games": {
+      "post": {
+        "operationId": "createGame",
+        "summary": "Create a game and deal its first flag",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/CreateGameRequest"}}}
+        },
+        "responses": {
+          "201": {
+            "description": "The new game",
+            "headers": {"Location": {"description": "Where the game can be fetched", "schema": {"type": "string"}}},
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Game"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "502": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/v1/games/{id}": {
+      "parameters": [{"$ref": "#/components/parameters/GameID"}],
+      "get": {
+        "operationId": "getGame",
+        "summary": "Get a game's settings and scores, best first",
+        "responses": {
+          "200": {"description": "The game", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Game"}}}},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/v1/games/{id}/round": {
+      "parameters": [{"$ref": "#/components/parameters/GameID"}],
+      "get": {
+        "operationId": "getRound",
+        "summary": "Get the flag waiting for an answer",
+        "description": "A round whose time ran out counts as a wrong answer, and the next flag is dealt.",
+        "responses": {
+          "200": {"description": "The current round", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Round"}}}},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"},
+          "502": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/v1/games/{id}/answers": {
+      "parameters": [{"$ref": "#/components/parameters/GameID"}],
+      "post": {
+        "operationId": "answerRound",
+        "summary": "Answer the current round, reveal the result and deal the next flag",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/AnswerRequest"}}}
+        },
+        "responses": {
+          "200": {"description": "The result", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/AnswerResult"}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"},
+          "502": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    }
+  },
+  "components": {
+    "parameters": {
+      "GameID": {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}
+    },
+    "responses": {
+      "Error": {
+        "description": "Something went wrong",
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ErrorResponse"}}}
+      }
+    },
+    "schemas": {
+      "GameMode": {"type": "string", "enum": ["truefalse", "choice", "pickreal", "spot"]},
+      "Difficulty": {"type": "string", "enum": ["easy", "normal", "hard"]},
+      "Selection": {"type": "string", "enum": ["uniform", "deck", "weakness"]},
+      "CreateGameRequest": {
+        "type": "object",
+        "required": ["players"],
+        "properties": {
+          "players": {"type": "array", "items": {"type": "string"}, "minItems": 1},
+          "rounds": {"type": "integer", "minimum": 1, "maximum": 100, "default": 10},
+          "mode": {"$ref": "#/components/schemas/GameMode"},
+          "difficulty": {"$ref": "#/components/schemas/Difficulty"},
+          "pickOptions": {"type": "integer", "minimum": 2, "maximum": 4},
+          "timeLimit": {"type": "integer", "minimum": 0, "description": "Seconds per answer, 0 for no limit"},
+          "lives": {"type": "integer", "minimum": 0, "description": "Survival mode when above 0"},
+          "selection": {"$ref": "#/components/schemas/Selection"},
+          "exploration": {"type": "number", "minimum": 0, "maximum": 1},
+          "regions": {"type": "array", "items": {"type": "string"}, "description": "Only countries from these regions"},
+          "seed": {"type": "integer", "format": "int64", "description": "Makes the game repeatable"}
+        }
+      },
+      "GameSettings": {
+        "type": "object",
+        "properties": {
+          "mode": {"$ref": "#/components/schemas/GameMode"},
+          "difficulty": {"$ref": "#/components/schemas/Difficulty"},
+          "pickOptions": {"type": "integer"},
+          "timeLimit": {"type": "integer"},
+          "lives": {"type": "integer"},
+          "selection": {"$ref": "#/components/schemas/Selection"},
+          "exploration": {"type": "number"},
+          "regions": {"type": "array", "items": {"type": "string"}}
+        }
+      },
+      "PlayerScore": {
+        "type": "object",
+        "required": ["name", "points", "correct", "incorrect", "streak", "bestStreak"],
+        "properties": {
+          "name": {"type": "string"},
+          "points": {"type": "integer"},
+          "correct": {"type": "integer"},
+          "incorrect": {"type": "integer"},
+          "streak": {"type": "integer"},
+          "bestStreak": {"type": "integer"},
+          "lives": {"type": "integer"},
+          "eliminated": {"type": "boolean"}
+        }
+      },
+      "Game": {
+        "type": "object",
+        "required": ["id", "status", "settings", "round", "totalRounds", "scores"],
+        "properties": {
+          "id": {"type": "string"},
+          "status": {"type": "string", "enum": ["playing", "finished"]},
+          "settings": {"$ref": "#/components/schemas/GameSettings"},
+          "round": {"type": "integer"},
+          "totalRounds": {"type": "integer"},
+          "seed": {"type": "integer", "format": "int64"},
+          "scores": {"type": "array", "items": {"$ref": "#/components/schemas/PlayerScore"}},
+          "replay": {"type": "string", "description": "ID of the saved replay, once the game is over"}
+        }
+      },
+      "Round": {
+        "type": "object",
+        "required": ["round", "totalRounds", "player", "mode"],
+        "properties": {
+          "round": {"type": "integer"},
+          "totalRounds": {"type": "integer"},
+          "player": {"type": "string", "description": "Whose turn it is"},
+          "mode": {"$ref": "#/components/schemas/GameMode"},
+          "country": {"type": "string", "description": "Left out in choice rounds, where naming it is the question"},
+          "flagUrl": {"type": "string", "description": "PNG of the flag, left out in pickreal rounds"},
+          "choices": {"type": "array", "items": {"type": "string"}, "description": "Country names to choose from in choice rounds"},
+          "options": {"type": "array", "items": {"type": "string"}, "description": "PNG URLs of the flags to pick from in pickreal rounds"},
+          "flagWidth": {"type": "integer"},
+          "flagHeight": {"type": "integer"},
+          "deadline": {"type": "string", "format": "date-time"}
+        }
+      },
+      "AnswerRequest": {
+        "type": "object",
+        "required": ["answer"],
+        "properties": {
+          "answer": {
+            "type": "string",
+            "description": "\"correct\" or \"incorrect\" in truefalse rounds, a country name in choice rounds, a 0-based option in pickreal rounds and an \"x,y\" click in spot rounds"
+          }
+        }
+      },
+      "AnswerResult": {
+        "type": "object",
+        "required": ["correct", "expired", "points", "message", "country", "real", "gameOver"],
+        "properties": {
+          "correct": {"type": "boolean"},
+          "expired": {"type": "boolean", "description": "Whether the answer came after the round's deadline, in which case it was not counted"},
+          "points": {"type": "integer"},
+          "message": {"type": "string"},
+          "country": {"type": "string"},
+          "real": {"type": "boolean", "description": "Whether the flag shown was the real one"},
+          "tamperDescription": {"type": "string"},
+          "deltaE": {"type": "number"},
+          "originalUrl": {"type": "string"},
+          "gameOver": {"type": "boolean"}
+        }
+      },
+      "Error": {
+        "type": "object",
+        "required": ["code", "message"],
+        "properties": {
+          "code": {
+            "type": "string",
+            "enum": ["invalid_json", "invalid_game", "invalid_answer", "game_not_found", "game_over", "flag_unavailable", "not_found"]
+          },
+          "message": {"type": "string"}
+        }
+      },
+      "ErrorResponse": {
+        "type": "object",
+        "required": ["error"],
+        "properties": {"error": {"$ref": "#/components/schemas/Error"}}
+      }
+    }
+  }
+}
+`
+
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(openAPISpec))
+}
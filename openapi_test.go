@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"FlagsGUI/client"
+)
+
+type openAPISchema struct {
+	Ref        string                   `json:"$ref"`
+	Required   []string                 `json:"required"`
+	Properties map[string]openAPISchema `json:"properties"`
+	Items      *openAPISchema           `json:"items"`
+}
+
+type openAPIMedia struct {
+	Schema openAPISchema `json:"schema"`
+}
+
+type openAPIResponse struct {
+	Ref     string                  `json:"$ref"`
+	Content map[string]openAPIMedia `json:"content"`
+}
+
+type openAPIOperation struct {
+	Responses map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                                `json:"openapi"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas   map[string]openAPISchema   `json:"schemas"`
+		Responses map[string]openAPIResponse `json:"responses"`
+	} `json:"components"`
+}
+
+func loadOpenAPIDocument(t *testing.T) openAPIDocument {
+	var doc openAPIDocument
+	if err := json.Unmarshal([]byte(openAPISpec), &doc); err != nil {
+		t.Fatalf("The OpenAPI document is not valid JSON: %v", err)
+	}
+	return doc
+}
+
+// schemaFor resolves the schema a response is documented with
+func (doc openAPIDocument) schemaFor(response openAPIResponse) openAPISchema {
+	if name, isRef := strings.CutPrefix(response.Ref, "#/components/responses/"); isRef {
+		response = doc.Components.Responses[name]
+	}
+	schema := response.Content["application/json"].Schema
+	return doc.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+}
+
+func jsonFieldNames(value any) []string {
+	var names []string
+	valueType := reflect.TypeOf(value)
+	for i := 0; i < valueType.NumField(); i++ {
+		name, _, _ := strings.Cut(valueType.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func schemaPropertyNames(schema openAPISchema) []string {
+	var names []string
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TestOpenAPISchemasMatchStructs tests that every schema lists exactly the fields of the structs the server and client use
+func TestOpenAPISchemasMatchStructs(t *testing.T) {
+	// Arrange
+	doc := loadOpenAPIDocument(t)
+	schemas := map[string][]any{
+		"CreateGameRequest": {CreateGameRequest{}, client.CreateGameRequest{}},
+		"GameSettings":      {GameSettings{}, client.GameSettings{}},
+		"PlayerScore":       {PlayerScore{}, client.PlayerScore{}},
+		"Game":              {GameResponse{}, client.Game{}},
+		"Round":             {RoundResponse{}, client.Round{}},
+		"AnswerRequest":     {AnswerRequest{}, client.AnswerRequest{}},
+		"AnswerResult":      {AnswerResponse{}, client.AnswerResult{}},
+		"Error":             {APIError{}, client.Error{}},
+		"ErrorResponse":     {apiErrorResponse{}},
+	}
+
+	for name, structs := range schemas {
+		schema, exists := doc.Components.Schemas[name]
+		if !exists {
+			t.Errorf("Expected a %s schema", name)
+			continue
+		}
+		properties := schemaPropertyNames(schema)
+		for _, value := range structs {
+			// Act
+			fields := jsonFieldNames(value)
+
+			// Assert
+			if !reflect.DeepEqual(fields, properties) {
+				t.Errorf("%s: %T has fields %v but the schema has %v", name, value, fields, properties)
+			}
+		}
+		for _, required := range schema.Required {
+			if _, exists := schema.Properties[required]; !exists {
+				t.Errorf("%s: required property %q is not described", name, required)
+			}
+		}
+	}
+}
+
+// Test_GIVEN_OpenAPIDocument_WHEN_CallingEveryOperation_THEN_ExpectDocumentedResponses tests the paths against the handlers
+func Test_GIVEN_OpenAPIDocument_WHEN_CallingEveryOperation_THEN_ExpectDocumentedResponses(t *testing.T) {
+	// Arrange
+	doc := loadOpenAPIDocument(t)
+	mux, _ := newAPITestMux()
+	var game GameResponse
+	callAPI(mux, "POST", "/api/v1/games", `{"players": ["Anna"], "rounds": 3}`, &game)
+	bodies := map[string]string{
+		"POST /api/v1/games":              `{"players": ["Ben"]}`,
+		"POST /api/v1/games/{id}/answers": `{"answer": "correct"}`,
+	}
+
+	operations := 0
+	for path, methods := range doc.Paths {
+		for method, raw := range methods {
+			if method == "parameters" {
+				continue
+			}
+			var operation openAPIOperation
+			if err := json.Unmarshal(raw, &operation); err != nil {
+				t.Fatalf("%s %s: %v", method, path, err)
+			}
+			operations++
+			key := strings.ToUpper(method) + " " + path
+
+			// Act
+			var body map[string]any
+			response := callAPI(mux, strings.ToUpper(method), strings.ReplaceAll(path, "{id}", game.ID), bodies[key], &body)
+
+			// Assert
+			documented, exists := operation.Responses[strconv.Itoa(response.Code)]
+			if !exists {
+				t.Errorf("%s: status %d is not documented (%s)", key, response.Code, response.Body.String())
+				continue
+			}
+			schema := doc.schemaFor(documented)
+			for field := range body {
+				if _, described := schema.Properties[field]; !described {
+					t.Errorf("%s: field %q of the %d response is not in its schema", key, field, response.Code)
+				}
+			}
+			for _, required := range schema.Required {
+				if _, present := body[required]; !present {
+					t.Errorf("%s: required field %q is missing from the %d response", key, required, response.Code)
+				}
+			}
+		}
+	}
+	if operations != 4 {
+		t.Errorf("Expected 4 documented operations, got %d", operations)
+	}
+
+	served := httptest.NewRecorder()
+	mux.ServeHTTP(served, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	if served.Code != 200 || served.Body.String() != openAPISpec {
+		t.Errorf("Expected the document at /api/openapi.json, got %d", served.Code)
+	}
+}
+
+// Test_GIVEN_GoClient_WHEN_PlayingAGame_THEN_ExpectTypedResults tests the client package against the real handlers
+func Test_GIVEN_GoClient_WHEN_PlayingAGame_THEN_ExpectTypedResults(t *testing.T) {
+	// Arrange
+	mux, _ := newAPITestMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	api := client.New(server.URL + "/")
+	ctx := context.Background()
+	seed := int64(3)
+
+	// Act
+	game, err := api.CreateGame(ctx, client.CreateGameRequest{Players: []string{"Anna", "Ben"}, Rounds: 1, Seed: &seed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for !game.Finished() {
+		round, err := api.Round(ctx, game.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := api.Image(ctx, round.FlagURL); err != nil {
+			t.Errorf("Expected the flag of round %d, got %v", round.Round, err)
+		}
+		if _, err := api.Answer(ctx, game.ID, client.AnswerFake); err != nil {
+			t.Fatal(err)
+		}
+		if game, err = api.Game(ctx, game.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, roundErr := api.Round(ctx, game.ID)
+	_, missingErr := api.Game(ctx, "nope")
+
+	// Assert
+	if len(game.Scores) != 2 || game.Scores[0].Correct+game.Scores[0].Incorrect != 1 || *game.Seed != 3 {
+		t.Errorf("Expected one answer from each player, got %+v", game)
+	}
+	if apiErr, ok := roundErr.(*client.Error); !ok || apiErr.Status != 409 || apiErr.Code != "game_over" {
+		t.Errorf("Expected a game_over error, got %v", roundErr)
+	}
+	if apiErr, ok := missingErr.(*client.Error); !ok || apiErr.Status != 404 || apiErr.Code != "game_not_found" {
+		t.Errorf("Expected a game_not_found error, got %v", missingErr)
+	}
+}
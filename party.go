@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+func roomCookieName(code string) string {
+	return "flagsgui_party_" + code
+}
+
+func hostCookieName(code string) string {
+	return "flagsgui_host_" + code
+}
+
+func cookieValue(r *http.Request, name string) string {
+	if cookie, err := r.Cookie(name); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+func setRoomCookie(w http.ResponseWriter, name, value string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func joinURL(r *http.Request, code string) string {
+	return fmt.Sprintf("http://%s/join?code=%s", r.Host, code)
+}
+
+func renderPage(w http.ResponseWriter, page string, data any) {
+	tmpl, err := parseGameTemplate(page)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	tmpl.Execute(w, data)
+}
+
+func renderRoom(w http.ResponseWriter, r *http.Request, room *Room, page string, me int) {
+	tmpl, err := parseGameTemplate(page)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	room.Render(w, tmpl, joinURL(r, room.Code), me)
+}
+
+// partyHandler shows the form for opening a party room
+func partyHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		renderPage(w, partyCreateTemplate, nil)
+	}
+}
+
+func createRoomHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		settings := GameSettings{
+			Mode:        parsePartyMode(r.FormValue("mode")),
+			Difficulty:  parseDifficulty(r.FormValue("difficulty")),
+			PickOptions: parsePickOptions(r.FormValue("pickOptions")),
+			TimeLimit:   parseTimeLimit(r.FormValue("timeLimit")),
+			Selection:   parseSelection(r.FormValue("selection")),
+		}
+		if settings.Selection == SelectWeakness {
+			settings.Exploration = defaultExploration
+		}
+		room := deps.Rooms.Create(deps, settings, parseRoundsCount(r.FormValue("numRounds")))
+
+		setRoomCookie(w, hostCookieName(room.Code), room.hostToken)
+		http.Redirect(w, r, "/party/"+room.Code, http.StatusSeeOther)
+	}
+}
+
+// parsePartyMode only allows the modes that can be answered from a phone
+// without seeing the flag up close
+func parsePartyMode(modeStr string) GameMode {
+	if mode := parseGameMode(modeStr); mode != ModeSpot {
+		return mode
+	}
+	return ModeTrueFalse
+}
+
+// roomHostHandler shows the host screen with the flag and who has answered
+func roomHostHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		room := deps.Rooms.Get(r.PathValue("code"))
+		if room == nil || !room.IsHost(cookieValue(r, hostCookieName(room.Code))) {
+			http.NotFound(w, r)
+			return
+		}
+		renderRoom(w, r, room, partyHostTemplate, -1)
+	}
+}
+
+// roomAdvanceHandler starts the next round when the host asks for it
+func roomAdvanceHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		room := deps.Rooms.Get(r.PathValue("code"))
+		if room == nil || !room.IsHost(cookieValue(r, hostCookieName(room.Code))) {
+			http.NotFound(w, r)
+			return
+		}
+		if err := room.Advance(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/party/"+room.Code, http.StatusSeeOther)
+	}
+}
+
+type joinView struct {
+	Code  string
+	Name  string
+	Error string
+}
+
+// joinHandler lets a player enter the room code and their name from their own device
+func joinHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		view := joinView{Code: strings.ToUpper(r.FormValue("code")), Name: r.FormValue("name")}
+		if r.Method != "POST" {
+			renderPage(w, partyJoinTemplate, view)
+			return
+		}
+
+		room := deps.Rooms.Get(view.Code)
+		if room == nil {
+			view.Error = "There is no room with that code."
+			renderPage(w, partyJoinTemplate, view)
+			return
+		}
+		if room.PlayerIndex(cookieValue(r, roomCookieName(room.Code))) >= 0 {
+			// this device already has a seat in the room
+			http.Redirect(w, r, "/play/"+room.Code, http.StatusSeeOther)
+			return
+		}
+
+		token, err := room.Join(view.Name)
+		if err != nil {
+			view.Error = err.Error()
+			renderPage(w, partyJoinTemplate, view)
+			return
+		}
+		setRoomCookie(w, roomCookieName(room.Code), token)
+		http.Redirect(w, r, "/play/"+room.Code, http.StatusSeeOther)
+	}
+}
+
+// playHandler shows a player's controller for the current round
+func playHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		room := deps.Rooms.Get(r.PathValue("code"))
+		if room == nil {
+			http.Redirect(w, r, "/join", http.StatusSeeOther)
+			return
+		}
+		me := room.PlayerIndex(cookieValue(r, roomCookieName(room.Code)))
+		if me < 0 {
+			http.Redirect(w, r, "/join?code="+room.Code, http.StatusSeeOther)
+			return
+		}
+		renderRoom(w, r, room, partyPlayTemplate, me)
+	}
+}
+
+func playAnswerHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		room := deps.Rooms.Get(r.PathValue("code"))
+		if room == nil {
+			http.Redirect(w, r, "/join", http.StatusSeeOther)
+			return
+		}
+		room.Submit(cookieValue(r, roomCookieName(room.Code)), r.FormValue("answer"))
+		http.Redirect(w, r, "/play/"+room.Code, http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	practiceCookieName  = "flagsgui_practice"
+	practiceIdleTimeout = 2 * time.Hour
+)
+
+// Phases of a practice card
+const (
+	practiceStudy    = "study"
+	practiceQuiz     = "quiz"
+	practiceAnswered = "answered"
+)
+
+// ColorShare is one of a flag's colors and how much of the flag it covers
+type ColorShare struct {
+	Color   color.RGBA
+	Percent float64
+}
+
+func (c ColorShare) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.Color.R, c.Color.G, c.Color.B)
+}
+
+// paletteBreakdown splits a flag into its main colors from getDistinctColors'
+// buckets, with the share of the flag each one covers
+func paletteBreakdown(img image.Image) []ColorShare {
+	infos := distinctColorInfos(img)
+	total := 0
+	for _, info := range infos {
+		total += info.Count
+	}
+
+	shares := make([]ColorShare, len(infos))
+	for i, info := range infos {
+		shares[i] = ColorShare{Color: info.Color, Percent: 100 * float64(info.Count) / float64(total)}
+	}
+	return shares
+}
+
+// practiceCard is the flag currently in front of a studying player
+type practiceCard struct {
+	Country CountryFlag
+	FlagSrc string
+	Palette []ColorShare
+	Phase   string
+	Choices []string
+	Chosen  string
+	Correct bool
+}
+
+type practiceSession struct {
+	mu         sync.Mutex
+	learning   *LearningState
+	card       practiceCard
+	rng        *rand.Rand
+	images     *ImageStore
+	lastActive time.Time // guarded by the Practice's lock
+}
+
+// Practice runs the unscored study mode and remembers what each player has learned
+type Practice struct {
+	mu       sync.Mutex
+	store    *LearningStore
+	sessions map[string]*practiceSession
+}
+
+func NewPractice(store *LearningStore) *Practice {
+	return &Practice{store: store, sessions: make(map[string]*practiceSession)}
+}
+
+// Start picks up where the player left off last time and returns the
+// token of their session
+func (p *Practice) Start(deps *Dependencies, name string) (string, error) {
+	name = normalizeDisplayName(name)
+	if name == "" {
+		return "", errNameRequired
+	}
+	learning, err := p.store.Load(name)
+	if err != nil {
+		return "", err
+	}
+
+	session := &practiceSession{
+		learning:   learning,
+		rng:        rand.New(rand.NewSource(deps.now().UnixNano())),
+		images:     deps.Images.ForGame(),
+		lastActive: deps.now(),
+	}
+	if err := session.nextCard(deps); err != nil {
+		session.images.Close()
+		return "", err
+	}
+
+	token := newToken()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pruneIdle(deps.now())
+	p.sessions[token] = session
+	return token, nil
+}
+
+// Session returns the session the token belongs to and marks it as in use
+func (p *Practice) Session(token string, now time.Time) *practiceSession {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	session := p.sessions[token]
+	if session != nil {
+		session.lastActive = now
+	}
+	return session
+}
+
+// End forgets a session the player has finished with
+func (p *Practice) End(token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if session := p.sessions[token]; session != nil {
+		session.images.Close()
+	}
+	delete(p.sessions, token)
+}
+
+// pruneIdle forgets the sessions nobody has used for practiceIdleTimeout.
+// Their progress is saved after every card, so nothing is lost. The caller
+// must hold p.mu
+func (p *Practice) pruneIdle(now time.Time) {
+	for token, session := range p.sessions {
+		if now.Sub(session.lastActive) > practiceIdleTimeout {
+			delete(p.sessions, token)
+			session.images.Close()
+		}
+	}
+}
+
+// nextCard deals the card the spaced repetition schedule asks for. The
+// caller must hold the session's lock
+func (s *practiceSession) nextCard(deps *Dependencies) error {
+	countries := deps.CountryService.AllCountries()
+	names := make([]string, len(countries))
+	byName := make(map[string]CountryFlag, len(countries))
+	for i, country := range countries {
+		names[i] = country.Name
+		byName[country.Name] = country
+	}
+
+	name, isNew := s.learning.Next(names, s.rng)
+	country, exists := byName[name]
+	if !exists {
+		return fmt.Errorf("no flag to practice")
+	}
+	img, err := deps.ImageService.DownloadFlag(country.FlagURL)
+	if err != nil {
+		return fmt.Errorf("failed to download flag for %s: %w", country.Name, err)
+	}
+	if deps.Palettes != nil {
+		deps.Palettes.Add(country.Name, img)
+	}
+
+	roundID := s.images.NewRound()
+	s.images.Put(roundID, variantShown, img)
+	s.card = practiceCard{
+		Country: country,
+		FlagSrc: flagImageURL(roundID, variantShown),
+		Palette: paletteBreakdown(img),
+		Phase:   practiceStudy,
+	}
+	if !isNew {
+		s.card.Phase = practiceQuiz
+		s.card.Choices = append([]string{country.Name}, pickDistractors(deps, country, choiceCount-1, s.rng)...)
+		s.rng.Shuffle(len(s.card.Choices), func(i, j int) {
+			s.card.Choices[i], s.card.Choices[j] = s.card.Choices[j], s.card.Choices[i]
+		})
+	}
+	return nil
+}
+
+// answer checks the player's pick for a quiz card and reschedules the card
+func (s *practiceSession) answer(deps *Dependencies, store *LearningStore, choice string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.card.Phase != practiceQuiz {
+		return nil
+	}
+	s.card.Chosen = choice
+	s.card.Correct = choice == s.card.Country.Name
+	s.card.Phase = practiceAnswered
+	s.learning.Review(s.card.Country.Name, s.card.Correct, deps.now())
+	return store.Save(s.learning)
+}
+
+// next moves on from a studied or answered card
+func (s *practiceSession) next(deps *Dependencies, store *LearningStore) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch s.card.Phase {
+	case practiceStudy:
+		s.learning.Introduce(s.card.Country.Name, deps.now())
+		if err := store.Save(s.learning); err != nil {
+			return err
+		}
+	case practiceQuiz:
+		return nil
+	}
+	return s.nextCard(deps)
+}
+
+// practiceView is what the practice page shows
+type practiceView struct {
+	Player   string
+	Card     practiceCard
+	Seen     int
+	Mastered int
+	Error    string
+}
+
+func practiceSessionFromCookie(deps *Dependencies, r *http.Request) *practiceSession {
+	return deps.Practice.Session(cookieValue(r, practiceCookieName), deps.now())
+}
+
+// practiceHandler asks who is studying, then hands over to their cards
+func practiceHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		view := practiceView{Player: r.FormValue("name")}
+		if r.Method == "POST" {
+			// switching players finishes the old session
+			deps.Practice.End(cookieValue(r, practiceCookieName))
+			token, err := deps.Practice.Start(deps, view.Player)
+			if err == nil {
+				setRoomCookie(w, practiceCookieName, token)
+				http.Redirect(w, r, "/practice/card", http.StatusSeeOther)
+				return
+			}
+			view.Error = err.Error()
+		} else if r.FormValue("switch") == "" && practiceSessionFromCookie(deps, r) != nil {
+			http.Redirect(w, r, "/practice/card", http.StatusSeeOther)
+			return
+		}
+		renderPage(w, practiceStartTemplate, view)
+	}
+}
+
+func practiceCardHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session := practiceSessionFromCookie(deps, r)
+		if session == nil {
+			http.Redirect(w, r, "/practice", http.StatusSeeOther)
+			return
+		}
+
+		session.mu.Lock()
+		view := practiceView{
+			Player:   session.learning.Player,
+			Card:     session.card,
+			Seen:     len(session.learning.Cards),
+			Mastered: session.learning.Mastered(),
+		}
+		session.mu.Unlock()
+		renderPage(w, practiceTemplate, view)
+	}
+}
+
+func practiceAnswerHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if session := practiceSessionFromCookie(deps, r); session != nil {
+			if err := session.answer(deps, deps.Practice.store, r.FormValue("choice")); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		http.Redirect(w, r, "/practice/card", http.StatusSeeOther)
+	}
+}
+
+func practiceNextHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if session := practiceSessionFromCookie(deps, r); session != nil {
+			if err := session.next(deps, deps.Practice.store); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		http.Redirect(w, r, "/practice/card", http.StatusSeeOther)
+	}
+}
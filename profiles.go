@@ -0,0 +1,421 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxDisplayName = 30
+
+// avatarColors are handed out to new profiles in turn
+var avatarColors = []string{"#e74c3c", "#3498db", "#27ae60", "#f39c12", "#9b59b6", "#1abc9c", "#e67e22", "#34495e"}
+
+var (
+	errProfileNotFound = errors.New("no such profile")
+	errInvalidColor    = errors.New("colors must look like #1a2b3c")
+)
+
+// normalizeDisplayName trims a name, squeezes its inner spaces and caps its
+// length, so that "Anna" and " Anna " are the same person
+func normalizeDisplayName(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if runes := []rune(name); len(runes) > maxDisplayName {
+		name = string(runes[:maxDisplayName])
+	}
+	return name
+}
+
+// profileKey is what two names must share to belong to the same profile
+func profileKey(name string) string {
+	return strings.ToLower(normalizeDisplayName(name))
+}
+
+func validAvatarColor(color string) bool {
+	if len(color) != 7 || color[0] != '#' {
+		return false
+	}
+	for _, c := range strings.ToLower(color[1:]) {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// CountryAccuracy is how a player has done on one country's flag
+type CountryAccuracy struct {
+	Country  string    `json:"-"`
+	Correct  int       `json:"correct"`
+	Attempts int       `json:"attempts"`
+	LastSeen time.Time `json:"lastSeen,omitempty"`
+}
+
+func (c CountryAccuracy) Percentage() int {
+	if c.Attempts == 0 {
+		return 0
+	}
+	return c.Correct * 100 / c.Attempts
+}
+
+// ProfileStats are a player's lifetime totals
+type ProfileStats struct {
+	Games      int `json:"games"`
+	Wins       int `json:"wins"`
+	Points     int `json:"points"`
+	BestScore  int `json:"bestScore"`
+	BestStreak int `json:"bestStreak"`
+	Correct    int `json:"correct"`
+	Incorrect  int `json:"incorrect"`
+}
+
+func (s ProfileStats) Accuracy() int {
+	if s.Correct+s.Incorrect == 0 {
+		return 0
+	}
+	return s.Correct * 100 / (s.Correct + s.Incorrect)
+}
+
+// Profile is a player who is recognised from game to game
+type Profile struct {
+	ID        string                      `json:"id"`
+	Name      string                      `json:"name"`
+	Color     string                      `json:"color"`
+	Created   time.Time                   `json:"created"`
+	LastSeen  time.Time                   `json:"lastSeen"`
+	Stats     ProfileStats                `json:"stats"`
+	Countries map[string]*CountryAccuracy `json:"countries"`
+}
+
+// CountryAccuracies lists the countries the player has seen, weakest first
+func (p Profile) CountryAccuracies() []CountryAccuracy {
+	list := make([]CountryAccuracy, 0, len(p.Countries))
+	for country, accuracy := range p.Countries {
+		entry := *accuracy
+		entry.Country = country
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if a, b := list[i].Percentage(), list[j].Percentage(); a != b {
+			return a < b
+		}
+		if list[i].Attempts != list[j].Attempts {
+			return list[i].Attempts > list[j].Attempts
+		}
+		return list[i].Country < list[j].Country
+	})
+	return list
+}
+
+func (p *Profile) clone() Profile {
+	copied := *p
+	copied.Countries = make(map[string]*CountryAccuracy, len(p.Countries))
+	for country, accuracy := range p.Countries {
+		entry := *accuracy
+		copied.Countries[country] = &entry
+	}
+	return copied
+}
+
+// ProfileStore keeps every profile in one JSON file
+type ProfileStore struct {
+	mu       sync.Mutex
+	path     string
+	profiles map[string]*Profile
+}
+
+func NewProfileStore(path string) *ProfileStore {
+	return &ProfileStore{path: path}
+}
+
+// load reads the file the first time it is needed. The caller must hold s.mu
+func (s *ProfileStore) load() error {
+	if s.profiles != nil {
+		return nil
+	}
+	profiles := make(map[string]*Profile)
+	data, err := os.ReadFile(s.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		var list []*Profile
+		if err := json.Unmarshal(data, &list); err != nil {
+			return err
+		}
+		for _, profile := range list {
+			if profile.Countries == nil {
+				profile.Countries = make(map[string]*CountryAccuracy)
+			}
+			profiles[profile.ID] = profile
+		}
+	}
+	s.profiles = profiles
+	return nil
+}
+
+// save writes every profile through a temporary file. The caller must hold s.mu
+func (s *ProfileStore) save() error {
+	list := make([]*Profile, 0, len(s.profiles))
+	for _, profile := range s.profiles {
+		list = append(list, profile)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Created.Before(list[j].Created) })
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(s.path+".tmp", s.path)
+}
+
+func (s *ProfileStore) findByName(name string) *Profile {
+	key := profileKey(name)
+	for _, profile := range s.profiles {
+		if profileKey(profile.Name) == key {
+			return profile
+		}
+	}
+	return nil
+}
+
+// List returns every profile, sorted by name
+func (s *ProfileStore) List() ([]Profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	list := make([]Profile, 0, len(s.profiles))
+	for _, profile := range s.profiles {
+		list = append(list, profile.clone())
+	}
+	sort.Slice(list, func(i, j int) bool { return profileKey(list[i].Name) < profileKey(list[j].Name) })
+	return list, nil
+}
+
+func (s *ProfileStore) Get(id string) (Profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return Profile{}, err
+	}
+	profile, exists := s.profiles[id]
+	if !exists {
+		return Profile{}, errProfileNotFound
+	}
+	return profile.clone(), nil
+}
+
+// Resolve returns the profile with the given name, creating it if nobody
+// has played under that name before
+func (s *ProfileStore) Resolve(name string, now time.Time) (Profile, error) {
+	name = normalizeDisplayName(name)
+	if name == "" {
+		return Profile{}, errNameRequired
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return Profile{}, err
+	}
+	if profile := s.findByName(name); profile != nil {
+		return profile.clone(), nil
+	}
+
+	profile := &Profile{
+		ID:        newRoundID(),
+		Name:      name,
+		Color:     avatarColors[len(s.profiles)%len(avatarColors)],
+		Created:   now,
+		Countries: make(map[string]*CountryAccuracy),
+	}
+	s.profiles[profile.ID] = profile
+	if err := s.save(); err != nil {
+		delete(s.profiles, profile.ID)
+		return Profile{}, err
+	}
+	return profile.clone(), nil
+}
+
+// SetColor changes a profile's avatar color
+func (s *ProfileStore) SetColor(id, color string) error {
+	if !validAvatarColor(color) {
+		return errInvalidColor
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	profile, exists := s.profiles[id]
+	if !exists {
+		return errProfileNotFound
+	}
+	profile.Color = strings.ToLower(color)
+	return s.save()
+}
+
+// RecordGame adds a finished game and its guesses to the lifetime stats of
+// every player in it who has a profile
+func (s *ProfileStore) RecordGame(game GameRecord, guesses []Guess) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	best := 0
+	for _, player := range game.Players {
+		best = max(best, player.Points)
+	}
+	changed := false
+	for _, player := range game.Players {
+		profile, exists := s.profiles[player.ProfileID]
+		if !exists {
+			continue
+		}
+		stats := &profile.Stats
+		stats.Games++
+		if len(game.Players) > 1 && player.Points == best {
+			stats.Wins++
+		}
+		stats.Points += player.Points
+		stats.BestScore = max(stats.BestScore, player.Points)
+		stats.BestStreak = max(stats.BestStreak, player.BestStreak)
+		stats.Correct += player.Correct
+		stats.Incorrect += player.Incorrect
+		profile.LastSeen = game.Finished
+		changed = true
+	}
+	for _, guess := range guesses {
+		profile, exists := s.profiles[guess.ProfileID]
+		if !exists || guess.Country == "" {
+			continue
+		}
+		accuracy, seen := profile.Countries[guess.Country]
+		if !seen {
+			accuracy = &CountryAccuracy{}
+			profile.Countries[guess.Country] = accuracy
+		}
+		accuracy.Attempts++
+		if guess.Correct {
+			accuracy.Correct++
+		}
+		if guess.At.After(accuracy.LastSeen) {
+			accuracy.LastSeen = guess.At
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+	return s.save()
+}
+
+// linkProfile gives a player the identity of their profile. Teams share a
+// scoreboard entry between people, so they are never linked
+func linkProfile(deps *Dependencies, player *Player) {
+	if deps.Profiles == nil || player.Team != nil {
+		return
+	}
+	profile, err := deps.Profiles.Resolve(player.Name, deps.now())
+	if err != nil {
+		warnf("⚠️  Could not load the profile of %s: %v", player.Name, err)
+		return
+	}
+	player.Name = profile.Name
+	player.ProfileID = profile.ID
+	player.Color = profile.Color
+}
+
+// linkProfiles links every player and drops anyone entered twice
+func linkProfiles(deps *Dependencies, players []Player) []Player {
+	linked := players[:0]
+	seen := make(map[string]bool)
+	for _, player := range players {
+		linkProfile(deps, &player)
+		if player.ProfileID != "" {
+			if seen[player.ProfileID] {
+				continue
+			}
+			seen[player.ProfileID] = true
+		}
+		linked = append(linked, player)
+	}
+	return linked
+}
+
+// profilesView is what the profile pages show
+type profilesView struct {
+	Profiles []Profile
+	Profile  Profile
+	Colors   []string
+	Error    string
+}
+
+// profilesHandler lists every profile and creates new ones
+func profilesHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		view := profilesView{Colors: avatarColors}
+		if r.Method == "POST" {
+			profile, err := deps.Profiles.Resolve(r.FormValue("name"), deps.now())
+			if err == nil {
+				http.Redirect(w, r, "/profiles/"+profile.ID, http.StatusSeeOther)
+				return
+			}
+			view.Error = err.Error()
+		}
+
+		profiles, err := deps.Profiles.List()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		view.Profiles = profiles
+		renderPage(w, profilesTemplate, view)
+	}
+}
+
+// profileHandler shows a profile's lifetime stats and per-country accuracy
+func profileHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		view := profilesView{Colors: avatarColors}
+		id := r.PathValue("id")
+		if r.Method == "POST" {
+			if err := deps.Profiles.SetColor(id, r.FormValue("color")); err == nil {
+				http.Redirect(w, r, "/profiles/"+id, http.StatusSeeOther)
+				return
+			} else if !errors.Is(err, errProfileNotFound) {
+				view.Error = err.Error()
+			}
+		}
+
+		profile, err := deps.Profiles.Get(id)
+		if errors.Is(err, errProfileNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		view.Profile = profile
+		renderPage(w, profileTemplate, view)
+	}
+}
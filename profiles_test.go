@@ -0,0 +1,156 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeDisplayName(t *testing.T) {
+	tests := map[string]string{
+		"Anna":                  "Anna",
+		"  anna  ":              "anna",
+		"Anna   Maria\tLind ":   "Anna Maria Lind",
+		strings.Repeat("x", 40): strings.Repeat("x", maxDisplayName),
+	}
+	for input, expected := range tests {
+		if got := normalizeDisplayName(input); got != expected {
+			t.Errorf("normalizeDisplayName(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+// Test_GIVEN_SameNameTypedDifferently_WHEN_Resolving_THEN_ExpectOneProfile tests that names map to a stable profile
+func Test_GIVEN_SameNameTypedDifferently_WHEN_Resolving_THEN_ExpectOneProfile(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	store := NewProfileStore(path)
+	now := time.Now()
+
+	// Act
+	first, err := store.Resolve("Anna", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := store.Resolve("  anna ", now)
+	other, _ := store.Resolve("Ben", now)
+	reloaded, _ := NewProfileStore(path).Resolve("ANNA", now)
+
+	// Assert
+	if first.ID == "" || second.ID != first.ID || reloaded.ID != first.ID {
+		t.Errorf("Expected one profile for Anna, got %q, %q and %q", first.ID, second.ID, reloaded.ID)
+	}
+	if reloaded.Name != "Anna" {
+		t.Errorf("Expected the name as first entered, got %q", reloaded.Name)
+	}
+	if other.ID == first.ID || other.Color == first.Color {
+		t.Errorf("Expected Ben to get their own profile and color, got %+v", other)
+	}
+	if _, err := store.Resolve("   ", now); err != errNameRequired {
+		t.Errorf("Expected errNameRequired for a blank name, got %v", err)
+	}
+}
+
+// Test_GIVEN_SavedPlayers_WHEN_SettingUpGame_THEN_ExpectProfilesLinked tests the setup page with profiles
+func Test_GIVEN_SavedPlayers_WHEN_SettingUpGame_THEN_ExpectProfilesLinked(t *testing.T) {
+	// Arrange
+	store := NewProfileStore(filepath.Join(t.TempDir(), "profiles.json"))
+	anna, _ := store.Resolve("Anna", time.Now())
+	deps := &Dependencies{GameState: &GameState{}, Profiles: store}
+	form := url.Values{"playerName": {"anna ", "Ben", "ANNA"}, "numRounds": {"5"}}
+	req := httptest.NewRequest("POST", "/setup", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	// Act
+	setupPlayersHandler(deps).ServeHTTP(httptest.NewRecorder(), req)
+
+	// Assert
+	players := deps.GameState.Players
+	if len(players) != 2 {
+		t.Fatalf("Expected Anna to be entered once, got %+v", players)
+	}
+	if players[0].ProfileID != anna.ID || players[0].Name != "Anna" || players[0].Color != anna.Color {
+		t.Errorf("Expected Anna's saved profile, got %+v", players[0])
+	}
+	if players[1].ProfileID == "" || players[1].Name != "Ben" {
+		t.Errorf("Expected a new profile for Ben, got %+v", players[1])
+	}
+}
+
+// Test_GIVEN_FinishedGame_WHEN_GameEnds_THEN_ExpectLifetimeStatsUpdated tests lifetime and per-country stats
+func Test_GIVEN_FinishedGame_WHEN_GameEnds_THEN_ExpectLifetimeStatsUpdated(t *testing.T) {
+	// Arrange
+	store := NewProfileStore(filepath.Join(t.TempDir(), "profiles.json"))
+	deps := &Dependencies{GameState: &GameState{}, Profiles: store}
+	players := linkProfiles(deps, []Player{{Name: "Anna"}, {Name: "Ben"}})
+	initializeGameState(deps.GameState, players, 2, GameSettings{})
+	state := deps.GameState
+	state.CountryName = "Chad"
+	scoreAnswer(state, &state.Players[0], "Chad", 1, time.Second)
+	scoreAnswer(state, &state.Players[1], "Chile", 0, time.Second)
+	state.CurrentRound++
+	state.CountryName = "Romania"
+	scoreAnswer(state, &state.Players[0], "Moldova", 0, time.Second)
+	scoreAnswer(state, &state.Players[1], "Romania", 1, time.Second)
+
+	// Act
+	finishGame(deps)
+
+	// Assert
+	anna, err := store.Get(players[0].ProfileID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if anna.Stats.Games != 1 || anna.Stats.Correct != 1 || anna.Stats.Incorrect != 1 || anna.Stats.Accuracy() != 50 {
+		t.Errorf("Unexpected lifetime stats %+v", anna.Stats)
+	}
+	accuracies := anna.CountryAccuracies()
+	if len(accuracies) != 2 || accuracies[0].Country != "Romania" || accuracies[0].Percentage() != 0 || accuracies[1].Percentage() != 100 {
+		t.Errorf("Expected Romania as Anna's weakest flag, got %+v", accuracies)
+	}
+}
+
+func TestProfileHandler(t *testing.T) {
+	// Arrange
+	store := NewProfileStore(filepath.Join(t.TempDir(), "profiles.json"))
+	anna, _ := store.Resolve("Anna", time.Now())
+	deps := &Dependencies{GameState: &GameState{}, Profiles: store}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/profiles", profilesHandler(deps))
+	mux.HandleFunc("/profiles/{id}", profileHandler(deps))
+
+	// Act - change the color, then try an invalid one
+	form := url.Values{"color": {"#123ABC"}}
+	req := httptest.NewRequest("POST", "/profiles/"+anna.ID, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	bad := httptest.NewRequest("POST", "/profiles/"+anna.ID, strings.NewReader("color=red"))
+	bad.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	badResponse := httptest.NewRecorder()
+	mux.ServeHTTP(badResponse, bad)
+
+	missing := httptest.NewRecorder()
+	mux.ServeHTTP(missing, httptest.NewRequest("GET", "/profiles/nobody", nil))
+
+	list := httptest.NewRecorder()
+	mux.ServeHTTP(list, httptest.NewRequest("GET", "/profiles", nil))
+
+	// Assert
+	if saved, _ := store.Get(anna.ID); saved.Color != "#123abc" {
+		t.Errorf("Expected the new color to be saved, got %q", saved.Color)
+	}
+	if !strings.Contains(badResponse.Body.String(), errInvalidColor.Error()) {
+		t.Errorf("Expected an invalid color error, got %s", badResponse.Body.String())
+	}
+	if missing.Code != 404 {
+		t.Errorf("Expected 404 for a missing profile, got %d", missing.Code)
+	}
+	if !strings.Contains(list.Body.String(), "/profiles/"+anna.ID) {
+		t.Errorf("Expected Anna in the list, got %s", list.Body.String())
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+// countingSource is a seeded source that counts the values drawn from it,
+// so a seeded game can be put back exactly where it was
+type countingSource struct {
+	src   rand.Source64
+	draws uint64
+}
+
+func newCountingSource(seed int64) *countingSource {
+	return &countingSource{src: rand.NewSource(seed).(rand.Source64)}
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.draws = 0
+}
+
+// SeedRandom makes every random choice of the game follow from the seed:
+// the countries, whether flags are real, and how they are tampered with
+func (s *GameState) SeedRandom(seed int64) {
+	s.Seed = seed
+	s.Seeded = true
+	s.rngSource = newCountingSource(seed)
+	s.rng = rand.New(s.rngSource)
+}
+
+// seedNewGame seeds a game that was just set up with the configured seed,
+// if there is one
+func (deps *Dependencies) seedNewGame() {
+	if deps.Seed != nil {
+		deps.GameState.SeedRandom(*deps.Seed)
+	}
+}
+
+// RandomDraws is how many values the game's seeded generator has produced
+func (s *GameState) RandomDraws() uint64 {
+	if s.rngSource == nil {
+		return 0
+	}
+	return s.rngSource.draws
+}
+
+// random returns the game's generator, starting an unseeded one if the game
+// was not given a seed
+func (s *GameState) random() *rand.Rand {
+	if s.rng == nil {
+		s.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return s.rng
+}
+
+// restoreRandom puts a seeded game's generator back where it was after the
+// given number of draws
+func (s *GameState) restoreRandom(draws uint64) {
+	s.SeedRandom(s.Seed)
+	for i := uint64(0); i < draws; i++ {
+		s.rngSource.src.Uint64()
+	}
+	s.rngSource.draws = draws
+}
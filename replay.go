@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+var errReplayNotFound = errors.New("no such replay")
+
+// ReplayRound is one revealed flag of a game: what was shown, what the
+// flag really looks like and how everyone answered. Images are PNGs, so
+// that the log still makes sense once the image store has moved on
+type ReplayRound struct {
+	Round             int      `json:"round"`
+	Mode              GameMode `json:"mode"`
+	Country           string   `json:"country"`
+	Region            string   `json:"region,omitempty"`
+	Truth             bool     `json:"truth"`
+	TamperMode        string   `json:"tamperMode"`
+	TamperDescription string   `json:"tamperDescription,omitempty"`
+	DeltaE            float64  `json:"deltaE"`
+	Answers           []Guess  `json:"answers"`
+	Shown             []byte   `json:"shown,omitempty"`
+	Original          []byte   `json:"original,omitempty"`
+	Options           [][]byte `json:"options,omitempty"`
+	RealOption        int      `json:"realOption,omitempty"`
+}
+
+// Replay is the round-by-round log of a finished game
+type Replay struct {
+	ID     string        `json:"id"`
+	Game   GameRecord    `json:"game"`
+	Rounds []ReplayRound `json:"rounds"`
+}
+
+// ReplayStore keeps the replay of every finished game
+type ReplayStore interface {
+	SaveReplay(replay Replay) error
+	Replay(id string) (Replay, error)
+}
+
+// FileReplayStore writes one JSON file per replay
+type FileReplayStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+func NewFileReplayStore(dir string) *FileReplayStore {
+	return &FileReplayStore{dir: dir}
+}
+
+// validReplayID keeps IDs from the URL inside the replay directory
+func validReplayID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, c := range id {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *FileReplayStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+func (s *FileReplayStore) SaveReplay(replay Replay) error {
+	if !validReplayID(replay.ID) {
+		return fmt.Errorf("invalid replay ID %q", replay.ID)
+	}
+	data, err := json.Marshal(replay)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	path := s.path(replay.ID)
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (s *FileReplayStore) Replay(id string) (Replay, error) {
+	if !validReplayID(id) {
+		return Replay{}, errReplayNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return Replay{}, errReplayNotFound
+	}
+	if err != nil {
+		return Replay{}, err
+	}
+	var replay Replay
+	err = json.Unmarshal(data, &replay)
+	return replay, err
+}
+
+// roundImage returns a revealed image of the current round, or nil if the
+// round has none of that variant
+func roundImage(deps *Dependencies, variant string) []byte {
+	data, _, err := deps.Images.PNG(deps.GameState.RoundID, variant)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// logRound adds the round that has just been revealed to the game's replay,
+// with the answers given since the last revealed round
+func logRound(deps *Dependencies) {
+	state := deps.GameState
+	round := ReplayRound{
+		Round:             state.CurrentRound,
+		Mode:              state.Mode,
+		Country:           state.CountryName,
+		Region:            state.Region,
+		Truth:             state.IsCorrect,
+		TamperMode:        state.TamperMode,
+		TamperDescription: state.TamperDescription,
+		DeltaE:            state.DeltaE,
+		Answers:           append([]Guess(nil), state.Guesses[state.replayedGuesses:]...),
+		Shown:             roundImage(deps, variantShown),
+		Original:          roundImage(deps, variantOriginal),
+	}
+	if state.Mode == ModePickReal {
+		round.RealOption = state.RealOption
+		for i := range state.FlagOptions {
+			round.Options = append(round.Options, roundImage(deps, fmt.Sprintf("option-%d", i)))
+		}
+		if round.Original == nil && state.RealOption < len(round.Options) {
+			round.Original = round.Options[state.RealOption]
+		}
+	}
+	state.replayedGuesses = len(state.Guesses)
+	state.Replay = append(state.Replay, round)
+}
+
+// saveReplay stores the replay of the game that has just ended. Room codes
+// and daily challenges reuse game IDs, so every replay gets its own
+func saveReplay(deps *Dependencies) {
+	state := deps.GameState
+	if deps.Replays == nil || len(state.Replay) == 0 {
+		return
+	}
+	replay := Replay{
+		ID:     newRoundID(),
+		Game:   newGameRecord(state, deps.now()),
+		Rounds: state.Replay,
+	}
+	if err := deps.Replays.SaveReplay(replay); err != nil {
+		warnf("⚠️  Could not save the replay of game %s: %v", state.GameID, err)
+		return
+	}
+	state.ReplayID = replay.ID
+}
+
+func pngDataURL(data []byte) template.URL {
+	return template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(data))
+}
+
+// replayView is what the review page and the HTML export show
+type replayView struct {
+	Replay
+	Step  int
+	Round ReplayRound
+}
+
+func (v replayView) Prev() int { return v.Step - 1 }
+
+func (v replayView) Next() int {
+	if v.Step >= len(v.Rounds) {
+		return 0
+	}
+	return v.Step + 1
+}
+
+func loadReplay(deps *Dependencies, w http.ResponseWriter, r *http.Request) (Replay, bool) {
+	if deps.Replays == nil {
+		http.NotFound(w, r)
+		return Replay{}, false
+	}
+	replay, err := deps.Replays.Replay(r.PathValue("id"))
+	if errors.Is(err, errReplayNotFound) {
+		http.NotFound(w, r)
+		return Replay{}, false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return Replay{}, false
+	}
+	return replay, true
+}
+
+// reviewHandler steps through a finished game one round at a time, with
+// the flag that was shown next to the real one
+func reviewHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		replay, ok := loadReplay(deps, w, r)
+		if !ok {
+			return
+		}
+		view := replayView{Replay: replay, Step: 1}
+		if step, err := strconv.Atoi(r.FormValue("step")); err == nil && step >= 1 && step <= len(replay.Rounds) {
+			view.Step = step
+		}
+		if len(replay.Rounds) > 0 {
+			view.Round = replay.Rounds[view.Step-1]
+		}
+		renderPage(w, reviewTemplate, view)
+	}
+}
+
+// replayExportHandler downloads a replay as JSON, or as a single HTML file
+// with the images inlined that opens without the server
+func replayExportHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		replay, ok := loadReplay(deps, w, r)
+		if !ok {
+			return
+		}
+
+		switch format := r.FormValue("format"); format {
+		case "json", "":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="flag-quiz-%s.json"`, replay.ID))
+			json.NewEncoder(w).Encode(replay)
+		case "html":
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="flag-quiz-%s.html"`, replay.ID))
+			renderPage(w, replayExportTemplate, replayView{Replay: replay})
+		default:
+			http.Error(w, fmt.Sprintf("unknown export format %q", format), http.StatusBadRequest)
+		}
+	}
+}
+
+// Describe puts an answer the way the player gave it
+func (g Guess) Describe() string {
+	if g.Answer == "" {
+		return "no answer in time"
+	}
+	switch g.Mode {
+	case ModeTrueFalse, "":
+		if g.Answer == "correct" {
+			return "said it was real"
+		}
+		return "said it was fake"
+	case ModePickReal:
+		if option, err := strconv.Atoi(g.Answer); err == nil {
+			return fmt.Sprintf("picked option %d", option+1)
+		}
+	case ModeSpot:
+		return "clicked at " + g.Answer
+	}
+	return g.Answer
+}
+
+// Seconds is how long the answer took
+func (g Guess) Seconds() string {
+	return strconv.FormatFloat(float64(g.ResponseMS)/1000, 'f', 1, 64)
+}
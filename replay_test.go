@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test_GIVEN_PlayedRound_WHEN_GameEnds_THEN_ExpectReplaySaved tests that every revealed round reaches the replay
+func Test_GIVEN_PlayedRound_WHEN_GameEnds_THEN_ExpectReplaySaved(t *testing.T) {
+	// Arrange
+	store := NewFileReplayStore(filepath.Join(t.TempDir(), "replays"))
+	deps := &Dependencies{
+		GameState:      &GameState{},
+		CountryService: &MockCountryService{country: CountryFlag{Name: "Austria", Region: "Europe"}},
+		ImageService:   &MockImageService{},
+		Images:         NewImageStore(8),
+		Replays:        store,
+		Now:            time.Now,
+	}
+	initializeGameState(deps.GameState, []Player{{Name: "Anna"}}, 1, GameSettings{Mode: ModeTrueFalse})
+	if err := startRound(deps); err != nil {
+		t.Fatal(err)
+	}
+	guessHandler(deps).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/guess?answer=correct", nil))
+
+	// Act
+	finishGame(deps)
+
+	// Assert
+	state := deps.GameState
+	if state.ReplayID == "" {
+		t.Fatal("Expected the replay ID to be kept for the game over screen")
+	}
+	replay, err := store.Replay(state.ReplayID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.Rounds) != 1 || replay.Game.ID != state.GameID {
+		t.Fatalf("Expected one round of game %s, got %+v", state.GameID, replay)
+	}
+	round := replay.Rounds[0]
+	if round.Country != "Austria" || round.Region != "Europe" || round.Truth != state.IsCorrect {
+		t.Errorf("Unexpected round details %+v", round)
+	}
+	if len(round.Shown) == 0 || len(round.Original) == 0 {
+		t.Error("Expected the shown and original flags as PNGs")
+	}
+	if len(round.Answers) != 1 || round.Answers[0].Player != "Anna" || round.Answers[0].Answer != "correct" {
+		t.Errorf("Expected Anna's answer, got %+v", round.Answers)
+	}
+}
+
+func TestReviewAndExportHandlers(t *testing.T) {
+	// Arrange
+	store := NewFileReplayStore(filepath.Join(t.TempDir(), "replays"))
+	png := []byte{0x89, 'P', 'N', 'G'}
+	store.SaveReplay(Replay{
+		ID:   "abc123",
+		Game: GameRecord{Settings: GameSettings{Mode: ModeTrueFalse}, Players: []PlayerRecord{{Name: "Anna", Points: 100}}},
+		Rounds: []ReplayRound{
+			{Round: 1, Country: "Chad", Truth: true, Shown: png, Original: png,
+				Answers: []Guess{{Player: "Anna", Mode: ModeTrueFalse, Answer: "correct", Correct: true, ResponseMS: 2300}}},
+			{Round: 2, Country: "Romania", TamperDescription: "Blue → Green", DeltaE: 21.5, Shown: png, Original: png,
+				Answers: []Guess{{Player: "Anna", Mode: ModeTrueFalse, Answer: "correct", ResponseMS: 900}}},
+		},
+	})
+	deps := &Dependencies{GameState: &GameState{}, Replays: store}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /games/{id}/review", reviewHandler(deps))
+	mux.HandleFunc("GET /games/{id}/export", replayExportHandler(deps))
+	get := func(url string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		mux.ServeHTTP(response, httptest.NewRequest("GET", url, nil))
+		return response
+	}
+
+	// Act
+	review := get("/games/abc123/review?step=2")
+	exportedHTML := get("/games/abc123/export?format=html")
+	exportedJSON := get("/games/abc123/export?format=json")
+	badFormat := get("/games/abc123/export?format=xml")
+	missing := get("/games/nothing/review")
+
+	// Assert
+	body := review.Body.String()
+	if !strings.Contains(body, "Romania") || !strings.Contains(body, "Blue → Green") || !strings.Contains(body, "said it was real") {
+		t.Errorf("Expected the second round with Anna's answer, got %s", body)
+	}
+	if !strings.Contains(body, "review?step=1") || strings.Contains(body, "review?step=3") {
+		t.Errorf("Expected only a link back to the first round, got %s", body)
+	}
+	html := exportedHTML.Body.String()
+	if !strings.Contains(html, "Chad") || !strings.Contains(html, "Romania") || !strings.Contains(html, "data:image/png;base64,") {
+		t.Errorf("Expected every round with inlined images, got %s", html)
+	}
+	if strings.Contains(html, "/flag/") {
+		t.Error("Expected the HTML export not to need the server")
+	}
+	var replay Replay
+	if err := json.NewDecoder(exportedJSON.Body).Decode(&replay); err != nil || len(replay.Rounds) != 2 || string(replay.Rounds[0].Shown) != string(png) {
+		t.Errorf("Expected the replay as JSON, got %+v (%v)", replay, err)
+	}
+	if badFormat.Code != 400 || missing.Code != 404 {
+		t.Errorf("Expected 400 for an unknown format and 404 for a missing replay, got %d and %d", badFormat.Code, missing.Code)
+	}
+}
+
+func TestValidReplayID(t *testing.T) {
+	for id, valid := range map[string]bool{"abc123": true, "daily-2026": true, "": false, "../profiles": false, "a/b": false} {
+		if validReplayID(id) != valid {
+			t.Errorf("validReplayID(%q) should be %v", id, valid)
+		}
+	}
+}
@@ -0,0 +1,563 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	roomCodeLetters       = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	roomCodeLength        = 4
+	maxPartyPlayers       = 16
+	defaultPartyTimeLimit = 20
+	roomIdleTimeout       = 2 * time.Hour
+	roomPruneInterval     = 10 * time.Minute
+)
+
+var (
+	errRoomClosed   = errors.New("this room has closed")
+	errRoomFull     = errors.New("this room is full")
+	errNameTaken    = errors.New("that name is already taken in this room")
+	errNameRequired = errors.New("please enter a name")
+)
+
+type partyAnswer struct {
+	Value string
+	At    time.Time
+}
+
+// PartyResult is how one player did in the last resolved round
+type PartyResult struct {
+	Name         string        `json:"name"`
+	Answer       string        `json:"answer"`
+	Correct      bool          `json:"correct"`
+	Points       int           `json:"points"`
+	Achievements []Achievement `json:"achievements,omitempty"`
+}
+
+// Room is a party game: the host screen shows the flag and every player
+// answers at the same time from their own device. The room's state is owned
+// by a single goroutine, and everything else reaches it through do
+type Room struct {
+	Code string
+
+	inbox     chan func()
+	done      chan struct{}
+	closeOnce sync.Once
+
+	deps       *Dependencies
+	hostToken  string
+	tokens     map[string]int
+	answers    map[int]partyAnswer
+	ready      map[int]bool
+	results    []PartyResult
+	lastActive time.Time
+}
+
+type RoomManager struct {
+	mu    sync.Mutex
+	rooms map[string]*Room
+}
+
+func NewRoomManager() *RoomManager {
+	return &RoomManager{rooms: make(map[string]*Room)}
+}
+
+// Create opens a room whose game shares the server's services but has its
+// own state
+func (m *RoomManager) Create(deps *Dependencies, settings GameSettings, totalRounds int) *Room {
+	if settings.TimeLimit <= 0 {
+		// party rounds need a deadline, or one idle phone would stall everyone
+		settings.TimeLimit = defaultPartyTimeLimit
+	}
+
+	roomDeps := *deps
+	roomDeps.GameState = &GameState{}
+	roomDeps.Events = NewEventBus()
+	roomDeps.Images = deps.Images.ForGame()
+	roomDeps.Snapshots = nil
+	initializeGameState(roomDeps.GameState, nil, totalRounds, settings)
+	roomDeps.seedNewGame()
+	roomDeps.GameState.CurrentRound = 0
+	roomDeps.GameState.CurrentPlayer = -1
+
+	room := &Room{
+		inbox:      make(chan func()),
+		done:       make(chan struct{}),
+		deps:       &roomDeps,
+		hostToken:  newToken(),
+		tokens:     make(map[string]int),
+		answers:    make(map[int]partyAnswer),
+		ready:      make(map[int]bool),
+		lastActive: deps.now(),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for {
+		room.Code = newRoomCode()
+		if _, taken := m.rooms[room.Code]; !taken {
+			break
+		}
+	}
+	roomDeps.GameState.GameID = room.Code
+	m.rooms[room.Code] = room
+	go room.run()
+	return room
+}
+
+func (m *RoomManager) Get(code string) *Room {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rooms[strings.ToUpper(strings.TrimSpace(code))]
+}
+
+// pruneIdle closes the rooms nobody has used for roomIdleTimeout. The rooms
+// are asked one at a time without holding the lock, so a busy room cannot
+// stall Create and Get
+func (m *RoomManager) pruneIdle(now time.Time) {
+	m.mu.Lock()
+	rooms := make([]*Room, 0, len(m.rooms))
+	for _, room := range m.rooms {
+		rooms = append(rooms, room)
+	}
+	m.mu.Unlock()
+
+	for _, room := range rooms {
+		idle := true
+		room.do(func() { idle = now.Sub(room.lastActive) > roomIdleTimeout })
+		if !idle {
+			continue
+		}
+		m.mu.Lock()
+		if m.rooms[room.Code] == room {
+			delete(m.rooms, room.Code)
+		}
+		m.mu.Unlock()
+		room.close()
+		room.deps.Images.Close()
+	}
+}
+
+// pruneEvery runs pruneIdle on every tick of interval, for as long as the
+// server runs
+func (m *RoomManager) pruneEvery(interval time.Duration, now func() time.Time) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		m.pruneIdle(now())
+	}
+}
+
+// run applies the room's actions one at a time until the room is closed
+func (room *Room) run() {
+	for {
+		select {
+		case action := <-room.inbox:
+			action()
+		case <-room.done:
+			return
+		}
+	}
+}
+
+// do runs an action on the room's goroutine and waits for it to finish. It
+// returns false if the room has closed
+func (room *Room) do(action func()) bool {
+	finished := make(chan struct{})
+	select {
+	case room.inbox <- func() { action(); close(finished) }:
+		<-finished
+		return true
+	case <-room.done:
+		return false
+	}
+}
+
+func (room *Room) close() {
+	room.closeOnce.Do(func() { close(room.done) })
+}
+
+// Join adds a player to the room and returns the token their device uses
+// to answer
+func (room *Room) Join(name string) (string, error) {
+	var token string
+	err := errRoomClosed
+	room.do(func() { token, err = room.join(name) })
+	return token, err
+}
+
+func (room *Room) join(name string) (string, error) {
+	name = normalizeDisplayName(name)
+	if name == "" {
+		return "", errNameRequired
+	}
+	state := room.deps.GameState
+	if len(state.Players) >= maxPartyPlayers {
+		return "", errRoomFull
+	}
+	for _, player := range state.Players {
+		if strings.EqualFold(player.Name, name) {
+			return "", errNameTaken
+		}
+	}
+
+	player := Player{Name: name, Lives: state.Lives}
+	linkProfile(room.deps, &player)
+	token := newToken()
+	room.tokens[token] = len(state.Players)
+	state.Players = append(state.Players, player)
+	room.touch()
+	publishEvent(room.deps, EventPlayerJoined, player.Name)
+	return token, nil
+}
+
+// PlayerIndex returns the player the token belongs to, or -1
+func (room *Room) PlayerIndex(token string) int {
+	index := -1
+	room.do(func() { index = room.playerIndex(token) })
+	return index
+}
+
+func (room *Room) playerIndex(token string) int {
+	if index, exists := room.tokens[token]; exists {
+		return index
+	}
+	return -1
+}
+
+func (room *Room) IsHost(token string) bool {
+	return token != "" && token == room.hostToken
+}
+
+// Advance starts the next round, or ends the game after the last one
+func (room *Room) Advance() error {
+	err := errRoomClosed
+	room.do(func() { err = room.advance() })
+	return err
+}
+
+func (room *Room) advance() error {
+	state := room.deps.GameState
+	if state.GameOver || len(state.Players) == 0 {
+		return nil
+	}
+	if state.CurrentRound > 0 && !state.ShowResult {
+		room.resolve()
+	}
+	if state.GameOver {
+		// the last player standing was just decided
+		room.touch()
+		return nil
+	}
+	if state.CurrentRound >= state.TotalRounds {
+		finishGame(room.deps)
+		room.touch()
+		return nil
+	}
+
+	state.CurrentRound++
+	if err := startRound(room.deps); err != nil {
+		return err
+	}
+	room.answers = make(map[int]partyAnswer)
+	room.ready = make(map[int]bool)
+	room.results = nil
+	room.touch()
+
+	roundID := state.RoundID
+	time.AfterFunc(state.RoundDeadline.Sub(room.deps.now()), func() {
+		room.do(func() {
+			if state.RoundID == roundID && !state.ShowResult {
+				room.resolve()
+			}
+		})
+	})
+	return nil
+}
+
+// Submit records a player's answer and resolves the round once everyone has
+// answered. Late and repeated answers are ignored
+func (room *Room) Submit(token, answer string) {
+	room.do(func() { room.submit(token, answer) })
+}
+
+func (room *Room) submit(token, answer string) {
+	state := room.deps.GameState
+	index, exists := room.tokens[token]
+	if !exists || !roundOpen(state) || state.Players[index].Eliminated {
+		return
+	}
+	if _, answered := room.answers[index]; answered {
+		return
+	}
+	if now := room.deps.now(); now.Before(state.RoundDeadline) {
+		room.answers[index] = partyAnswer{Value: answer, At: now}
+		publishEvent(room.deps, EventAnswerSubmitted, state.Players[index].Name)
+	}
+	room.touch()
+
+	if len(room.answers) >= activePlayers(state) || !room.deps.now().Before(state.RoundDeadline) {
+		room.resolve()
+	}
+}
+
+// SetReady marks a player as ready for the next round. Once every player
+// still in the game is ready, the room moves on without waiting for the host
+func (room *Room) SetReady(token string) error {
+	err := errRoomClosed
+	room.do(func() { err = room.setReady(token) })
+	return err
+}
+
+func (room *Room) setReady(token string) error {
+	state := room.deps.GameState
+	index, exists := room.tokens[token]
+	if !exists || state.GameOver || roundOpen(state) || room.ready[index] {
+		return nil
+	}
+	room.ready[index] = true
+	room.touch()
+	publishEvent(room.deps, EventPlayerReady, state.Players[index].Name)
+
+	for index, player := range state.Players {
+		if !player.Eliminated && !room.ready[index] {
+			return nil
+		}
+	}
+	return room.advance()
+}
+
+// Kick removes a player from the room; their token stops working
+func (room *Room) Kick(name string) {
+	room.do(func() { room.kick(name) })
+}
+
+func (room *Room) kick(name string) {
+	state := room.deps.GameState
+	kicked := -1
+	for index, player := range state.Players {
+		if player.Name == name {
+			kicked = index
+		}
+	}
+	if kicked < 0 {
+		return
+	}
+
+	// everyone after the kicked player moves up a seat
+	state.Players = append(state.Players[:kicked], state.Players[kicked+1:]...)
+	tokens := make(map[string]int, len(room.tokens))
+	answers := make(map[int]partyAnswer, len(room.answers))
+	ready := make(map[int]bool, len(room.ready))
+	shift := func(index int) (int, bool) {
+		if index > kicked {
+			return index - 1, true
+		}
+		return index, index != kicked
+	}
+	for token, index := range room.tokens {
+		if index, keep := shift(index); keep {
+			tokens[token] = index
+		}
+	}
+	for index, answer := range room.answers {
+		if index, keep := shift(index); keep {
+			answers[index] = answer
+		}
+	}
+	for index, isReady := range room.ready {
+		if index, keep := shift(index); keep {
+			ready[index] = isReady
+		}
+	}
+	room.tokens, room.answers, room.ready = tokens, answers, ready
+	room.touch()
+	publishEvent(room.deps, EventPlayerKicked, name)
+
+	if roundOpen(state) && len(state.Players) > 0 && len(room.answers) >= activePlayers(state) {
+		room.resolve()
+	}
+}
+
+// Chat passes a message on to everyone in the room
+func (room *Room) Chat(from, text string) {
+	room.do(func() {
+		room.touch()
+		publishChat(room.deps, from, text)
+	})
+}
+
+// resolve scores every player's answer, counting a missing answer as wrong.
+// It must run on the room's goroutine
+func (room *Room) resolve() {
+	state := room.deps.GameState
+	room.results = nil
+	for index := range state.Players {
+		player := &state.Players[index]
+		if player.Eliminated {
+			continue
+		}
+
+		answer, answered := room.answers[index]
+		credit, responseTime := 0.0, state.RoundDeadline.Sub(state.RoundStarted)
+		if answered {
+			credit = answerCredit(state, answer.Value)
+			responseTime = answer.At.Sub(state.RoundStarted)
+		}
+
+		score := scoreAnswer(state, player, answer.Value, credit, responseTime)
+		room.results = append(room.results, PartyResult{
+			Name:         player.Name,
+			Answer:       describeAnswer(state, answer.Value),
+			Correct:      score.Correct,
+			Points:       score.Points,
+			Achievements: score.Achievements,
+		})
+	}
+
+	state.ShowResult = true
+	room.deps.Images.Reveal(state.RoundID)
+	logGuesses(room.deps)
+	logRound(room.deps)
+	publishEvent(room.deps, EventResultRevealed, "")
+	if state.Lives > 0 && survivalOver(state) {
+		finishGame(room.deps)
+	}
+}
+
+func (room *Room) touch() {
+	room.lastActive = room.deps.now()
+}
+
+// roomView is everything the host and player pages show about a room
+type roomView struct {
+	Code      string
+	JoinURL   string
+	EventsURL string
+	State     *GameState
+	Me        int
+	Answered  []bool
+	Ready     []bool
+	Results   []PartyResult
+	MyResult  *PartyResult
+	Error     string
+}
+
+// Render executes a room page on the room's goroutine, so the round timer
+// cannot change the state halfway through. me is the viewing player, or -1
+// for the host screen
+func (room *Room) Render(w io.Writer, tmpl *template.Template, joinURL string, me int) error {
+	var page bytes.Buffer
+	err := errRoomClosed
+	room.do(func() { err = tmpl.Execute(&page, room.view(joinURL, me)) })
+	if err != nil {
+		return err
+	}
+	_, err = page.WriteTo(w)
+	return err
+}
+
+// view snapshots the room for a template. It must run on the room's goroutine
+func (room *Room) view(joinURL string, me int) roomView {
+	state := room.deps.GameState
+	if !state.ShowResult && state.CurrentRound > 0 && !room.deps.now().Before(state.RoundDeadline) {
+		room.resolve()
+	}
+
+	view := roomView{
+		Code:      room.Code,
+		JoinURL:   joinURL,
+		EventsURL: "/party/" + room.Code + "/events",
+		State:     state,
+		Me:        me,
+		Answered:  make([]bool, len(state.Players)),
+		Ready:     make([]bool, len(state.Players)),
+		Results:   room.results,
+	}
+	for index := range state.Players {
+		_, view.Answered[index] = room.answers[index]
+		view.Ready[index] = room.ready[index]
+	}
+	if me >= 0 && me < len(state.Players) {
+		for i := range room.results {
+			if room.results[i].Name == state.Players[me].Name {
+				view.MyResult = &room.results[i]
+			}
+		}
+	}
+	return view
+}
+
+// MyAnswered reports whether the viewing player has answered this round
+func (v roomView) MyAnswered() bool {
+	return v.Me >= 0 && v.Me < len(v.Answered) && v.Answered[v.Me]
+}
+
+// Player returns the viewing player
+func (v roomView) Player() Player {
+	if v.Me >= 0 && v.Me < len(v.State.Players) {
+		return v.State.Players[v.Me]
+	}
+	return Player{}
+}
+
+func roundOpen(state *GameState) bool {
+	return state.CurrentRound > 0 && !state.ShowResult && !state.GameOver
+}
+
+// answerCredit scores a submitted answer for the round's mode
+func answerCredit(state *GameState, answer string) float64 {
+	switch state.Mode {
+	case ModeChoice:
+		return fullCredit(answer == state.CountryName)
+	case ModePickReal:
+		picked, err := strconv.Atoi(answer)
+		return fullCredit(err == nil && picked == state.RealOption)
+	}
+	return fullCredit(evaluateGuess(answer, state.IsCorrect))
+}
+
+func describeAnswer(state *GameState, answer string) string {
+	if answer == "" {
+		return "No answer"
+	}
+	switch state.Mode {
+	case ModeChoice:
+		return answer
+	case ModePickReal:
+		if picked, err := strconv.Atoi(answer); err == nil {
+			return fmt.Sprintf("Flag %d", picked+1)
+		}
+		return answer
+	}
+	if answer == "correct" {
+		return "Real"
+	}
+	return "Fake"
+}
+
+func newRoomCode() string {
+	b := make([]byte, roomCodeLength)
+	rand.Read(b)
+	for i := range b {
+		b[i] = roomCodeLetters[int(b[i])%len(roomCodeLetters)]
+	}
+	return string(b)
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
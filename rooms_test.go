@@ -0,0 +1,186 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRoom(t *testing.T, now *time.Time) (*Dependencies, *Room) {
+	t.Helper()
+	deps := &Dependencies{
+		GameState:      &GameState{},
+		CountryService: &MockCountryService{country: CountryFlag{Name: "TestCountry"}, similar: []string{"A", "B", "C"}},
+		ImageService:   &MockImageService{},
+		Images:         NewImageStore(8),
+		Now:            func() time.Time { return *now },
+		Rooms:          NewRoomManager(),
+	}
+	room := deps.Rooms.Create(deps, GameSettings{Mode: ModeChoice, TimeLimit: 10}, 2)
+	return deps, room
+}
+
+// Test_GIVEN_PartyRoom_WHEN_EveryoneAnswers_THEN_ExpectRoundResolvedForEachPlayer tests simultaneous answers
+func Test_GIVEN_PartyRoom_WHEN_EveryoneAnswers_THEN_ExpectRoundResolvedForEachPlayer(t *testing.T) {
+	// Arrange
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	_, room := newTestRoom(t, &now)
+	anna, err := room.Join("Anna")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ben, err := room.Join("Ben")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := room.Advance(); err != nil {
+		t.Fatal(err)
+	}
+	state := room.deps.GameState
+
+	// Act
+	now = now.Add(2 * time.Second)
+	room.Submit(anna, "TestCountry")
+	if state.ShowResult {
+		t.Fatal("Expected the round to stay open until every player has answered")
+	}
+	room.Submit(ben, "A")
+
+	// Assert
+	if !state.ShowResult {
+		t.Fatal("Expected the round to be resolved once every player answered")
+	}
+	if state.Players[0].Correct != 1 || state.Players[0].Points == 0 {
+		t.Errorf("Expected Anna to score, got %+v", state.Players[0])
+	}
+	if state.Players[1].Incorrect != 1 || state.Players[1].Points != 0 {
+		t.Errorf("Expected Ben's answer to be wrong, got %+v", state.Players[1])
+	}
+	if len(room.results) != 2 {
+		t.Errorf("Expected a result for each player, got %d", len(room.results))
+	}
+}
+
+// Test_GIVEN_PartyRoom_WHEN_DeadlinePasses_THEN_ExpectMissingAnswersWrong tests that idle players cannot stall the round
+func Test_GIVEN_PartyRoom_WHEN_DeadlinePasses_THEN_ExpectMissingAnswersWrong(t *testing.T) {
+	// Arrange
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	_, room := newTestRoom(t, &now)
+	anna, _ := room.Join("Anna")
+	room.Join("Ben")
+	room.Advance()
+	room.Submit(anna, "TestCountry")
+
+	// Act
+	now = now.Add(11 * time.Second)
+	room.Render(&strings.Builder{}, mustParseGameTemplate(t, partyHostTemplate), "", -1)
+
+	// Assert
+	state := room.deps.GameState
+	if !state.ShowResult {
+		t.Fatal("Expected the round to be resolved after the deadline")
+	}
+	if state.Players[1].Incorrect != 1 {
+		t.Errorf("Expected Ben's missing answer to count as wrong, got %+v", state.Players[1])
+	}
+}
+
+// Test_GIVEN_LastSurvivorDecided_WHEN_Advancing_THEN_ExpectNoFurtherRound tests that advancing stops at the end of a survival game
+func Test_GIVEN_LastSurvivorDecided_WHEN_Advancing_THEN_ExpectNoFurtherRound(t *testing.T) {
+	// Arrange
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	deps := &Dependencies{
+		GameState:      &GameState{},
+		CountryService: &MockCountryService{country: CountryFlag{Name: "TestCountry"}, similar: []string{"A", "B", "C"}},
+		ImageService:   &MockImageService{},
+		Images:         NewImageStore(8),
+		Now:            func() time.Time { return now },
+		Rooms:          NewRoomManager(),
+	}
+	room := deps.Rooms.Create(deps, GameSettings{Mode: ModeChoice, TimeLimit: 10, Lives: 1}, 5)
+	anna, _ := room.Join("Anna")
+	room.Join("Ben")
+	room.Advance()
+	room.Submit(anna, "TestCountry")
+	now = now.Add(11 * time.Second)
+
+	// Act
+	err := room.Advance()
+
+	// Assert
+	state := room.deps.GameState
+	if err != nil || !state.GameOver {
+		t.Fatalf("Expected the game to be over, got %v", err)
+	}
+	if state.CurrentRound != 1 || !state.ShowResult {
+		t.Errorf("Expected no round after the game ended, got round %d", state.CurrentRound)
+	}
+}
+
+// Test_GIVEN_IdleRoom_WHEN_Pruning_THEN_ExpectOnlyIdleRoomClosed tests closing abandoned rooms
+func Test_GIVEN_IdleRoom_WHEN_Pruning_THEN_ExpectOnlyIdleRoomClosed(t *testing.T) {
+	// Arrange
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	deps, idle := newTestRoom(t, &now)
+	now = now.Add(roomIdleTimeout)
+	active := deps.Rooms.Create(deps, GameSettings{Mode: ModeChoice}, 2)
+
+	// Act
+	deps.Rooms.pruneIdle(now.Add(time.Minute))
+
+	// Assert
+	if deps.Rooms.Get(idle.Code) != nil || idle.do(func() {}) {
+		t.Error("Expected the idle room to be closed")
+	}
+	if deps.Rooms.Get(active.Code) != active {
+		t.Error("Expected the active room to stay open")
+	}
+}
+
+func TestRoomJoinRejectsDuplicateNames(t *testing.T) {
+	now := time.Now()
+	_, room := newTestRoom(t, &now)
+	room.Join("Anna")
+
+	if _, err := room.Join(" anna "); err != errNameTaken {
+		t.Errorf("Expected errNameTaken, got %v", err)
+	}
+	if _, err := room.Join(""); err != errNameRequired {
+		t.Errorf("Expected errNameRequired, got %v", err)
+	}
+}
+
+func TestJoinHandlerSetsPlayerCookie(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	deps, room := newTestRoom(t, &now)
+	form := url.Values{"code": {strings.ToLower(room.Code)}, "name": {"Anna"}}
+	req := httptest.NewRequest("POST", "/join", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+
+	// Act
+	joinHandler(deps).ServeHTTP(rr, req)
+
+	// Assert
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/play/"+room.Code {
+		t.Fatalf("Expected a redirect to the controller, got %d %q", rr.Code, rr.Header().Get("Location"))
+	}
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || room.PlayerIndex(cookies[0].Value) != 0 {
+		t.Errorf("Expected a cookie identifying the new player, got %v", cookies)
+	}
+}
+
+func mustParseGameTemplate(t *testing.T, page string) *template.Template {
+	t.Helper()
+	tmpl, err := parseGameTemplate(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tmpl
+}
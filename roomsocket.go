@@ -0,0 +1,381 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Party rooms can also be played over a WebSocket at /party/{code}/ws. Every
+// message is a JSON object with a "type".
+//
+// Client to server:
+//
+//	{"type": "join", "name": "Anna"}      take a seat in the room
+//	{"type": "join", "token": "..."}      take back a seat after reconnecting
+//	{"type": "ready"}                     ready for the next round
+//	{"type": "answer", "answer": "..."}   "correct"/"incorrect", a country, or a flag index
+//	{"type": "chat", "text": "..."}       talk to the room
+//	{"type": "kick", "player": "Anna"}    host only: remove a player
+//	{"type": "host-advance"}              host only: start the next round
+//
+// Server to client:
+//
+//	{"type": "welcome", "token": "...", "host": false}   the seat the connection holds
+//	{"type": "state", "room": {...}}                      the room after every change
+//	{"type": "chat", "from": "Anna", "text": "..."}
+//	{"type": "kicked"}                                    the seat was taken away
+//	{"type": "error", "message": "..."}
+//
+// The host screen's cookie makes a connection the host, and a player's
+// cookie restores their seat straight away. The server pings every
+// wsPingInterval and drops connections that stay silent for wsIdleTimeout
+
+const (
+	msgJoin        = "join"
+	msgReady       = "ready"
+	msgAnswer      = "answer"
+	msgChat        = "chat"
+	msgKick        = "kick"
+	msgHostAdvance = "host-advance"
+
+	msgWelcome = "welcome"
+	msgState   = "state"
+	msgKicked  = "kicked"
+	msgError   = "error"
+)
+
+const (
+	wsPingInterval = 20 * time.Second
+	wsIdleTimeout  = 60 * time.Second
+	maxChatLength  = 200
+)
+
+// Room phases as seen by WebSocket clients
+const (
+	phaseLobby    = "lobby"
+	phaseQuestion = "question"
+	phaseResult   = "result"
+	phaseOver     = "over"
+)
+
+type clientMessage struct {
+	Type   string `json:"type"`
+	Name   string `json:"name,omitempty"`
+	Token  string `json:"token,omitempty"`
+	Answer string `json:"answer,omitempty"`
+	Text   string `json:"text,omitempty"`
+	Player string `json:"player,omitempty"`
+}
+
+type serverMessage struct {
+	Type    string        `json:"type"`
+	Token   string        `json:"token,omitempty"`
+	Host    bool          `json:"host,omitempty"`
+	Room    *RoomSnapshot `json:"room,omitempty"`
+	From    string        `json:"from,omitempty"`
+	Text    string        `json:"text,omitempty"`
+	Message string        `json:"message,omitempty"`
+}
+
+// SnapshotPlayer is one seat of a room as sent to WebSocket clients
+type SnapshotPlayer struct {
+	Name      string `json:"name"`
+	Points    int    `json:"points"`
+	Correct   int    `json:"correct"`
+	Incorrect int    `json:"incorrect"`
+	Streak    int    `json:"streak"`
+	Ready     bool   `json:"ready"`
+	Answered  bool   `json:"answered"`
+}
+
+// RoomSnapshot is what a WebSocket client may know about a room. The answer
+// is only filled in once the round has been resolved
+type RoomSnapshot struct {
+	Code        string           `json:"code"`
+	Phase       string           `json:"phase"`
+	Mode        GameMode         `json:"mode"`
+	Round       int              `json:"round"`
+	TotalRounds int              `json:"totalRounds"`
+	Version     int              `json:"version"`
+	Me          int              `json:"me"`
+	Deadline    int64            `json:"deadline,omitempty"`
+	CountryName string           `json:"countryName,omitempty"`
+	FlagSrc     string           `json:"flagSrc,omitempty"`
+	Choices     []string         `json:"choices,omitempty"`
+	FlagOptions []string         `json:"flagOptions,omitempty"`
+	Answer      string           `json:"answer,omitempty"`
+	Players     []SnapshotPlayer `json:"players"`
+	Results     []PartyResult    `json:"results,omitempty"`
+}
+
+// Snapshot describes the room for the player holding the token
+func (room *Room) Snapshot(token string) RoomSnapshot {
+	var snapshot RoomSnapshot
+	room.do(func() { snapshot = room.snapshot(token) })
+	return snapshot
+}
+
+func (room *Room) snapshot(token string) RoomSnapshot {
+	state := room.deps.GameState
+	snapshot := RoomSnapshot{
+		Code:        room.Code,
+		Mode:        state.Mode,
+		Round:       state.CurrentRound,
+		TotalRounds: state.TotalRounds,
+		Version:     state.Version,
+		Me:          room.playerIndex(token),
+		Players:     make([]SnapshotPlayer, len(state.Players)),
+	}
+	for index, player := range state.Players {
+		_, answered := room.answers[index]
+		snapshot.Players[index] = SnapshotPlayer{
+			Name:      player.Name,
+			Points:    player.Points,
+			Correct:   player.Correct,
+			Incorrect: player.Incorrect,
+			Streak:    player.Streak,
+			Ready:     room.ready[index],
+			Answered:  answered,
+		}
+	}
+
+	switch {
+	case state.GameOver:
+		snapshot.Phase = phaseOver
+		return snapshot
+	case state.CurrentRound == 0:
+		snapshot.Phase = phaseLobby
+		return snapshot
+	case state.ShowResult:
+		snapshot.Phase = phaseResult
+		snapshot.Answer = roundAnswer(state)
+		snapshot.Results = room.results
+	default:
+		snapshot.Phase = phaseQuestion
+		snapshot.Deadline = state.RoundDeadline.UnixMilli()
+	}
+
+	snapshot.FlagSrc = state.FlagSrc
+	snapshot.Choices = state.Choices
+	snapshot.FlagOptions = state.FlagOptions
+	if state.Mode != ModeChoice || state.ShowResult {
+		// naming the country is the question in every mode but "name the flag"
+		snapshot.CountryName = state.CountryName
+	}
+	return snapshot
+}
+
+// roundAnswer is the answer that would have scored in the current round
+func roundAnswer(state *GameState) string {
+	switch state.Mode {
+	case ModeChoice:
+		return state.CountryName
+	case ModePickReal:
+		return strconv.Itoa(state.RealOption)
+	}
+	if state.IsCorrect {
+		return "correct"
+	}
+	return "incorrect"
+}
+
+// roomClient is one WebSocket connection to a room
+type roomClient struct {
+	room *Room
+	ws   *WebSocket
+
+	mu    sync.Mutex
+	token string
+	host  bool
+}
+
+func (c *roomClient) identity() (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token, c.host
+}
+
+func (c *roomClient) send(message serverMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return c.ws.WriteText(data)
+}
+
+func (c *roomClient) sendError(message string) {
+	c.send(serverMessage{Type: msgError, Message: message})
+}
+
+func (c *roomClient) sendState() error {
+	token, _ := c.identity()
+	snapshot := c.room.Snapshot(token)
+	if token != "" && snapshot.Me < 0 {
+		c.send(serverMessage{Type: msgKicked})
+		c.ws.closeWith(closeNormal)
+		return errConnectionGone
+	}
+	return c.send(serverMessage{Type: msgState, Room: &snapshot})
+}
+
+func (c *roomClient) welcome() error {
+	token, host := c.identity()
+	if err := c.send(serverMessage{Type: msgWelcome, Token: token, Host: host}); err != nil {
+		return err
+	}
+	return c.sendState()
+}
+
+// pushUpdates sends the room's state after every event, chat as it comes,
+// and pings the client, until the connection goes away
+func (c *roomClient) pushUpdates(done <-chan struct{}) {
+	events, cancel := c.room.deps.Events.Subscribe()
+	defer cancel()
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+		case <-c.room.done:
+			c.ws.closeWith(closeNormal)
+			return
+		case <-ping.C:
+			err = c.ws.Ping()
+		case event := <-events:
+			if event.Type == EventChat {
+				err = c.send(serverMessage{Type: msgChat, From: event.Player, Text: event.Text})
+			} else {
+				err = c.sendState()
+			}
+		}
+		if err != nil {
+			c.ws.Close()
+			return
+		}
+	}
+}
+
+// handle applies one message from the client
+func (c *roomClient) handle(message clientMessage) {
+	token, host := c.identity()
+	switch message.Type {
+	case msgJoin:
+		if token != "" {
+			c.sendError("you have already joined this room")
+			return
+		}
+		if message.Token != "" {
+			if c.room.PlayerIndex(message.Token) < 0 {
+				c.sendError("that seat is no longer in the room")
+				return
+			}
+			token = message.Token
+		} else {
+			var err error
+			if token, err = c.room.Join(message.Name); err != nil {
+				c.sendError(err.Error())
+				return
+			}
+		}
+		c.mu.Lock()
+		c.token = token
+		c.mu.Unlock()
+		c.welcome()
+	case msgReady:
+		if err := c.room.SetReady(token); err != nil {
+			c.sendError(err.Error())
+		}
+	case msgAnswer:
+		c.room.Submit(token, message.Answer)
+	case msgChat:
+		text := strings.TrimSpace(message.Text)
+		if len(text) > maxChatLength {
+			text = text[:maxChatLength]
+		}
+		if from := c.chatName(token, host); from != "" && text != "" {
+			c.room.Chat(from, text)
+		}
+	case msgKick:
+		if !host {
+			c.sendError("only the host can kick players")
+			return
+		}
+		c.room.Kick(message.Player)
+	case msgHostAdvance:
+		if !host {
+			c.sendError("only the host can start the next round")
+			return
+		}
+		if err := c.room.Advance(); err != nil {
+			c.sendError(err.Error())
+		}
+	default:
+		c.sendError("unknown message type " + message.Type)
+	}
+}
+
+// chatName is who a chat message is from, or "" if the client has no seat
+func (c *roomClient) chatName(token string, host bool) string {
+	if host {
+		return "Host"
+	}
+	var name string
+	c.room.do(func() {
+		if index := c.room.playerIndex(token); index >= 0 {
+			name = c.room.deps.GameState.Players[index].Name
+		}
+	})
+	return name
+}
+
+// roomSocketHandler upgrades to a WebSocket and speaks the room protocol
+func roomSocketHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		room := deps.Rooms.Get(r.PathValue("code"))
+		if room == nil {
+			http.NotFound(w, r)
+			return
+		}
+		ws, err := UpgradeWebSocket(w, r)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		ws.SetIdleTimeout(wsIdleTimeout)
+
+		client := &roomClient{room: room, ws: ws}
+		if room.IsHost(cookieValue(r, hostCookieName(room.Code))) {
+			client.host = true
+		}
+		if token := cookieValue(r, roomCookieName(room.Code)); room.PlayerIndex(token) >= 0 {
+			client.token = token
+		}
+		if err := client.welcome(); err != nil {
+			return
+		}
+
+		done := make(chan struct{})
+		defer close(done)
+		go client.pushUpdates(done)
+
+		for {
+			_, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			var message clientMessage
+			if err := json.Unmarshal(data, &message); err != nil {
+				client.sendError("messages must be JSON objects")
+				continue
+			}
+			client.handle(message)
+		}
+	}
+}
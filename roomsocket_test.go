@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSocket is a bare-bones WebSocket client for talking to the room endpoint
+type testSocket struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialTestSocket(t *testing.T, server *httptest.Server, path, cookie string) *testSocket {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	key := make([]byte, 16)
+	rand.Read(key)
+	req, _ := http.NewRequest("GET", server.URL+path, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", base64.StdEncoding.EncodeToString(key))
+	if cookie != "" {
+		req.Header.Set("Cookie", cookie)
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101 Switching Protocols, got %d", resp.StatusCode)
+	}
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != websocketAccept(req.Header.Get("Sec-WebSocket-Key")) {
+		t.Fatalf("Unexpected Sec-WebSocket-Accept %q", accept)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testSocket{t: t, conn: conn, reader: reader}
+}
+
+func (s *testSocket) send(message clientMessage) {
+	s.t.Helper()
+	payload, _ := json.Marshal(message)
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opText, 0x80 | byte(len(payload))}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := s.conn.Write(frame); err != nil {
+		s.t.Fatal(err)
+	}
+}
+
+// receive returns the next message of the given type, skipping the others
+func (s *testSocket) receive(messageType string) serverMessage {
+	s.t.Helper()
+	for {
+		var header [2]byte
+		if _, err := io.ReadFull(s.reader, header[:]); err != nil {
+			s.t.Fatalf("Waiting for %q: %v", messageType, err)
+		}
+		length := int(header[1] & 0x7F)
+		switch length {
+		case 126:
+			var ext [2]byte
+			io.ReadFull(s.reader, ext[:])
+			length = int(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			io.ReadFull(s.reader, ext[:])
+			length = int(binary.BigEndian.Uint64(ext[:]))
+		}
+		payload := make([]byte, length)
+		io.ReadFull(s.reader, payload)
+		if header[0]&0x0F != opText {
+			continue
+		}
+
+		var message serverMessage
+		if err := json.Unmarshal(payload, &message); err != nil {
+			s.t.Fatal(err)
+		}
+		if message.Type == messageType {
+			return message
+		}
+	}
+}
+
+// receivePhase returns the next room state in the given phase
+func (s *testSocket) receivePhase(phase string) RoomSnapshot {
+	s.t.Helper()
+	for {
+		if message := s.receive(msgState); message.Room.Phase == phase {
+			return *message.Room
+		}
+	}
+}
+
+func newSocketTestServer(t *testing.T) (*httptest.Server, *Room) {
+	now := time.Now()
+	deps, room := newTestRoom(t, &now)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /party/{code}/ws", roomSocketHandler(deps))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, room
+}
+
+func TestWebSocketAccept(t *testing.T) {
+	// the example from RFC 6455 section 1.3
+	if accept := websocketAccept("dGhlIHNhbXBsZSBub25jZQ=="); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Unexpected accept value %q", accept)
+	}
+}
+
+// Test_GIVEN_RoomSocket_WHEN_PlayingARound_THEN_ExpectStateUpdates tests joining, advancing and answering over the protocol
+func Test_GIVEN_RoomSocket_WHEN_PlayingARound_THEN_ExpectStateUpdates(t *testing.T) {
+	// Arrange
+	server, room := newSocketTestServer(t)
+	path := "/party/" + room.Code + "/ws"
+	host := dialTestSocket(t, server, path, hostCookieName(room.Code)+"="+room.hostToken)
+	if welcome := host.receive(msgWelcome); !welcome.Host {
+		t.Fatal("Expected the host cookie to make the connection the host")
+	}
+	player := dialTestSocket(t, server, path, "")
+	player.receive(msgWelcome)
+
+	// Act
+	player.send(clientMessage{Type: msgJoin, Name: "Anna"})
+	welcome := player.receive(msgWelcome)
+	host.send(clientMessage{Type: msgHostAdvance})
+	question := player.receivePhase(phaseQuestion)
+	player.send(clientMessage{Type: msgAnswer, Answer: "TestCountry"})
+	result := player.receivePhase(phaseResult)
+
+	// Assert
+	if welcome.Token == "" {
+		t.Fatal("Expected a token for the new seat")
+	}
+	if question.CountryName != "" || question.Answer != "" {
+		t.Errorf("Expected the answer to stay hidden during the question, got %+v", question)
+	}
+	if result.Me != 0 || result.Answer != "TestCountry" {
+		t.Errorf("Expected the revealed answer for seat 0, got me=%d answer=%q", result.Me, result.Answer)
+	}
+	if len(result.Players) != 1 || result.Players[0].Correct != 1 {
+		t.Errorf("Expected Anna's answer to be scored, got %+v", result.Players)
+	}
+
+	// Act - reconnect with the token
+	again := dialTestSocket(t, server, path, "")
+	again.receive(msgWelcome)
+	again.send(clientMessage{Type: msgJoin, Token: welcome.Token})
+
+	// Assert
+	if rejoined := again.receive(msgWelcome); rejoined.Token != welcome.Token {
+		t.Errorf("Expected the seat to be restored, got token %q", rejoined.Token)
+	}
+	if state := again.receive(msgState); state.Room.Me != 0 {
+		t.Errorf("Expected the restored seat to be 0, got %d", state.Room.Me)
+	}
+}
+
+func Test_GIVEN_RoomSocket_WHEN_HostKicksPlayer_THEN_ExpectPlayerKicked(t *testing.T) {
+	// Arrange
+	server, room := newSocketTestServer(t)
+	path := "/party/" + room.Code + "/ws"
+	token, _ := room.Join("Anna")
+	room.Join("Ben")
+	player := dialTestSocket(t, server, path, roomCookieName(room.Code)+"="+token)
+	player.receive(msgWelcome)
+	host := dialTestSocket(t, server, path, hostCookieName(room.Code)+"="+room.hostToken)
+	host.receive(msgWelcome)
+
+	// Act
+	player.send(clientMessage{Type: msgKick, Player: "Ben"})
+	denied := player.receive(msgError)
+	host.send(clientMessage{Type: msgKick, Player: "Anna"})
+	player.receive(msgKicked)
+
+	// Assert
+	if denied.Message == "" {
+		t.Error("Expected players to be refused when kicking")
+	}
+	if room.PlayerIndex(token) != -1 {
+		t.Error("Expected the kicked player's token to stop working")
+	}
+	if snapshot := room.Snapshot(""); len(snapshot.Players) != 1 || snapshot.Players[0].Name != "Ben" {
+		t.Errorf("Expected only Ben to be left, got %+v", snapshot.Players)
+	}
+}
+
+// Test_GIVEN_JoinedSocket_WHEN_JoiningAgain_THEN_ExpectOneSeat tests that a connection cannot take a second seat
+func Test_GIVEN_JoinedSocket_WHEN_JoiningAgain_THEN_ExpectOneSeat(t *testing.T) {
+	// Arrange
+	server, room := newSocketTestServer(t)
+	player := dialTestSocket(t, server, "/party/"+room.Code+"/ws", "")
+	player.receive(msgWelcome)
+	player.send(clientMessage{Type: msgJoin, Name: "Anna"})
+	player.receive(msgWelcome)
+
+	// Act
+	player.send(clientMessage{Type: msgJoin, Name: "Ben"})
+	denied := player.receive(msgError)
+
+	// Assert
+	if denied.Message == "" {
+		t.Error("Expected the second join to be refused")
+	}
+	if snapshot := room.Snapshot(""); len(snapshot.Players) != 1 {
+		t.Errorf("Expected one seat, got %+v", snapshot.Players)
+	}
+}
+
+func TestWebSocketRejectsOtherOrigins(t *testing.T) {
+	server, room := newSocketTestServer(t)
+	tests := map[string]int{
+		"":                                 http.StatusSwitchingProtocols,
+		server.URL:                         http.StatusSwitchingProtocols,
+		"https://evil.example":             http.StatusForbidden,
+		"http://evil.example/" + room.Code: http.StatusForbidden,
+	}
+	for origin, want := range tests {
+		req, _ := http.NewRequest("GET", server.URL+"/party/"+room.Code+"/ws", nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("Origin %q: expected %d, got %d", origin, want, resp.StatusCode)
+		}
+	}
+}
+
+func TestRoomStartsWhenEveryoneIsReady(t *testing.T) {
+	now := time.Now()
+	_, room := newTestRoom(t, &now)
+	anna, _ := room.Join("Anna")
+	ben, _ := room.Join("Ben")
+
+	room.SetReady(anna)
+	if room.Snapshot("").Phase != phaseLobby {
+		t.Fatal("Expected the room to wait for Ben")
+	}
+	room.SetReady(ben)
+
+	if phase := room.Snapshot("").Phase; phase != phaseQuestion {
+		t.Errorf("Expected the first round to start, got %q", phase)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Country selection strategies, chosen on the setup page
+const (
+	SelectUniform  = "uniform"
+	SelectDeck     = "deck"
+	SelectWeakness = "weakness"
+)
+
+const (
+	defaultExploration = 0.15
+
+	// a flag seen this long ago counts as fully due again
+	weaknessRecovery = 24 * time.Hour
+)
+
+// CountryHistory is how the players in front of the screen have done on
+// each country so far
+type CountryHistory map[string]CountryAccuracy
+
+func (h CountryHistory) add(country string, correct bool, at time.Time) {
+	accuracy := h[country]
+	accuracy.Attempts++
+	if correct {
+		accuracy.Correct++
+	}
+	if at.After(accuracy.LastSeen) {
+		accuracy.LastSeen = at
+	}
+	h[country] = accuracy
+}
+
+func (h CountryHistory) merge(country string, other CountryAccuracy) {
+	accuracy := h[country]
+	accuracy.Attempts += other.Attempts
+	accuracy.Correct += other.Correct
+	if other.LastSeen.After(accuracy.LastSeen) {
+		accuracy.LastSeen = other.LastSeen
+	}
+	h[country] = accuracy
+}
+
+// Selection is what a CountrySelector may look at to pick the next country.
+// History is only worked out for the selectors that ask for it
+type Selection struct {
+	Countries []CountryFlag
+	Rng       *rand.Rand
+	Now       time.Time
+	History   func() CountryHistory
+}
+
+// CountrySelector picks the country for the next round
+type CountrySelector interface {
+	Select(selection Selection) CountryFlag
+}
+
+// UniformSelector gives every country the same chance every round
+type UniformSelector struct{}
+
+func (UniformSelector) Select(selection Selection) CountryFlag {
+	return selection.Countries[selection.Rng.Intn(len(selection.Countries))]
+}
+
+// DeckSelector deals the countries like a shuffled deck, so none comes up
+// twice until every other one has
+type DeckSelector struct {
+	deck []int
+	size int
+}
+
+func (d *DeckSelector) Select(selection Selection) CountryFlag {
+	if len(d.deck) == 0 || d.size != len(selection.Countries) {
+		d.size = len(selection.Countries)
+		d.deck = selection.Rng.Perm(d.size)
+	}
+	next := d.deck[0]
+	d.deck = d.deck[1:]
+	return selection.Countries[next]
+}
+
+// WeaknessSelector favours the flags the players get wrong and have not
+// seen for a while. Exploration is the share of every country's weight
+// that is handed out regardless of history, so that strong and unseen
+// flags still come up
+type WeaknessSelector struct {
+	Exploration float64
+}
+
+func (w WeaknessSelector) Select(selection Selection) CountryFlag {
+	history := selection.History()
+	weights := make([]float64, len(selection.Countries))
+	total := 0.0
+	for i, country := range selection.Countries {
+		weights[i] = w.weight(history[country.Name], selection.Now)
+		total += weights[i]
+	}
+
+	pick := selection.Rng.Float64() * total
+	for i, weight := range weights {
+		if pick < weight {
+			return selection.Countries[i]
+		}
+		pick -= weight
+	}
+	return selection.Countries[len(selection.Countries)-1]
+}
+
+// weight blends the smoothed error rate, which starts at one half for an
+// unseen flag, with how long it has been since the flag was last shown
+func (w WeaknessSelector) weight(accuracy CountryAccuracy, now time.Time) float64 {
+	errorRate := float64(accuracy.Attempts-accuracy.Correct+1) / float64(accuracy.Attempts+2)
+	due := 1.0
+	if !accuracy.LastSeen.IsZero() {
+		due = math.Max(0, math.Min(1, float64(now.Sub(accuracy.LastSeen))/float64(weaknessRecovery)))
+	}
+	return w.Exploration + (1-w.Exploration)*errorRate*due
+}
+
+// filterRegions keeps the countries of the given regions, or all of them
+// when no region is given
+func filterRegions(countries []CountryFlag, regions []string) []CountryFlag {
+	if len(regions) == 0 {
+		return countries
+	}
+	var filtered []CountryFlag
+	for _, country := range countries {
+		for _, region := range regions {
+			if strings.EqualFold(country.Region, region) {
+				filtered = append(filtered, country)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// newCountrySelector builds the strategy the game's settings ask for
+func newCountrySelector(settings GameSettings) CountrySelector {
+	switch settings.Selection {
+	case SelectDeck:
+		return &DeckSelector{}
+	case SelectWeakness:
+		return WeaknessSelector{Exploration: settings.Exploration}
+	}
+	return UniformSelector{}
+}
+
+func parseSelection(selectionStr string) string {
+	switch selectionStr {
+	case SelectDeck, SelectWeakness:
+		return selectionStr
+	}
+	return SelectUniform
+}
+
+func parseExploration(explorationStr string) float64 {
+	if exploration, err := strconv.ParseFloat(explorationStr, 64); err == nil && exploration >= 0 && exploration <= 1 {
+		return exploration
+	}
+	return defaultExploration
+}
+
+// countryHistory gathers the record of whoever is answering: the current
+// player, or everyone when all players answer together. Lifetime records
+// come from profiles, and this game's guesses are added on top since
+// profiles are only updated once a game ends
+func countryHistory(deps *Dependencies) CountryHistory {
+	state := deps.GameState
+	players := state.Players
+	if state.CurrentPlayer >= 0 && state.CurrentPlayer < len(players) {
+		players = players[state.CurrentPlayer : state.CurrentPlayer+1]
+	}
+
+	history := CountryHistory{}
+	answering := make(map[string]bool)
+	for _, player := range players {
+		answering[player.Name] = true
+		if deps.Profiles == nil || player.ProfileID == "" {
+			continue
+		}
+		profile, err := deps.Profiles.Get(player.ProfileID)
+		if err != nil {
+			continue
+		}
+		for country, accuracy := range profile.Countries {
+			history.merge(country, *accuracy)
+		}
+	}
+	for _, guess := range state.Guesses {
+		if answering[guess.Player] {
+			history.add(guess.Country, guess.Correct, guess.At)
+		}
+	}
+	return history
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testCountries(names ...string) []CountryFlag {
+	countries := make([]CountryFlag, len(names))
+	for i, name := range names {
+		countries[i] = CountryFlag{Name: name}
+	}
+	return countries
+}
+
+// Test_GIVEN_DeckSelection_WHEN_DrawingEveryCountry_THEN_ExpectNoRepeats tests that the deck deals each country once
+func Test_GIVEN_DeckSelection_WHEN_DrawingEveryCountry_THEN_ExpectNoRepeats(t *testing.T) {
+	// Arrange
+	countries := testCountries("Chad", "Romania", "Peru", "Italy", "Mali")
+	selector := newCountrySelector(GameSettings{Selection: SelectDeck})
+	selection := Selection{Countries: countries, Rng: rand.New(rand.NewSource(7))}
+
+	// Act
+	seen := make(map[string]int)
+	for range countries {
+		seen[selector.Select(selection).Name]++
+	}
+	next := selector.Select(selection)
+
+	// Assert
+	if len(seen) != len(countries) {
+		t.Errorf("Expected every country once before a repeat, got %v", seen)
+	}
+	if seen[next.Name] != 1 {
+		t.Errorf("Expected a new deck once the first ran out, got %q", next.Name)
+	}
+}
+
+// Test_GIVEN_MissedCountry_WHEN_FocusingOnWeaknesses_THEN_ExpectItFavoured tests the weakness weights
+func Test_GIVEN_MissedCountry_WHEN_FocusingOnWeaknesses_THEN_ExpectItFavoured(t *testing.T) {
+	// Arrange
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	countries := testCountries("Chad", "Romania", "Peru")
+	history := CountryHistory{
+		"Chad":    {Correct: 0, Attempts: 4, LastSeen: now.Add(-48 * time.Hour)},
+		"Romania": {Correct: 4, Attempts: 4, LastSeen: now.Add(-48 * time.Hour)},
+		"Peru":    {Correct: 0, Attempts: 4, LastSeen: now.Add(-time.Minute)},
+	}
+	selector := WeaknessSelector{Exploration: defaultExploration}
+	selection := Selection{
+		Countries: countries,
+		Rng:       rand.New(rand.NewSource(1)),
+		Now:       now,
+		History:   func() CountryHistory { return history },
+	}
+
+	// Act
+	picks := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		picks[selector.Select(selection).Name]++
+	}
+
+	// Assert
+	if picks["Chad"] < 600 {
+		t.Errorf("Expected the missed flag most of the time, got %v", picks)
+	}
+	if picks["Romania"] == 0 || picks["Peru"] == 0 {
+		t.Errorf("Expected exploration to still show the other flags, got %v", picks)
+	}
+	if picks["Peru"] > picks["Chad"]/2 {
+		t.Errorf("Expected a flag seen a minute ago to be held back, got %v", picks)
+	}
+}
+
+func TestWeaknessWeight(t *testing.T) {
+	now := time.Now()
+	unseen := WeaknessSelector{Exploration: 0}.weight(CountryAccuracy{}, now)
+	if unseen != 0.5 {
+		t.Errorf("Expected an unseen flag to weigh 0.5, got %v", unseen)
+	}
+	explored := WeaknessSelector{Exploration: 1}.weight(CountryAccuracy{Correct: 9, Attempts: 9, LastSeen: now}, now)
+	if explored != 1 {
+		t.Errorf("Expected full exploration to ignore history, got %v", explored)
+	}
+}
+
+func TestParseSelection(t *testing.T) {
+	if parseSelection("deck") != SelectDeck || parseSelection("weakness") != SelectWeakness || parseSelection("bogus") != SelectUniform {
+		t.Error("Expected deck, weakness and uniform for anything else")
+	}
+	if parseExploration("0.4") != 0.4 || parseExploration("2") != defaultExploration || parseExploration("") != defaultExploration {
+		t.Error("Expected explorations between 0 and 1 only")
+	}
+}
+
+// Test_GIVEN_ProfileAndGameGuesses_WHEN_GatheringHistory_THEN_ExpectBothCounted tests countryHistory
+func Test_GIVEN_ProfileAndGameGuesses_WHEN_GatheringHistory_THEN_ExpectBothCounted(t *testing.T) {
+	// Arrange
+	store := NewProfileStore(filepath.Join(t.TempDir(), "profiles.json"))
+	deps := &Dependencies{GameState: &GameState{}, Profiles: store}
+	players := linkProfiles(deps, []Player{{Name: "Anna"}, {Name: "Ben"}})
+	store.RecordGame(GameRecord{Players: []PlayerRecord{{Name: "Anna", ProfileID: players[0].ProfileID}}}, []Guess{
+		{Player: "Anna", ProfileID: players[0].ProfileID, Country: "Chad", Correct: false, At: time.Now()},
+	})
+	initializeGameState(deps.GameState, players, 3, GameSettings{Selection: SelectWeakness})
+	state := deps.GameState
+	state.CountryName = "Chad"
+	scoreAnswer(state, &state.Players[0], "Chad", 1, time.Second)
+	scoreAnswer(state, &state.Players[1], "Chile", 0, time.Second)
+	state.CurrentPlayer = 0
+
+	// Act
+	history := countryHistory(deps)
+
+	// Assert
+	if chad := history["Chad"]; chad.Attempts != 2 || chad.Correct != 1 || chad.LastSeen.IsZero() {
+		t.Errorf("Expected Anna's lifetime and current guesses on Chad only, got %+v", chad)
+	}
+}
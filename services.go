@@ -1,24 +1,53 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"image"
+	"image/png"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/pariz/gountries"
 )
 
+// defaultFlagSource is where flags are downloaded from unless configured
+// otherwise. {name} stands for the country's name as the site spells it
+const defaultFlagSource = "https://flagdownload.com/wp-content/uploads/Flag_of_{name}-512x256.png"
+
 type CountryServiceImpl struct {
-	query *gountries.Query
+	query      *gountries.Query
+	flagSource string
 }
 
-func NewCountryService() CountryService {
+// NewCountryService lists the countries with their flags at flagSource: a
+// URL containing {name}, or a directory holding a {name}.png per country.
+// An empty flagSource means defaultFlagSource
+func NewCountryService(flagSource string) CountryService {
 	return &CountryServiceImpl{
-		query: gountries.New(),
+		query:      gountries.New(),
+		flagSource: flagSourceTemplate(flagSource),
 	}
 }
 
+func flagSourceTemplate(flagSource string) string {
+	switch {
+	case flagSource == "":
+		return defaultFlagSource
+	case strings.Contains(flagSource, "://"):
+		return flagSource
+	}
+	if abs, err := filepath.Abs(flagSource); err == nil {
+		flagSource = abs
+	}
+	return "file://" + filepath.ToSlash(filepath.Join(flagSource, "{name}.png"))
+}
+
 var countryNameMappings = map[string]string{
 	"Taiwan":                   "Taiwan",
 	"United States":            "United_States_of_America",
@@ -56,25 +85,62 @@ func (s *CountryServiceImpl) getCleanCountryName(countryName string) string {
 }
 
 func (s *CountryServiceImpl) GetRandomCountry() CountryFlag {
-	allCountries := s.query.FindAllCountries()
-	if len(allCountries) == 0 {
-		return CountryFlag{"Sweden", "https://flagdownload.com/wp-content/uploads/Flag_of_Sweden-256x171.png"}
+	countryList := s.AllCountries()
+	if len(countryList) == 0 {
+		return CountryFlag{Name: "Sweden", FlagURL: "https://flagdownload.com/wp-content/uploads/Flag_of_Sweden-256x171.png", Capital: "Stockholm", Region: "Europe", SubRegion: "Northern Europe"}
 	}
+	return countryList[rand.Intn(len(countryList))]
+}
 
-	var countryList []gountries.Country
-	for _, country := range allCountries {
-		countryList = append(countryList, country)
+// AllCountries returns every known country sorted by name, so that the same
+// index always means the same country
+func (s *CountryServiceImpl) AllCountries() []CountryFlag {
+	var countryList []CountryFlag
+	for _, country := range s.query.FindAllCountries() {
+		countryName := country.Name.Common
+		cleanName := s.getCleanCountryName(countryName)
+		countryList = append(countryList, CountryFlag{
+			Name:      countryName,
+			FlagURL:   strings.ReplaceAll(s.flagSource, "{name}", cleanName),
+			Capital:   country.Capital,
+			Region:    country.Region,
+			SubRegion: country.SubRegion,
+		})
 	}
+	sort.Slice(countryList, func(i, j int) bool { return countryList[i].Name < countryList[j].Name })
+	return countryList
+}
 
-	randomCountry := countryList[rand.Intn(len(countryList))]
-	countryName := randomCountry.Name.Common
-	cleanName := s.getCleanCountryName(countryName)
-	flagURL := fmt.Sprintf("https://flagdownload.com/wp-content/uploads/Flag_of_%s-512x256.png", cleanName)
+// SimilarCountries returns up to n other countries, preferring the same
+// subregion, then the same region, then anywhere else, shuffled with rng
+func (s *CountryServiceImpl) SimilarCountries(country CountryFlag, n int, rng *rand.Rand) []string {
+	var sameSubRegion, sameRegion, others []string
+	for _, candidate := range s.query.FindAllCountries() {
+		name := candidate.Name.Common
+		switch {
+		case name == country.Name:
+			continue
+		case country.SubRegion != "" && candidate.SubRegion == country.SubRegion:
+			sameSubRegion = append(sameSubRegion, name)
+		case country.Region != "" && candidate.Region == country.Region:
+			sameRegion = append(sameRegion, name)
+		default:
+			others = append(others, name)
+		}
+	}
 
-	return CountryFlag{
-		Name:    countryName,
-		FlagURL: flagURL,
+	var similar []string
+	for _, group := range [][]string{sameSubRegion, sameRegion, others} {
+		// the countries come back in no fixed order, so they are sorted for
+		// a seeded generator to deal the same ones every time
+		sort.Strings(group)
+		rng.Shuffle(len(group), func(i, j int) { group[i], group[j] = group[j], group[i] })
+		similar = append(similar, group...)
+		if len(similar) >= n {
+			return similar[:n]
+		}
 	}
+	return similar
 }
 
 type ImageServiceImpl struct{}
@@ -119,10 +185,54 @@ func (s *ImageServiceImpl) downloadWithHeightFallbacks(originalURL string) (imag
 	return downloadFlagImage(originalURL)
 }
 
+// cachingImageService keeps every flag it downloads as a PNG in dir, so
+// flags are only fetched once across runs
+type cachingImageService struct {
+	ImageService
+	dir string
+}
+
+func newCachingImageService(images ImageService, dir string) ImageService {
+	return &cachingImageService{ImageService: images, dir: dir}
+}
+
+func (s *cachingImageService) DownloadFlag(url string) (image.Image, error) {
+	sum := sha256.Sum256([]byte(url))
+	path := filepath.Join(s.dir, hex.EncodeToString(sum[:12])+".png")
+	if data, err := os.ReadFile(path); err == nil {
+		if img, err := png.Decode(bytes.NewReader(data)); err == nil {
+			return img, nil
+		}
+	}
+
+	img, err := s.ImageService.DownloadFlag(url)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store(path, img); err != nil {
+		warnf("⚠️  Could not cache the flag at %s: %v", url, err)
+	}
+	return img, nil
+}
+
+func (s *cachingImageService) store(path string, img image.Image) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".tmp", buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
 func (s *ImageServiceImpl) ModifyColors(img image.Image, correct bool) image.Image {
 	return modifyFlagColors(img, correct)
 }
 
-func (s *ImageServiceImpl) ToBase64(img image.Image) (string, error) {
-	return imageToBase64(img)
+func (s *ImageServiceImpl) Tamper(img image.Image, rng *rand.Rand, difficulty Difficulty) Tamper {
+	return tamperFlagWith(img, rng, difficulty)
 }
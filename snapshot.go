@@ -0,0 +1,320 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// snapshotVersion goes up whenever a snapshot's fields change meaning.
+// Fields that are only added are left out by older snapshots and ignored
+// by older servers, so they need no new version
+const snapshotVersion = 1
+
+var (
+	errSnapshotNotFound = errors.New("no such saved game")
+	errSnapshotTooNew   = errors.New("saved by a newer version of the game")
+)
+
+// Snapshot is an unfinished game as it is saved after every transition: the
+// state itself, the parts of it that are not exported, and the images of
+// the current round
+type Snapshot struct {
+	Version int       `json:"version"`
+	Saved   time.Time `json:"saved"`
+	State   GameState `json:"state"`
+
+	// RandomDraws is how far a seeded game's generator had got
+	RandomDraws     uint64       `json:"randomDraws,omitempty"`
+	LoggedGuesses   int          `json:"loggedGuesses"`
+	ReplayedGuesses int          `json:"replayedGuesses"`
+	Deck            []int        `json:"deck,omitempty"`
+	DeckSize        int          `json:"deckSize,omitempty"`
+	Mask            *image.Alpha `json:"mask,omitempty"`
+	Images          []SavedImage `json:"images,omitempty"`
+}
+
+// replayImages are the pictures of one replayed round. They never change
+// once the round is revealed, so they are saved beside the snapshot once
+// rather than with every save
+type replayImages struct {
+	Shown    []byte   `json:"shown,omitempty"`
+	Original []byte   `json:"original,omitempty"`
+	Options  [][]byte `json:"options,omitempty"`
+}
+
+// Players lists who was playing, for the resume prompt
+func (s Snapshot) Players() string {
+	names := make([]string, len(s.State.Players))
+	for i, player := range s.State.Players {
+		names[i] = player.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// SnapshotStore writes one JSON file per unfinished game
+type SnapshotStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+func NewSnapshotStore(dir string) *SnapshotStore {
+	return &SnapshotStore{dir: dir}
+}
+
+func (s *SnapshotStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// imagesPath is where the pictures of a replayed round are kept
+func (s *SnapshotStore) imagesPath(id string, round int) string {
+	return filepath.Join(s.dir, id+".images", strconv.Itoa(round)+".json")
+}
+
+func (s *SnapshotStore) Save(snapshot Snapshot) error {
+	id := snapshot.State.GameID
+	if !validReplayID(id) {
+		return fmt.Errorf("invalid game ID %q", id)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.saveReplayImages(&snapshot); err != nil {
+		return err
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.path(id)+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(s.path(id)+".tmp", s.path(id))
+}
+
+// saveReplayImages writes the pictures of the rounds that have none saved
+// yet and leaves them out of the snapshot. The snapshot's replay is
+// copied first, as it still belongs to the running game. The caller must
+// hold s.mu
+func (s *SnapshotStore) saveReplayImages(snapshot *Snapshot) error {
+	id := snapshot.State.GameID
+	rounds := make([]ReplayRound, len(snapshot.State.Replay))
+	for i, round := range snapshot.State.Replay {
+		path := s.imagesPath(id, i)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			data, err := json.Marshal(replayImages{Shown: round.Shown, Original: round.Original, Options: round.Options})
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				return err
+			}
+		}
+		round.Shown, round.Original, round.Options = nil, nil, nil
+		rounds[i] = round
+	}
+	snapshot.State.Replay = rounds
+	return nil
+}
+
+func (s *SnapshotStore) Load(id string) (Snapshot, error) {
+	if !validReplayID(id) {
+		return Snapshot{}, errSnapshotNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot, err := s.load(id)
+	if err != nil {
+		return snapshot, err
+	}
+	for i := range snapshot.State.Replay {
+		data, err := os.ReadFile(s.imagesPath(id, i))
+		if err != nil {
+			// the replay can do without a round's pictures
+			warnf("⚠️  Missing the pictures of round %d of game %s: %v", i+1, id, err)
+			continue
+		}
+		var images replayImages
+		if err := json.Unmarshal(data, &images); err != nil {
+			warnf("⚠️  Unreadable pictures of round %d of game %s: %v", i+1, id, err)
+			continue
+		}
+		round := &snapshot.State.Replay[i]
+		round.Shown, round.Original, round.Options = images.Shown, images.Original, images.Options
+	}
+	return snapshot, nil
+}
+
+// load reads and checks one snapshot. The caller must hold s.mu
+func (s *SnapshotStore) load(id string) (Snapshot, error) {
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return Snapshot{}, errSnapshotNotFound
+	}
+	if err != nil {
+		return Snapshot{}, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return Snapshot{}, err
+	}
+	if snapshot.Version > snapshotVersion {
+		return Snapshot{}, fmt.Errorf("game %s: %w (version %d)", id, errSnapshotTooNew, snapshot.Version)
+	}
+	return snapshot, nil
+}
+
+// List returns every saved game that can be resumed, newest first. Files
+// that cannot be read are logged and left alone
+func (s *SnapshotStore) List() ([]Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []Snapshot
+	for _, file := range files {
+		snapshot, err := s.load(strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			warnf("⚠️  Skipping saved game %s: %v", file, err)
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Saved.After(snapshots[j].Saved) })
+	return snapshots, nil
+}
+
+func (s *SnapshotStore) Delete(id string) error {
+	if !validReplayID(id) {
+		return errSnapshotNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(s.dir, id+".images"))
+}
+
+// newSnapshot captures the game as it is right now
+func newSnapshot(deps *Dependencies) Snapshot {
+	state := deps.GameState
+	snapshot := Snapshot{
+		Version:         snapshotVersion,
+		Saved:           deps.now(),
+		State:           *state,
+		LoggedGuesses:   state.loggedGuesses,
+		ReplayedGuesses: state.replayedGuesses,
+		Mask:            state.tamperMask,
+	}
+	if state.Seeded {
+		snapshot.RandomDraws = state.RandomDraws()
+	}
+	if deck, ok := state.selector.(*DeckSelector); ok {
+		snapshot.Deck, snapshot.DeckSize = deck.deck, deck.size
+	}
+	if state.RoundID != "" {
+		images, err := deps.Images.SaveRound(state.RoundID)
+		if err != nil {
+			warnf("⚠️  Could not save the images of game %s: %v", state.GameID, err)
+		}
+		snapshot.Images = images
+	}
+	return snapshot
+}
+
+// snapshotGame saves the game after a transition, and forgets it once it is
+// over. Saving must never get in the way of playing, so failures are logged
+func snapshotGame(deps *Dependencies) {
+	state := deps.GameState
+	if deps.Snapshots == nil || !state.GameStarted {
+		return
+	}
+	var err error
+	if state.GameOver {
+		err = deps.Snapshots.Delete(state.GameID)
+	} else {
+		err = deps.Snapshots.Save(newSnapshot(deps))
+	}
+	if err != nil {
+		warnf("⚠️  Could not save game %s: %v", state.GameID, err)
+	}
+}
+
+// resumeGame makes a snapshot the current game again. The clock of an
+// unanswered round picks up where it stopped rather than where the wall
+// clock has got to
+func resumeGame(deps *Dependencies, snapshot Snapshot) {
+	state := deps.GameState
+	*state = snapshot.State
+	state.loggedGuesses = snapshot.LoggedGuesses
+	state.replayedGuesses = snapshot.ReplayedGuesses
+	state.tamperMask = snapshot.Mask
+	if state.Seeded {
+		state.restoreRandom(snapshot.RandomDraws)
+	}
+	if snapshot.Deck != nil {
+		state.selector = &DeckSelector{deck: snapshot.Deck, size: snapshot.DeckSize}
+	}
+	if state.RoundID != "" {
+		deps.Images.RestoreRound(state.RoundID, state.ShowResult, snapshot.Images)
+	}
+
+	if !state.ShowResult {
+		paused := deps.now().Sub(snapshot.Saved)
+		state.RoundStarted = state.RoundStarted.Add(paused)
+		if !state.RoundDeadline.IsZero() {
+			state.RoundDeadline = state.RoundDeadline.Add(paused)
+		}
+	}
+	publishEvent(deps, EventRoundStarted, "")
+}
+
+// resumeHandler resumes or throws away a saved game
+func resumeHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Snapshots == nil {
+			http.NotFound(w, r)
+			return
+		}
+		id := r.FormValue("id")
+		if r.FormValue("discard") != "" {
+			if err := deps.Snapshots.Delete(id); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+
+		snapshot, err := deps.Snapshots.Load(id)
+		if errors.Is(err, errSnapshotNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		resumeGame(deps, snapshot)
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
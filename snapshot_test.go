@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSnapshotTestDeps(store *SnapshotStore, now *time.Time) *Dependencies {
+	return &Dependencies{
+		GameState:      &GameState{},
+		CountryService: &MockCountryService{countries: []CountryFlag{{Name: "Austria"}, {Name: "Chad"}, {Name: "Peru"}}},
+		ImageService:   &MockImageService{},
+		Images:         NewImageStore(8),
+		Snapshots:      store,
+		Now:            func() time.Time { return *now },
+	}
+}
+
+// Test_GIVEN_SeededGameMidRound_WHEN_Resuming_THEN_ExpectSameGameAndRandomness tests that a resumed game carries on exactly
+func Test_GIVEN_SeededGameMidRound_WHEN_Resuming_THEN_ExpectSameGameAndRandomness(t *testing.T) {
+	// Arrange
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	store := NewSnapshotStore(t.TempDir())
+	original := newSnapshotTestDeps(store, &now)
+	initializeGameState(original.GameState, []Player{{Name: "Anna"}}, 5, GameSettings{Mode: ModeTrueFalse, TimeLimit: 30, Selection: SelectDeck})
+	original.GameState.SeedRandom(42)
+	if err := startRound(original); err != nil {
+		t.Fatal(err)
+	}
+	gameID := original.GameState.GameID
+
+	// Act - the server restarts ten minutes later
+	now = now.Add(10 * time.Minute)
+	resumed := newSnapshotTestDeps(store, &now)
+	snapshot, err := store.Load(gameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumeGame(resumed, snapshot)
+
+	// Assert
+	state := resumed.GameState
+	if state.GameID != gameID || state.CountryName != original.GameState.CountryName || state.Players[0].Name != "Anna" {
+		t.Errorf("Expected the same round, got %+v", state)
+	}
+	if state.RandomDraws() != original.GameState.RandomDraws() {
+		t.Errorf("Expected %d random draws, got %d", original.GameState.RandomDraws(), state.RandomDraws())
+	}
+	if state.random().Int63() != original.GameState.random().Int63() {
+		t.Error("Expected the resumed generator to carry on where it stopped")
+	}
+	if next, wanted := pickCountry(resumed), pickCountry(original); next.Name != wanted.Name {
+		t.Errorf("Expected the rest of the deck to be dealt the same, got %s instead of %s", next.Name, wanted.Name)
+	}
+	if remaining := state.RoundDeadline.Sub(now); remaining != 30*time.Second {
+		t.Errorf("Expected the round clock to pick up where it stopped, got %v left", remaining)
+	}
+	if response := serveFlagImage(resumed, state.FlagSrc, nil); response.Code != 200 {
+		t.Errorf("Expected the shown flag to be served again, got %d", response.Code)
+	}
+}
+
+// Test_GIVEN_SavedGame_WHEN_GameEnds_THEN_ExpectSnapshotRemoved tests that finished games are not offered again
+func Test_GIVEN_SavedGame_WHEN_GameEnds_THEN_ExpectSnapshotRemoved(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	store := NewSnapshotStore(t.TempDir())
+	deps := newSnapshotTestDeps(store, &now)
+	initializeGameState(deps.GameState, []Player{{Name: "Anna"}}, 1, GameSettings{Mode: ModeTrueFalse})
+	if err := startRound(deps); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := store.List(); len(saved) != 1 {
+		t.Fatalf("Expected the game to be saved, got %d saved games", len(saved))
+	}
+
+	// Act
+	finishGame(deps)
+
+	// Assert
+	if saved, _ := store.List(); len(saved) != 0 {
+		t.Errorf("Expected no saved games once it is over, got %d", len(saved))
+	}
+}
+
+// Test_GIVEN_RevealedRounds_WHEN_SavingAgain_THEN_ExpectPicturesSavedOnce tests that snapshots leave the replay's pictures out
+func Test_GIVEN_RevealedRounds_WHEN_SavingAgain_THEN_ExpectPicturesSavedOnce(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	dir := t.TempDir()
+	store := NewSnapshotStore(dir)
+	deps := newSnapshotTestDeps(store, &now)
+	initializeGameState(deps.GameState, []Player{{Name: "Anna"}}, 3, GameSettings{Mode: ModeTrueFalse})
+	if err := startRound(deps); err != nil {
+		t.Fatal(err)
+	}
+	answerRound(deps, "correct")
+	gameID := deps.GameState.GameID
+	picturesPath := filepath.Join(dir, gameID+".images", "0.json")
+	saved, err := os.Stat(picturesPath)
+	if err != nil {
+		t.Fatalf("Expected the first round's pictures to be saved, got %v", err)
+	}
+
+	// Act
+	now = now.Add(time.Minute)
+	deps.GameState.CurrentRound++
+	if err := startRound(deps); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := store.Load(gameID)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.Stat(picturesPath); !again.ModTime().Equal(saved.ModTime()) {
+		t.Error("Expected the pictures not to be written again")
+	}
+	var file Snapshot
+	data, _ := os.ReadFile(filepath.Join(dir, gameID+".json"))
+	if json.Unmarshal(data, &file); len(file.State.Replay) != 1 || file.State.Replay[0].Shown != nil {
+		t.Error("Expected the snapshot to leave the pictures out")
+	}
+	if len(snapshot.State.Replay) != 1 || snapshot.State.Replay[0].Shown == nil {
+		t.Errorf("Expected the loaded replay to get its pictures back, got %d rounds", len(snapshot.State.Replay))
+	}
+	if deps.GameState.Replay[0].Shown == nil {
+		t.Error("Expected the running game to keep its pictures")
+	}
+	finishGame(deps)
+	if _, err := os.Stat(picturesPath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the pictures to go with the saved game, got %v", err)
+	}
+}
+
+func TestSnapshotFromNewerVersion(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	store := NewSnapshotStore(dir)
+	os.WriteFile(filepath.Join(dir, "future.json"), []byte(`{"version": 99, "state": {"GameID": "future"}}`), 0o644)
+	store.Save(Snapshot{Version: snapshotVersion, State: GameState{GameID: "current", GameStarted: true}})
+
+	// Act
+	_, err := store.Load("future")
+	saved, listErr := store.List()
+
+	// Assert
+	if !errors.Is(err, errSnapshotTooNew) {
+		t.Errorf("Expected errSnapshotTooNew, got %v", err)
+	}
+	if listErr != nil || len(saved) != 1 || saved[0].State.GameID != "current" {
+		t.Errorf("Expected only the current snapshot to be offered, got %+v (%v)", saved, listErr)
+	}
+}
+
+func TestResumeHandler(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	store := NewSnapshotStore(t.TempDir())
+	deps := newSnapshotTestDeps(store, &now)
+	initializeGameState(deps.GameState, []Player{{Name: "Anna"}, {Name: "Ben"}}, 5, GameSettings{Mode: ModeTrueFalse})
+	if err := startRound(deps); err != nil {
+		t.Fatal(err)
+	}
+	gameID := deps.GameState.GameID
+	resetGameState(deps.GameState)
+
+	// Act
+	setup := httptest.NewRecorder()
+	indexHandler(deps).ServeHTTP(setup, httptest.NewRequest("GET", "/", nil))
+	resumeHandler(deps).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/resume?id="+gameID, nil))
+
+	// Assert
+	if body := setup.Body.String(); !strings.Contains(body, "Anna, Ben - round 1 of 5") || !strings.Contains(body, gameID) {
+		t.Errorf("Expected the setup page to offer the unfinished game, got %s", body)
+	}
+	if state := deps.GameState; !state.GameStarted || state.GameID != gameID || len(state.Players) != 2 {
+		t.Errorf("Expected the game to be resumed, got %+v", state)
+	}
+}
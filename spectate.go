@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// spectateHandler shows a read-only view of a game for a projector or a
+// stream. The game ID is either the local game's ID or a party room code
+func spectateHandler(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		gameID := r.PathValue("gameID")
+		tmpl, err := parseGameTemplate(spectateTemplate)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// the page is rendered into a buffer so a slow viewer does not hold
+		// the local game's lock
+		var page bytes.Buffer
+		unlock := deps.lockGame()
+		state := deps.GameState
+		local := state.GameStarted && state.GameID == gameID
+		if local {
+			expireRound(deps)
+			err = tmpl.Execute(&page, roomView{Code: gameID, EventsURL: "/events", State: state, Me: -1})
+		}
+		unlock()
+		if local {
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			page.WriteTo(w)
+			return
+		}
+		if deps.Rooms != nil {
+			if room := deps.Rooms.Get(gameID); room != nil {
+				room.Render(w, tmpl, joinURL(r, room.Code), -1)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"image"
+	"math"
+)
+
+const (
+	// Spot-the-difference flags are always served at this width so click
+	// coordinates line up with the tamper mask
+	spotImageWidth = 512
+
+	// Clicks within this many pixels of the changed region earn partial credit
+	spotNearRadius = 48.0
+	spotBasePoints = 300
+)
+
+// prepareSpotRound tampers the flag and keeps the mask for scoring clicks
+func prepareSpotRound(deps *Dependencies, originalImg image.Image) {
+	state := deps.GameState
+	scaled := scaleToWidth(originalImg, spotImageWidth)
+	tamper := deps.ImageService.Tamper(scaled, deps.GameState.random(), deps.GameState.Difficulty)
+
+	state.IsCorrect = false
+	state.RoundID = deps.Images.NewRound()
+	state.tamperMask = tamper.Mask
+	state.TamperDescription = tamper.Description()
+	state.TamperMode = tamper.Mode()
+	state.DeltaE = tamper.DeltaE
+	state.FlagWidth = scaled.Bounds().Dx()
+	state.FlagHeight = scaled.Bounds().Dy()
+	state.SpotX, state.SpotY = -1, -1
+
+	deps.Images.Put(state.RoundID, variantShown, tamper.Image)
+	deps.Images.PutHidden(state.RoundID, variantOriginal, scaled)
+	deps.Images.PutHidden(state.RoundID, variantHighlight, highlightMask(tamper.Image, tamper.Mask))
+	state.FlagSrc = flagImageURL(state.RoundID, variantShown)
+	state.OriginalSrc = flagImageURL(state.RoundID, variantOriginal)
+	state.ModifiedSrc = ""
+	state.HighlightSrc = flagImageURL(state.RoundID, variantHighlight)
+}
+
+// spotCredit is 1 for a click inside the changed region and falls off
+// linearly to 0 at spotNearRadius pixels away
+func spotCredit(mask *image.Alpha, p image.Point) float64 {
+	if mask == nil {
+		return 0
+	}
+	distance := distanceToMask(mask, p)
+	if distance < 0 {
+		return 0
+	}
+	return math.Max(0, 1-distance/spotNearRadius)
+}
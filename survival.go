@@ -0,0 +1,86 @@
+package main
+
+import "sort"
+
+// loseLife takes a life from the player and reports whether that eliminated them
+func loseLife(state *GameState, player *Player) bool {
+	if player.Eliminated || player.Lives <= 0 {
+		return false
+	}
+
+	player.Lives--
+	if player.Lives > 0 {
+		return false
+	}
+
+	state.Eliminations++
+	player.Eliminated = true
+	player.EliminatedAt = state.Eliminations
+	player.EliminatedRound = state.CurrentRound
+	return true
+}
+
+func activePlayers(state *GameState) int {
+	active := 0
+	for _, player := range state.Players {
+		if !player.Eliminated {
+			active++
+		}
+	}
+	return active
+}
+
+// survivalOver is true once one player is left standing, or when a solo
+// player has run out of lives
+func survivalOver(state *GameState) bool {
+	active := activePlayers(state)
+	if len(state.Players) == 1 {
+		return active == 0
+	}
+	return active <= 1
+}
+
+// rotateSurvivors moves the turn to the next player who still has lives.
+// Survival games ignore TotalRounds and only end once survivalOver says so
+func rotateSurvivors(deps *Dependencies) bool {
+	state := deps.GameState
+	if survivalOver(state) {
+		finishGame(deps)
+		return false
+	}
+
+	next := state.CurrentPlayer
+	for {
+		next = (next + 1) % len(state.Players)
+		if next == 0 {
+			state.CurrentRound++
+		}
+		if !state.Players[next].Eliminated {
+			break
+		}
+	}
+	state.CurrentPlayer = next
+	return true
+}
+
+// Standings ranks the players for the final results. Survival games rank
+// by how long players lasted, everything else by points
+func (s *GameState) Standings() []Player {
+	standings := make([]Player, len(s.Players))
+	copy(standings, s.Players)
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if s.Lives > 0 && a.Eliminated != b.Eliminated {
+			return !a.Eliminated
+		}
+		if s.Lives > 0 && a.EliminatedAt != b.EliminatedAt {
+			return a.EliminatedAt > b.EliminatedAt
+		}
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		return a.Correct > b.Correct
+	})
+	return standings
+}
@@ -0,0 +1,74 @@
+package main
+
+import "strings"
+
+const (
+	defaultMaxPlayers = 4
+	maxTeamMembers    = 10
+)
+
+type setupView struct {
+	MaxPlayers     int
+	MaxTeamMembers int
+	Profiles       []Profile
+	SavedGames     []Snapshot
+}
+
+// maxPlayers is how many players or teams a game can have
+func (deps *Dependencies) maxPlayers() int {
+	if deps.MaxPlayers > 0 {
+		return deps.MaxPlayers
+	}
+	return defaultMaxPlayers
+}
+
+// parseTeams builds one scoreboard entry per team from the team names and
+// their comma-separated member lists. Teams without members are skipped
+func parseTeams(names, memberLists []string, limit int) []Player {
+	var teams []Player
+	for i, name := range names {
+		if len(teams) >= limit || i >= len(memberLists) {
+			break
+		}
+
+		var members []string
+		for _, member := range strings.Split(memberLists[i], ",") {
+			if member = strings.TrimSpace(member); member != "" && len(members) < maxTeamMembers {
+				members = append(members, member)
+			}
+		}
+		if len(members) == 0 {
+			continue
+		}
+
+		name = strings.TrimSpace(name)
+		if name == "" {
+			name = members[0] + "'s team"
+		}
+		teams = append(teams, Player{Name: name, Team: &Team{Members: members}})
+	}
+	return teams
+}
+
+// passTeamTurn hands the team's next turn to its next member
+func passTeamTurn(player *Player) {
+	if player.Team != nil && len(player.Team.Members) > 0 {
+		player.Team.Turn = (player.Team.Turn + 1) % len(player.Team.Members)
+	}
+}
+
+// CurrentMember is the team member whose turn it is, or the player's own name
+func (p Player) CurrentMember() string {
+	if p.Team == nil || len(p.Team.Members) == 0 {
+		return p.Name
+	}
+	return p.Team.Members[p.Team.Turn]
+}
+
+// TurnName names whoever is answering, including their team when playing in teams
+func (p Player) TurnName() string {
+	if p.Team == nil || len(p.Team.Members) == 0 {
+		return p.Name
+	}
+	return p.CurrentMember() + " (" + p.Name + ")"
+}
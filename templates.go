@@ -35,7 +35,7 @@ const setupTemplate = `
             margin-bottom: 10px;
             font-weight: bold;
         }
-        input[type="number"], input[type="text"] {
+        input[type="number"], input[type="text"], select {
             width: 100%;
             padding: 12px;
             font-size: 16px;
@@ -44,10 +44,14 @@ const setupTemplate = `
             box-sizing: border-box;
             margin-bottom: 15px;
         }
-        input[type="number"]:focus, input[type="text"]:focus {
+        input[type="number"]:focus, input[type="text"]:focus, select:focus {
             outline: none;
             border-color: #3498db;
         }
+        .checkbox-label {
+            font-weight: normal;
+            cursor: pointer;
+        }
         .player-inputs {
             margin-top: 20px;
         }
@@ -81,21 +85,32 @@ const setupTemplate = `
         }
     </style>
     <script>
+        const maxPlayers = {{.MaxPlayers}};
+
         function updatePlayerInputs() {
             const numPlayers = parseInt(document.getElementById('numPlayers').value) || 1;
             const container = document.getElementById('playerInputs');
-            const limitedPlayers = Math.min(Math.max(numPlayers, 1), 4);
+            const limitedPlayers = Math.min(Math.max(numPlayers, 1), maxPlayers);
+            const teams = document.getElementById('teams').checked;
             
             if (numPlayers !== limitedPlayers) {
                 document.getElementById('numPlayers').value = limitedPlayers;
             }
+            document.getElementById('numPlayersLabel').textContent =
+                'Number of ' + (teams ? 'Teams' : 'Players') + ' (1-' + maxPlayers + '):';
             
             container.innerHTML = '';
             for (let i = 0; i < limitedPlayers; i++) {
                 const div = document.createElement('div');
                 div.className = 'player-input';
-                div.innerHTML = '<label for="player' + (i+1) + '">Player ' + (i+1) + ' Name:</label>' +
-                               '<input type="text" id="player' + (i+1) + '" name="playerName" placeholder="Enter name" required>';
+                if (teams) {
+                    div.innerHTML = '<label for="team' + (i+1) + '">Team ' + (i+1) + ' Name:</label>' +
+                                   '<input type="text" id="team' + (i+1) + '" name="teamName" placeholder="Enter team name" required>' +
+                                   '<input type="text" name="teamMembers" placeholder="Members, separated by commas" required>';
+                } else {
+                    div.innerHTML = '<label for="player' + (i+1) + '">Player ' + (i+1) + ' Name:</label>' +
+                                   '<input type="text" id="player' + (i+1) + '" name="playerName" list="profiles" placeholder="Enter name or pick a saved player" required>';
+                }
                 container.appendChild(div);
             }
         }
@@ -109,13 +124,99 @@ const setupTemplate = `
     <div class="container">
         <h1>🏴 Flag Quiz Game</h1>
         <h2 style="text-align: center; color: #7f8c8d; margin-top: -20px;">Player Setup</h2>
-        
+
+        {{if .SavedGames}}
+        <div class="setup-section">
+            <label>Unfinished Games:</label>
+            {{range .SavedGames}}
+            <form method="POST" action="/resume" class="player-input">
+                <input type="hidden" name="id" value="{{.State.GameID}}">
+                <div class="info">{{.Players}} - round {{.State.CurrentRound}} of {{.State.TotalRounds}}, saved {{.Saved.Format "2 Jan 15:04"}}</div>
+                <button type="submit" class="btn">Resume</button>
+                <button type="submit" name="discard" value="1" class="btn" style="background-color: #95a5a6;">Discard</button>
+            </form>
+            {{end}}
+        </div>
+        {{end}}
+
         <form method="POST" action="/setup">
             <div class="setup-section">
-                <label for="numPlayers">Number of Players (1-4):</label>
-                <input type="number" id="numPlayers" min="1" max="4" value="1" onchange="updatePlayerInputs()" required>
+                <label class="checkbox-label">
+                    <input type="checkbox" id="teams" name="teams" onchange="updatePlayerInputs()">
+                    Play in teams (up to {{.MaxTeamMembers}} members each, taking turns)
+                </label>
+            </div>
+
+            <div class="setup-section">
+                <label for="numPlayers" id="numPlayersLabel">Number of Players (1-{{.MaxPlayers}}):</label>
+                <input type="number" id="numPlayers" min="1" max="{{.MaxPlayers}}" value="1" onchange="updatePlayerInputs()" required>
             </div>
             
+            <div class="setup-section">
+                <label for="mode">Game Mode:</label>
+                <select id="mode" name="mode">
+                    <option value="truefalse">Spot the fake: is this flag correct?</option>
+                    <option value="choice">Name this flag: pick the right country</option>
+                    <option value="pickreal">Pick the real one: find the genuine flag</option>
+                    <option value="spot">Spot the difference: click what was changed</option>
+                </select>
+            </div>
+
+            <div class="setup-section">
+                <label for="difficulty">Difficulty:</label>
+                <select id="difficulty" name="difficulty">
+                    <option value="easy">Easy - bold, obvious color swaps</option>
+                    <option value="normal" selected>Normal - a mix of bold and subtle changes</option>
+                    <option value="hard">Hard - only subtle shade changes</option>
+                </select>
+            </div>
+
+            <div class="setup-section">
+                <label for="selection">Which Flags Come Up:</label>
+                <select id="selection" name="selection">
+                    <option value="uniform">Any flag, every round</option>
+                    <option value="deck">Every flag once before any repeats</option>
+                    <option value="weakness">Focus on the flags you get wrong</option>
+                </select>
+                <label for="exploration">Mix in other flags (focus on weaknesses):</label>
+                <select id="exploration" name="exploration">
+                    <option value="0.05">Rarely</option>
+                    <option value="0.15" selected>Sometimes</option>
+                    <option value="0.4">Often</option>
+                </select>
+            </div>
+
+            <div class="setup-section">
+                <label for="pickOptions">Flags per Round (Pick the real one):</label>
+                <select id="pickOptions" name="pickOptions">
+                    <option value="2">2 flags</option>
+                    <option value="3" selected>3 flags</option>
+                    <option value="4">4 flags</option>
+                </select>
+            </div>
+
+            <div class="setup-section">
+                <label for="timeLimit">Time per Answer:</label>
+                <select id="timeLimit" name="timeLimit">
+                    <option value="0">No time limit</option>
+                    <option value="10">10 seconds</option>
+                    <option value="15">15 seconds</option>
+                    <option value="20">20 seconds</option>
+                    <option value="30">30 seconds</option>
+                    <option value="60">60 seconds</option>
+                </select>
+            </div>
+
+            <div class="setup-section">
+                <label for="lives">Survival Mode:</label>
+                <select id="lives" name="lives">
+                    <option value="0">Off - play a fixed number of rounds</option>
+                    <option value="1">Sudden death - 1 life</option>
+                    <option value="3">3 lives</option>
+                    <option value="5">5 lives</option>
+                </select>
+            </div>
+
             <div class="setup-section">
                 <label for="numRounds">Number of Rounds:</label>
                 <input type="number" id="numRounds" name="numRounds" min="1" max="50" value="10" required>
@@ -124,6 +225,9 @@ const setupTemplate = `
             <div class="player-inputs" id="playerInputs">
                 <!-- Player inputs will be generated by JavaScript -->
             </div>
+            <datalist id="profiles">
+                {{range .Profiles}}<option value="{{.Name}}">{{end}}
+            </datalist>
             
             <button type="submit" class="btn">Start Game</button>
             
@@ -134,292 +238,596 @@ const setupTemplate = `
 </html>
 `
 
+// gamePartials contains the styles and blocks shared by the game pages
+const gamePartials = `
+{{define "gameStyles"}}
+<style>
+    body {
+        font-family: Arial, sans-serif;
+        max-width: 900px;
+        margin: 20px auto;
+        padding: 20px;
+        background-color: #f0f8ff;
+    }
+    .container {
+        background-color: white;
+        padding: 30px;
+        border-radius: 15px;
+        box-shadow: 0 4px 15px rgba(0,0,0,0.1);
+    }
+    h1 {
+        color: #2c3e50;
+        text-align: center;
+        margin-bottom: 10px;
+    }
+    .subtitle {
+        text-align: center;
+        color: #7f8c8d;
+        margin-bottom: 30px;
+        font-style: italic;
+    }
+    .game-area {
+        text-align: center;
+        margin: 30px 0;
+    }
+    .flag-container {
+        display: inline-block;
+        border: 3px solid #34495e;
+        border-radius: 10px;
+        overflow: hidden;
+        margin: 20px 0;
+        box-shadow: 0 2px 10px rgba(0,0,0,0.2);
+    }
+    .flag-image {
+        display: block;
+        max-height: 100%;
+        max-width: 200%;
+    }
+    .question {
+        font-size: 24px;
+        color: #2c3e50;
+        margin: 20px 0;
+        font-weight: bold;
+    }
+    .country-name {
+        font-size: 20px;
+        color: #e74c3c;
+        margin: 15px 0;
+        font-weight: bold;
+    }
+    .current-turn {
+        font-size: 18px;
+        color: #3498db;
+        margin: 10px 0;
+        font-weight: bold;
+        font-style: italic;
+    }
+    .buttons {
+        margin: 30px 0;
+    }
+    .btn {
+        font-size: 18px;
+        padding: 15px 30px;
+        margin: 10px;
+        border: none;
+        border-radius: 8px;
+        cursor: pointer;
+        font-weight: bold;
+        transition: all 0.3s ease;
+    }
+    .btn-correct {
+        background-color: #27ae60;
+        color: white;
+    }
+    .btn-correct:hover {
+        background-color: #229954;
+    }
+    .btn-incorrect {
+        background-color: #e74c3c;
+        color: white;
+    }
+    .btn-incorrect:hover {
+        background-color: #c0392b;
+    }
+    .btn-new {
+        background-color: #3498db;
+        color: white;
+    }
+    .btn-new:hover {
+        background-color: #2980b9;
+    }
+    .result {
+        font-size: 20px;
+        font-weight: bold;
+        margin: 20px 0;
+        padding: 15px;
+        border-radius: 8px;
+    }
+    .result.correct {
+        background-color: #d5f4e6;
+        color: #27ae60;
+        border: 2px solid #27ae60;
+    }
+    .result.incorrect {
+        background-color: #fadbd8;
+        color: #e74c3c;
+        border: 2px solid #e74c3c;
+    }
+    .score {
+        background-color: #ebf3fd;
+        padding: 15px;
+        border-radius: 8px;
+        margin: 20px 0;
+    }
+    .score h3 {
+        margin: 0 0 15px 0;
+        color: #2c3e50;
+        text-align: center;
+    }
+    .player-score {
+        background-color: white;
+        border: 2px solid #d1d8e0;
+        border-radius: 8px;
+        padding: 12px;
+        margin-bottom: 10px;
+        transition: all 0.3s ease;
+    }
+    .player-score.current-player {
+        border-color: #3498db;
+        background-color: #e8f4f8;
+        box-shadow: 0 2px 8px rgba(52, 152, 219, 0.3);
+    }
+    .player-name {
+        font-size: 18px;
+        font-weight: bold;
+        color: #2c3e50;
+        margin-bottom: 10px;
+        text-align: center;
+    }
+    .current-player .player-name {
+        color: #3498db;
+    }
+    .team-members {
+        text-align: center;
+        margin-bottom: 8px;
+    }
+    .team-member {
+        display: inline-block;
+        font-size: 13px;
+        color: #7f8c8d;
+        padding: 2px 8px;
+        margin: 2px;
+        border-radius: 10px;
+        background-color: #f4f6f7;
+    }
+    .team-member.up-next {
+        color: white;
+        background-color: #3498db;
+    }
+    .stats {
+        display: flex;
+        justify-content: space-around;
+        flex-wrap: wrap;
+    }
+    .stat {
+        margin: 5px;
+    }
+    .stat-value {
+        font-size: 24px;
+        font-weight: bold;
+        color: #3498db;
+    }
+    .stat-label {
+        font-size: 14px;
+        color: #7f8c8d;
+    }
+    .flag-comparison {
+        display: flex;
+        justify-content: center;
+        gap: 20px;
+        margin: 20px 0;
+        flex-wrap: wrap;
+    }
+    .flag-box {
+        text-align: center;
+        padding: 10px;
+        border-radius: 8px;
+        background-color: #f8f9fa;
+        border: 2px solid #dee2e6;
+        min-width: 120px;
+    }
+    .flag-box h4 {
+        margin: 0 0 10px 0;
+        font-size: 14px;
+        color: #495057;
+    }
+    .flag-thumbnail {
+        display: block;
+        max-width: 100%;
+        height: auto;
+        border: 2px solid #333;
+        border-radius: 4px;
+        object-fit: cover;
+    }
+    .final-results {
+        background-color: #fff;
+        border: 3px solid #f39c12;
+        border-radius: 10px;
+        padding: 20px;
+        margin: 20px 0;
+    }
+    .final-results h2 {
+        color: #2c3e50;
+        margin-top: 0;
+        text-align: center;
+    }
+    .choices {
+        display: grid;
+        grid-template-columns: 1fr 1fr;
+        gap: 10px;
+        max-width: 600px;
+        margin: 30px auto;
+    }
+    .btn-choice {
+        background-color: #ecf0f1;
+        color: #2c3e50;
+        border: 2px solid #bdc3c7;
+        margin: 0;
+    }
+    .btn-choice:hover:enabled {
+        background-color: #d6eaf8;
+        border-color: #3498db;
+    }
+    .btn-choice.answer {
+        background-color: #d5f4e6;
+        border-color: #27ae60;
+        color: #27ae60;
+    }
+    .btn-choice.chosen-wrong {
+        background-color: #fadbd8;
+        border-color: #e74c3c;
+        color: #e74c3c;
+    }
+    .pick-options {
+        display: flex;
+        justify-content: center;
+        gap: 15px;
+        flex-wrap: wrap;
+        margin: 20px 0;
+    }
+    .pick-option {
+        width: 200px;
+        padding: 6px;
+        border: 3px solid #34495e;
+        border-radius: 10px;
+        background-color: white;
+        cursor: pointer;
+        transition: all 0.3s ease;
+    }
+    .pick-option:hover:enabled {
+        border-color: #3498db;
+        transform: scale(1.03);
+    }
+    .pick-option:disabled {
+        cursor: default;
+    }
+    .pick-option img {
+        display: block;
+        width: 100%;
+        height: auto;
+    }
+    .pick-option.answer {
+        border-color: #27ae60;
+        box-shadow: 0 0 12px rgba(39, 174, 96, 0.6);
+    }
+    .pick-option.chosen-wrong {
+        border-color: #e74c3c;
+        opacity: 0.6;
+    }
+    .spot-area {
+        position: relative;
+        display: inline-block;
+        line-height: 0;
+    }
+    .spot-image {
+        display: block;
+        max-width: none;
+        cursor: crosshair;
+    }
+    .spot-marker {
+        position: absolute;
+        width: 24px;
+        height: 24px;
+        margin: -15px 0 0 -15px;
+        border: 3px solid #f39c12;
+        border-radius: 50%;
+        box-shadow: 0 0 0 2px white;
+        pointer-events: none;
+    }
+    .countdown {
+        font-size: 20px;
+        font-weight: bold;
+        color: #e67e22;
+        margin: 10px auto;
+        max-width: 300px;
+    }
+    .countdown-bar {
+        height: 8px;
+        background-color: #ecf0f1;
+        border-radius: 4px;
+        overflow: hidden;
+        margin-top: 5px;
+    }
+    .countdown-fill {
+        height: 100%;
+        width: 100%;
+        background-color: #e67e22;
+    }
+    .time-bonus {
+        font-size: 16px;
+        color: #e67e22;
+        margin-top: 5px;
+    }
+    .player-score.eliminated {
+        opacity: 0.5;
+    }
+    .achievement-earned {
+        font-size: 16px;
+        color: #8e44ad;
+        background-color: #f4ecf7;
+        border-radius: 6px;
+        padding: 6px;
+        margin-top: 8px;
+    }
+    .badges {
+        margin-top: 8px;
+    }
+    .avatar {
+        display: inline-block;
+        width: 14px;
+        height: 14px;
+        border-radius: 50%;
+        vertical-align: middle;
+        margin-right: 4px;
+    }
+    .badge {
+        display: inline-block;
+        font-size: 13px;
+        background-color: #f4ecf7;
+        color: #8e44ad;
+        border-radius: 12px;
+        padding: 3px 10px;
+        margin: 2px;
+    }
+    .final-player {
+        font-size: 18px;
+        padding: 12px;
+        margin: 10px 0;
+        background-color: #ecf0f1;
+        border-radius: 5px;
+        text-align: center;
+    }
+</style>
+{{end}}
+
+{{define "scoreboard"}}
+<div class="score">
+    {{if .Lives}}
+    <h3>Scoreboard (Round {{.CurrentRound}} - Survival)</h3>
+    {{else}}
+    <h3>Scoreboard {{if .TotalRounds}}(Round {{.CurrentRound}}/{{.TotalRounds}}){{end}}</h3>
+    {{end}}
+    {{range $index, $player := .Players}}
+    <div class="player-score {{if eq $index $.CurrentPlayer}}current-player{{end}} {{if $player.Eliminated}}eliminated{{end}}">
+        <div class="player-name">
+            {{if eq $index $.CurrentPlayer}}▶{{end}} {{with $player.Color}}<span class="avatar" style="background-color: {{.}}"></span>{{end}}{{$player.Name}}
+        </div>
+        {{with $player.Team}}
+        <div class="team-members">
+            {{range $member, $name := .Members}}<span class="team-member {{if eq $member $player.Team.Turn}}up-next{{end}}">{{$name}}</span>{{end}}
+        </div>
+        {{end}}
+        <div class="stats">
+            <div class="stat">
+                <div class="stat-value">{{$player.Correct}}</div>
+                <div class="stat-label">Correct</div>
+            </div>
+            <div class="stat">
+                <div class="stat-value">{{$player.Incorrect}}</div>
+                <div class="stat-label">Incorrect</div>
+            </div>
+            <div class="stat">
+                <div class="stat-value">{{$player.Total}}</div>
+                <div class="stat-label">Total</div>
+            </div>
+            <div class="stat">
+                <div class="stat-value">{{$player.Percentage}}%</div>
+                <div class="stat-label">Accuracy</div>
+            </div>
+            <div class="stat">
+                <div class="stat-value">{{$player.Points}}</div>
+                <div class="stat-label">Points</div>
+            </div>
+            <div class="stat">
+                <div class="stat-value">🔥 {{$player.Streak}}</div>
+                <div class="stat-label">Streak</div>
+            </div>
+            {{if $.Lives}}
+            <div class="stat">
+                <div class="stat-value">{{if $player.Eliminated}}💀{{else}}❤️ {{$player.Lives}}{{end}}</div>
+                <div class="stat-label">{{if $player.Eliminated}}Out{{else}}Lives{{end}}</div>
+            </div>
+            {{end}}
+        </div>
+    </div>
+    {{end}}
+</div>
+{{end}}
+
+{{define "countdown"}}
+{{if and (not .RoundDeadline.IsZero) (not .ShowResult)}}
+<div class="countdown" id="countdown" data-deadline="{{.RoundDeadline.UnixMilli}}" data-limit="{{.TimeLimit}}">
+    ⏱ <span id="countdown-seconds">{{.TimeLimit}}</span>s
+    <div class="countdown-bar"><div class="countdown-fill" id="countdown-fill"></div></div>
+</div>
+<script>
+    (function() {
+        const el = document.getElementById('countdown');
+        const deadline = parseInt(el.dataset.deadline);
+        const limit = parseInt(el.dataset.limit) * 1000;
+        function tick() {
+            const left = Math.max(0, deadline - Date.now());
+            document.getElementById('countdown-seconds').textContent = Math.ceil(left / 1000);
+            document.getElementById('countdown-fill').style.width = (100 * left / limit) + '%';
+            if (left <= 0) {
+                // the server marks the round as timed out on the next page load
+                location.reload();
+                return;
+            }
+            setTimeout(tick, 100);
+        }
+        tick();
+    })();
+</script>
+{{end}}
+{{end}}
+
+{{define "spectateLink"}}
+{{with .GameID}}<div class="stat-label" style="text-align: right;"><a href="/spectate/{{.}}" target="_blank">📺 Spectator view</a></div>{{end}}
+{{end}}
+
+{{define "liveUpdates"}}
+<script>
+    (function() {
+        // the page reloads when another browser changes the game, unless it
+        // already shows that change
+        const el = document.getElementById('live-updates');
+        if (!el || !window.EventSource) {
+            return;
+        }
+        const version = parseInt(el.dataset.version);
+        const source = new EventSource(el.dataset.events);
+        function refresh(e) {
+            if (JSON.parse(e.data).version > version) {
+                source.close();
+                location.reload();
+            }
+        }
+        ['player-joined', 'player-ready', 'player-kicked', 'round-started', 'answer-submitted', 'result-revealed', 'game-over'].forEach(function(type) {
+            source.addEventListener(type, refresh);
+        });
+    })();
+</script>
+{{end}}
+
+{{define "resultExtras"}}
+{{if gt .Multiplier 1.0}}<div class="time-bonus">🔥 Streak bonus ×{{printf "%.2g" .Multiplier}}</div>{{end}}
+{{if .TimeBonus}}<div class="time-bonus">⚡ +{{.TimeBonus}} speed bonus</div>{{end}}
+{{range .NewAchievements}}
+<div class="achievement-earned">{{.Icon}} Achievement unlocked: <strong>{{.Name}}</strong> - {{.Description}}</div>
+{{end}}
+{{end}}
+
+{{define "gameOver"}}
+<div class="question">🏆 Game Over! 🏆</div>
+{{template "finalResults" .}}
+<button class="btn btn-new" onclick="location.href='/setup'">New Game</button>
+<button class="btn" onclick="location.href='/leaderboard'">Leaderboard</button>
+<button class="btn" onclick="location.href='/profiles'">Player Profiles</button>
+<button class="btn" onclick="location.href='/stats'">Flag Stats</button>
+{{with .ReplayID}}<button class="btn" onclick="location.href='/games/{{.}}/review'">Review Game</button>{{end}}
+{{end}}
+
+{{define "replayRound"}}
+<div class="question">Round {{.Round}}: {{.Country}}{{with .Region}} <span class="stat-label">({{.}})</span>{{end}}</div>
+<div class="subtitle">{{if .Options}}The closest fake was ΔE {{printf "%.1f" .DeltaE}} from the real flag{{else if .Truth}}The real flag was shown{{else}}{{with .TamperDescription}}{{.}}{{else}}A fake flag was shown{{end}} (ΔE {{printf "%.1f" .DeltaE}}){{end}}</div>
+<div class="flag-comparison">
+    {{if .Options}}
+    {{range $i, $option := .Options}}
+    <div class="flag-box">
+        <h4>Option {{inc $i}}{{if eq $i $.RealOption}} (real){{end}}</h4>
+        {{with $option}}<img src="{{pngData .}}" alt="Option {{inc $i}}" class="flag-thumbnail">{{end}}
+    </div>
+    {{end}}
+    {{else}}
+    <div class="flag-box">
+        <h4>Shown</h4>
+        {{with .Shown}}<img src="{{pngData .}}" alt="Shown Flag" class="flag-thumbnail">{{end}}
+    </div>
+    <div class="flag-box">
+        <h4>Original</h4>
+        {{with .Original}}<img src="{{pngData .}}" alt="Original Flag" class="flag-thumbnail">{{end}}
+    </div>
+    {{end}}
+</div>
+<div class="final-results">
+    {{range .Answers}}
+    <div class="final-player">{{if .Correct}}✓{{else}}✗{{end}} <strong>{{.Player}}</strong>: {{.Describe}} <span class="stat-label">in {{.Seconds}}s</span></div>
+    {{end}}
+</div>
+{{end}}
+
+{{define "finalResults"}}
+<div class="final-results">
+    <h2>Final Results</h2>
+    {{range $place, $player := .Standings}}
+    <div class="final-player">
+        #{{inc $place}} <strong>{{$player.Name}}</strong>{{with $player.Team}} ({{range $i, $m := .Members}}{{if $i}}, {{end}}{{$m}}{{end}}){{end}}: {{$player.Correct}} correct out of {{$player.Total}} ({{$player.Percentage}}%) - {{$player.Points}} points
+        {{if $player.Eliminated}}<span class="stat-label">(out in round {{$player.EliminatedRound}})</span>{{end}}
+        <div class="stat-label">Best streak: {{$player.BestStreak}}</div>
+        {{with $player.Badges}}
+        <div class="badges">
+            {{range .}}<span class="badge" title="{{.Description}}">{{.Icon}} {{.Name}}</span>{{end}}
+        </div>
+        {{end}}
+    </div>
+    {{end}}
+</div>
+{{end}}
+`
+
 // htmlTemplate contains the HTML template for the flag quiz game
 const htmlTemplate = `
 <!DOCTYPE html>
 <html>
 <head>
     <title>Flag Quiz Game</title>
-    <style>
-        body {
-            font-family: Arial, sans-serif;
-            max-width: 900px;
-            margin: 20px auto;
-            padding: 20px;
-            background-color: #f0f8ff;
-        }
-        .container {
-            background-color: white;
-            padding: 30px;
-            border-radius: 15px;
-            box-shadow: 0 4px 15px rgba(0,0,0,0.1);
-        }
-        h1 {
-            color: #2c3e50;
-            text-align: center;
-            margin-bottom: 10px;
-        }
-        .subtitle {
-            text-align: center;
-            color: #7f8c8d;
-            margin-bottom: 30px;
-            font-style: italic;
-        }
-        .game-area {
-            text-align: center;
-            margin: 30px 0;
-        }
-        .flag-container {
-            display: inline-block;
-            border: 3px solid #34495e;
-            border-radius: 10px;
-            overflow: hidden;
-            margin: 20px 0;
-            box-shadow: 0 2px 10px rgba(0,0,0,0.2);
-        }
-        .flag-image {
-            display: block;
-            max-height: 100%;
-            max-width: 200%;
-        }
-        .question {
-            font-size: 24px;
-            color: #2c3e50;
-            margin: 20px 0;
-            font-weight: bold;
-        }
-        .country-name {
-            font-size: 20px;
-            color: #e74c3c;
-            margin: 15px 0;
-            font-weight: bold;
-        }
-        .current-turn {
-            font-size: 18px;
-            color: #3498db;
-            margin: 10px 0;
-            font-weight: bold;
-            font-style: italic;
-        }
-        .buttons {
-            margin: 30px 0;
-        }
-        .btn {
-            font-size: 18px;
-            padding: 15px 30px;
-            margin: 10px;
-            border: none;
-            border-radius: 8px;
-            cursor: pointer;
-            font-weight: bold;
-            transition: all 0.3s ease;
-        }
-        .btn-correct {
-            background-color: #27ae60;
-            color: white;
-        }
-        .btn-correct:hover {
-            background-color: #229954;
-        }
-        .btn-incorrect {
-            background-color: #e74c3c;
-            color: white;
-        }
-        .btn-incorrect:hover {
-            background-color: #c0392b;
-        }
-        .btn-new {
-            background-color: #3498db;
-            color: white;
-        }
-        .btn-new:hover {
-            background-color: #2980b9;
-        }
-        .result {
-            font-size: 20px;
-            font-weight: bold;
-            margin: 20px 0;
-            padding: 15px;
-            border-radius: 8px;
-        }
-        .result.correct {
-            background-color: #d5f4e6;
-            color: #27ae60;
-            border: 2px solid #27ae60;
-        }
-        .result.incorrect {
-            background-color: #fadbd8;
-            color: #e74c3c;
-            border: 2px solid #e74c3c;
-        }
-        .score {
-            background-color: #ebf3fd;
-            padding: 15px;
-            border-radius: 8px;
-            margin: 20px 0;
-        }
-        .score h3 {
-            margin: 0 0 15px 0;
-            color: #2c3e50;
-            text-align: center;
-        }
-        .player-score {
-            background-color: white;
-            border: 2px solid #d1d8e0;
-            border-radius: 8px;
-            padding: 12px;
-            margin-bottom: 10px;
-            transition: all 0.3s ease;
-        }
-        .player-score.current-player {
-            border-color: #3498db;
-            background-color: #e8f4f8;
-            box-shadow: 0 2px 8px rgba(52, 152, 219, 0.3);
-        }
-        .player-name {
-            font-size: 18px;
-            font-weight: bold;
-            color: #2c3e50;
-            margin-bottom: 10px;
-            text-align: center;
-        }
-        .current-player .player-name {
-            color: #3498db;
-        }
-        .stats {
-            display: flex;
-            justify-content: space-around;
-            flex-wrap: wrap;
-        }
-        .stat {
-            margin: 5px;
-        }
-        .stat-value {
-            font-size: 24px;
-            font-weight: bold;
-            color: #3498db;
-        }
-        .stat-label {
-            font-size: 14px;
-            color: #7f8c8d;
-        }
-        .flag-comparison {
-            display: flex;
-            justify-content: center;
-            gap: 20px;
-            margin: 20px 0;
-            flex-wrap: wrap;
-        }
-        .flag-box {
-            text-align: center;
-            padding: 10px;
-            border-radius: 8px;
-            background-color: #f8f9fa;
-            border: 2px solid #dee2e6;
-            min-width: 120px;
-        }
-        .flag-box h4 {
-            margin: 0 0 10px 0;
-            font-size: 14px;
-            color: #495057;
-        }
-        .flag-thumbnail {
-            display: block;
-            max-width: 100%;
-            height: auto;
-            border: 2px solid #333;
-            border-radius: 4px;
-            object-fit: cover;
-        }
-        .final-results {
-            background-color: #fff;
-            border: 3px solid #f39c12;
-            border-radius: 10px;
-            padding: 20px;
-            margin: 20px 0;
-        }
-        .final-results h2 {
-            color: #2c3e50;
-            margin-top: 0;
-            text-align: center;
-        }
-        .final-player {
-            font-size: 18px;
-            padding: 12px;
-            margin: 10px 0;
-            background-color: #ecf0f1;
-            border-radius: 5px;
-            text-align: center;
-        }
-    </style>
+    {{template "gameStyles"}}
 </head>
 <body>
     <div class="container">
         <h1>🏴 Flag Quiz Game</h1>
         <div class="subtitle">Can you spot the fake flags?</div>
         
-        <div class="score">
-            <h3>Scoreboard {{if .TotalRounds}}(Round {{.CurrentRound}}/{{.TotalRounds}}){{end}}</h3>
-            {{range $index, $player := .Players}}
-            <div class="player-score {{if eq $index $.CurrentPlayer}}current-player{{end}}">
-                <div class="player-name">
-                    {{if eq $index $.CurrentPlayer}}▶{{end}} {{$player.Name}}
-                </div>
-                <div class="stats">
-                    <div class="stat">
-                        <div class="stat-value">{{$player.Correct}}</div>
-                        <div class="stat-label">Correct</div>
-                    </div>
-                    <div class="stat">
-                        <div class="stat-value">{{$player.Incorrect}}</div>
-                        <div class="stat-label">Incorrect</div>
-                    </div>
-                    <div class="stat">
-                        <div class="stat-value">{{$player.Total}}</div>
-                        <div class="stat-label">Total</div>
-                    </div>
-                    <div class="stat">
-                        <div class="stat-value">{{$player.Percentage}}%</div>
-                        <div class="stat-label">Accuracy</div>
-                    </div>
-                </div>
-            </div>
-            {{end}}
-        </div>
+        {{template "scoreboard" .}}
+        {{template "spectateLink" .}}
 
         <div class="game-area">
             {{if .GameOver}}
-                <div class="question">🏆 Game Over! 🏆</div>
-                <div class="final-results">
-                    <h2>Final Results</h2>
-                    {{range .Players}}
-                    <div class="final-player">
-                        <strong>{{.Name}}</strong>: {{.Correct}} correct out of {{.Total}} ({{.Percentage}}%)
-                    </div>
-                    {{end}}
-                </div>
-                <button class="btn btn-new" onclick="location.href='/setup'">New Game</button>
-            {{else if .FlagData}}
+                {{template "gameOver" .}}
+            {{else if .FlagSrc}}
                 <div class="question">Is this the correct flag?</div>
                 <div class="country-name">{{.CountryName}}</div>
                 {{if .Players}}
-                <div class="current-turn">{{(index .Players .CurrentPlayer).Name}}'s turn</div>
+                <div class="current-turn">{{(index .Players .CurrentPlayer).TurnName}}'s turn</div>
                 {{end}}
+                {{template "countdown" .}}
                 <div class="flag-container">
-                    <img src="data:image/png;base64,{{.FlagData}}" alt="Flag" class="flag-image">
+                    <img src="{{.FlagSrc}}" alt="Flag" class="flag-image">
                 </div>
                 
                 {{if .ShowResult}}
                     <div class="result {{if .ResultCorrect}}correct{{else}}incorrect{{end}}">
                         {{if .ResultCorrect}}
                             ✓ Correct! {{.ResultMessage}}
+                            {{template "resultExtras" .}}
                         {{else}}
                             ✗ Wrong! {{.ResultMessage}}
-                            {{if and .OriginalFlag .ModifiedFlag (not .IsCorrect)}}
+                            {{if and .OriginalSrc .ModifiedSrc (not .IsCorrect)}}
                                 <div class="flag-comparison">
                                     <div class="flag-box">
                                         <h4>Correct Flag</h4>
-                                        <img src="data:image/png;base64,{{.OriginalFlag}}" alt="Correct Flag" class="flag-thumbnail">
+                                        <img src="{{.OriginalSrc}}" alt="Correct Flag" class="flag-thumbnail">
                                     </div>
                                 </div>
                             {{end}}
@@ -439,6 +847,1148 @@ const htmlTemplate = `
             {{end}}
         </div>
     </div>
+    <div id="live-updates" data-events="/events" data-version="{{.Version}}"></div>
+    {{template "liveUpdates"}}
+</body>
+</html>
+`
+
+// choiceTemplate contains the page for the "name this flag" mode
+const choiceTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Flag Quiz Game</title>
+    {{template "gameStyles"}}
+</head>
+<body>
+    <div class="container">
+        <h1>🏴 Flag Quiz Game</h1>
+        <div class="subtitle">Can you name the flag?</div>
+        
+        {{template "scoreboard" .}}
+        {{template "spectateLink" .}}
+
+        <div class="game-area">
+            {{if .GameOver}}
+                {{template "gameOver" .}}
+            {{else if .FlagSrc}}
+                <div class="question">Which country does this flag belong to?</div>
+                {{if .Players}}
+                <div class="current-turn">{{(index .Players .CurrentPlayer).TurnName}}'s turn</div>
+                {{end}}
+                {{template "countdown" .}}
+                <div class="flag-container">
+                    <img src="{{.FlagSrc}}" alt="Flag" class="flag-image">
+                </div>
+
+                <form class="choices" method="GET" action="/answer">
+                    {{range .Choices}}
+                        {{if $.ShowResult}}
+                        <button type="button" class="btn btn-choice {{if eq . $.CountryName}}answer{{else if eq . $.ChosenAnswer}}chosen-wrong{{end}}" disabled>{{.}}</button>
+                        {{else}}
+                        <button type="submit" class="btn btn-choice" name="choice" value="{{.}}">{{.}}</button>
+                        {{end}}
+                    {{end}}
+                </form>
+                
+                {{if .ShowResult}}
+                    <div class="result {{if .ResultCorrect}}correct{{else}}incorrect{{end}}">
+                        {{if .ResultCorrect}}✓ Correct!{{else}}✗ Wrong!{{end}} {{.ResultMessage}}
+                        {{template "resultExtras" .}}
+                    </div>
+                    <button class="btn btn-new" onclick="location.href='/new'">Next Flag</button>
+                {{end}}
+            {{else}}
+                <div class="question">Welcome to the Flag Quiz!</div>
+                <p>Test your knowledge of world flags. Each flag comes with four countries to choose from.</p>
+                <button class="btn btn-new" onclick="location.href='/new'">Start Game</button>
+            {{end}}
+        </div>
+    </div>
+    <div id="live-updates" data-events="/events" data-version="{{.Version}}"></div>
+    {{template "liveUpdates"}}
+</body>
+</html>
+`
+
+// pickTemplate contains the page for the "pick the real one" mode
+const pickTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Flag Quiz Game</title>
+    {{template "gameStyles"}}
+</head>
+<body>
+    <div class="container">
+        <h1>🏴 Flag Quiz Game</h1>
+        <div class="subtitle">Only one of these flags is real</div>
+        
+        {{template "scoreboard" .}}
+        {{template "spectateLink" .}}
+
+        <div class="game-area">
+            {{if .GameOver}}
+                {{template "gameOver" .}}
+            {{else if .FlagOptions}}
+                <div class="question">Which one is the real flag?</div>
+                <div class="country-name">{{.CountryName}}</div>
+                {{if .Players}}
+                <div class="current-turn">{{(index .Players .CurrentPlayer).TurnName}}'s turn</div>
+                {{end}}
+                {{template "countdown" .}}
+
+                <form class="pick-options" method="GET" action="/pick">
+                    {{range $index, $src := .FlagOptions}}
+                        {{if $.ShowResult}}
+                        <button type="button" class="pick-option {{if eq $index $.RealOption}}answer{{else if eq $index $.PickedOption}}chosen-wrong{{end}}" disabled>
+                            <img src="{{$src}}" alt="Flag option {{$index}}">
+                        </button>
+                        {{else}}
+                        <button type="submit" class="pick-option" name="option" value="{{$index}}">
+                            <img src="{{$src}}" alt="Flag option {{$index}}">
+                        </button>
+                        {{end}}
+                    {{end}}
+                </form>
+                
+                {{if .ShowResult}}
+                    <div class="result {{if .ResultCorrect}}correct{{else}}incorrect{{end}}">
+                        {{if .ResultCorrect}}✓ Correct!{{else}}✗ Wrong!{{end}} {{.ResultMessage}}
+                        {{template "resultExtras" .}}
+                    </div>
+                    <button class="btn btn-new" onclick="location.href='/new'">Next Flag</button>
+                {{end}}
+            {{else}}
+                <div class="question">Welcome to the Flag Quiz!</div>
+                <p>Test your knowledge of world flags. Each round shows the real flag next to tampered copies.</p>
+                <button class="btn btn-new" onclick="location.href='/new'">Start Game</button>
+            {{end}}
+        </div>
+    </div>
+    <div id="live-updates" data-events="/events" data-version="{{.Version}}"></div>
+    {{template "liveUpdates"}}
+</body>
+</html>
+`
+
+// spotTemplate contains the page for the spot-the-difference mode. The flag
+// is served at a fixed size, so the image input's click coordinates map
+// straight onto the tamper mask
+const spotTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Flag Quiz Game</title>
+    {{template "gameStyles"}}
+</head>
+<body>
+    <div class="container">
+        <h1>🏴 Flag Quiz Game</h1>
+        <div class="subtitle">Something on this flag is wrong - can you find it?</div>
+        
+        {{template "scoreboard" .}}
+        {{template "spectateLink" .}}
+
+        <div class="game-area">
+            {{if .GameOver}}
+                {{template "gameOver" .}}
+            {{else if .FlagSrc}}
+                <div class="question">Click on the part of the flag that was changed</div>
+                <div class="country-name">{{.CountryName}}</div>
+                {{if .Players}}
+                <div class="current-turn">{{(index .Players .CurrentPlayer).TurnName}}'s turn</div>
+                {{end}}
+                {{template "countdown" .}}
+
+                <div class="flag-container">
+                    {{if .ShowResult}}
+                    <div class="spot-area">
+                        <img src="{{.HighlightSrc}}" alt="Changed region" width="{{.FlagWidth}}" height="{{.FlagHeight}}" class="spot-image">
+                        {{if ge .SpotX 0}}
+                        <div class="spot-marker" style="left: {{.SpotX}}px; top: {{.SpotY}}px;"></div>
+                        {{end}}
+                    </div>
+                    {{else}}
+                    <form method="GET" action="/spot" class="spot-area">
+                        <input type="image" name="spot" src="{{.FlagSrc}}" alt="Flag" width="{{.FlagWidth}}" height="{{.FlagHeight}}" class="spot-image">
+                    </form>
+                    {{end}}
+                </div>
+                
+                {{if .ShowResult}}
+                    <div class="result {{if .ResultCorrect}}correct{{else}}incorrect{{end}}">
+                        {{if .ResultCorrect}}✓ Correct!{{else}}✗ Wrong!{{end}} {{.ResultMessage}}
+                        {{template "resultExtras" .}}
+                        <div class="flag-comparison">
+                            <div class="flag-box">
+                                <h4>Correct Flag</h4>
+                                <img src="{{.OriginalSrc}}" alt="Correct Flag" class="flag-thumbnail">
+                            </div>
+                            <div class="flag-box">
+                                <h4>Tampered Flag</h4>
+                                <img src="{{.FlagSrc}}" alt="Tampered Flag" class="flag-thumbnail">
+                            </div>
+                        </div>
+                        <div class="stat-label">{{.TamperDescription}}</div>
+                    </div>
+                    <button class="btn btn-new" onclick="location.href='/new'">Next Flag</button>
+                {{end}}
+            {{else}}
+                <div class="question">Welcome to the Flag Quiz!</div>
+                <p>Test your knowledge of world flags. Every flag has one color changed - click where you think it is.</p>
+                <button class="btn btn-new" onclick="location.href='/new'">Start Game</button>
+            {{end}}
+        </div>
+    </div>
+    <div id="live-updates" data-events="/events" data-version="{{.Version}}"></div>
+    {{template "liveUpdates"}}
+</body>
+</html>
+`
+
+// partyCreateTemplate contains the form for opening a party room
+const partyCreateTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Flag Quiz Party</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    {{template "gameStyles"}}
+</head>
+<body>
+    <div class="container">
+        <h1>🎉 Flag Quiz Party</h1>
+        <div class="subtitle">Show the flags on this screen and let everyone answer from their phone</div>
+
+        <form method="POST" action="/party" class="game-area">
+            <p>
+                <label for="mode">Game mode:</label>
+                <select id="mode" name="mode">
+                    <option value="truefalse">Real or fake</option>
+                    <option value="choice">Name the flag</option>
+                    <option value="pickreal">Pick the real one</option>
+                </select>
+            </p>
+            <p>
+                <label for="difficulty">Difficulty:</label>
+                <select id="difficulty" name="difficulty">
+                    <option value="easy">Easy</option>
+                    <option value="normal" selected>Normal</option>
+                    <option value="hard">Hard</option>
+                </select>
+            </p>
+            <p>
+                <label for="selection">Which flags come up:</label>
+                <select id="selection" name="selection">
+                    <option value="uniform">Any flag, every round</option>
+                    <option value="deck">Every flag once before any repeats</option>
+                    <option value="weakness">Focus on the flags the room gets wrong</option>
+                </select>
+            </p>
+            <p>
+                <label for="pickOptions">Flags per round (pick the real one):</label>
+                <select id="pickOptions" name="pickOptions">
+                    <option value="2">2</option>
+                    <option value="3" selected>3</option>
+                    <option value="4">4</option>
+                </select>
+            </p>
+            <p>
+                <label for="numRounds">Rounds:</label>
+                <input type="number" id="numRounds" name="numRounds" min="1" max="50" value="10">
+            </p>
+            <p>
+                <label for="timeLimit">Seconds per round:</label>
+                <input type="number" id="timeLimit" name="timeLimit" min="5" max="120" value="20">
+            </p>
+            <button type="submit" class="btn btn-new">Open Room</button>
+        </form>
+    </div>
+</body>
+</html>
+`
+
+// partyHostTemplate contains the shared screen of a party room
+const partyHostTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Flag Quiz Party - {{.Code}}</title>
+    {{template "gameStyles"}}
+</head>
+<body>
+    <div class="container">
+        <h1>🎉 Room {{.Code}}</h1>
+        <div class="subtitle">Join at <strong>{{.JoinURL}}</strong></div>
+        {{template "spectateLink" .State}}
+
+        {{with .State}}
+        <div class="score">
+            <h3>Players {{if .CurrentRound}}(Round {{.CurrentRound}}/{{.TotalRounds}}){{end}}</h3>
+            {{range $index, $player := .Players}}
+            <div class="player-score">
+                <div class="player-name">
+                    {{$player.Name}} {{if index $.Answered $index}}✓{{end}}{{if index $.Ready $index}} (ready){{end}}
+                </div>
+                <div class="stats">
+                    <div class="stat">
+                        <div class="stat-value">{{$player.Points}}</div>
+                        <div class="stat-label">Points</div>
+                    </div>
+                    <div class="stat">
+                        <div class="stat-value">{{$player.Correct}}/{{$player.Total}}</div>
+                        <div class="stat-label">Correct</div>
+                    </div>
+                    <div class="stat">
+                        <div class="stat-value">🔥 {{$player.Streak}}</div>
+                        <div class="stat-label">Streak</div>
+                    </div>
+                </div>
+            </div>
+            {{else}}
+            <p>Waiting for players to join...</p>
+            {{end}}
+        </div>
+        {{end}}
+
+        <div class="game-area">
+            {{if .State.GameOver}}
+                <div class="question">🏆 Game Over! 🏆</div>
+                {{template "finalResults" .State}}
+                <button class="btn btn-new" onclick="location.href='/party'">New Room</button>
+                {{with .State.ReplayID}}<button class="btn" onclick="location.href='/games/{{.}}/review'">Review Game</button>{{end}}
+            {{else if eq .State.CurrentRound 0}}
+                {{if .State.Players}}
+                <form method="POST" action="/party/{{.Code}}/next">
+                    <button type="submit" class="btn btn-new">Start Game</button>
+                </form>
+                {{end}}
+            {{else}}
+                {{with .State}}
+                {{if eq .Mode "choice"}}
+                <div class="question">Which country does this flag belong to?</div>
+                {{else if eq .Mode "pickreal"}}
+                <div class="question">Which one is the real flag?</div>
+                <div class="country-name">{{.CountryName}}</div>
+                {{else}}
+                <div class="question">Is this the correct flag?</div>
+                <div class="country-name">{{.CountryName}}</div>
+                {{end}}
+                {{template "countdown" .}}
+
+                {{if .FlagOptions}}
+                <div class="pick-options">
+                    {{range $index, $src := .FlagOptions}}
+                    <div class="pick-option {{if and $.State.ShowResult (eq $index $.State.RealOption)}}answer{{end}}">
+                        <div class="stat-label">Flag {{inc $index}}</div>
+                        <img src="{{$src}}" alt="Flag option {{inc $index}}">
+                    </div>
+                    {{end}}
+                </div>
+                {{else}}
+                <div class="flag-container">
+                    <img src="{{.FlagSrc}}" alt="Flag" class="flag-image">
+                </div>
+                {{end}}
+                {{if eq .Mode "choice"}}
+                <div class="choices">
+                    {{range .Choices}}
+                    <button type="button" class="btn btn-choice {{if and $.State.ShowResult (eq . $.State.CountryName)}}answer{{end}}" disabled>{{.}}</button>
+                    {{end}}
+                </div>
+                {{end}}
+                {{end}}
+
+                {{if .State.ShowResult}}
+                <div class="result">
+                    {{if eq .State.Mode "choice"}}
+                        It was <strong>{{.State.CountryName}}</strong>.
+                    {{else if eq .State.Mode "pickreal"}}
+                        The real flag was Flag {{inc .State.RealOption}}.
+                    {{else if .State.IsCorrect}}
+                        The flag was real.
+                    {{else}}
+                        The flag was fake. {{.State.TamperDescription}}
+                        {{if .State.OriginalSrc}}
+                        <div class="flag-comparison">
+                            <div class="flag-box">
+                                <h4>Correct Flag</h4>
+                                <img src="{{.State.OriginalSrc}}" alt="Correct Flag" class="flag-thumbnail">
+                            </div>
+                        </div>
+                        {{end}}
+                    {{end}}
+                    {{range .Results}}
+                    <div class="final-player">
+                        {{if .Correct}}✓{{else}}✗{{end}} <strong>{{.Name}}</strong>: {{.Answer}} (+{{.Points}})
+                        {{range .Achievements}}<span class="badge" title="{{.Description}}">{{.Icon}} {{.Name}}</span>{{end}}
+                    </div>
+                    {{end}}
+                </div>
+                <form method="POST" action="/party/{{.Code}}/next">
+                    <button type="submit" class="btn btn-new">{{if ge .State.CurrentRound .State.TotalRounds}}Show Results{{else}}Next Flag{{end}}</button>
+                </form>
+                {{end}}
+            {{end}}
+        </div>
+    </div>
+    <div id="live-updates" data-events="{{.EventsURL}}" data-version="{{.State.Version}}"></div>
+    {{template "liveUpdates"}}
+</body>
+</html>
+`
+
+// partyJoinTemplate contains the form players use to join a room from their phone
+const partyJoinTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Join Flag Quiz Party</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    {{template "gameStyles"}}
+</head>
+<body>
+    <div class="container">
+        <h1>🎉 Join the Party</h1>
+        <form method="POST" action="/join" class="game-area">
+            {{if .Error}}<div class="result incorrect">{{.Error}}</div>{{end}}
+            <p>
+                <label for="code">Room code:</label>
+                <input type="text" id="code" name="code" value="{{.Code}}" maxlength="4" autocapitalize="characters" required>
+            </p>
+            <p>
+                <label for="name">Your name:</label>
+                <input type="text" id="name" name="name" value="{{.Name}}" maxlength="30" required>
+            </p>
+            <button type="submit" class="btn btn-new">Join</button>
+        </form>
+    </div>
+</body>
+</html>
+`
+
+// partyPlayTemplate contains a player's controller
+const partyPlayTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Flag Quiz Party - {{.Player.Name}}</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    {{template "gameStyles"}}
+</head>
+<body>
+    <div class="container">
+        <h1>{{.Player.Name}}</h1>
+        <div class="subtitle">Room {{.Code}} - {{.Player.Points}} points</div>
+
+        <div class="game-area">
+            {{if .State.GameOver}}
+                <div class="question">🏆 Game Over! 🏆</div>
+                {{template "finalResults" .State}}
+            {{else if eq .State.CurrentRound 0}}
+                <div class="question">You're in! Waiting for the host to start...</div>
+            {{else if .State.ShowResult}}
+                {{with .MyResult}}
+                <div class="result {{if .Correct}}correct{{else}}incorrect{{end}}">
+                    {{if .Correct}}✓ Correct!{{else}}✗ Wrong!{{end}} +{{.Points}} points
+                    {{range .Achievements}}
+                    <div class="achievement-earned">{{.Icon}} Achievement unlocked: <strong>{{.Name}}</strong></div>
+                    {{end}}
+                </div>
+                {{end}}
+                <div class="question">Look at the big screen for the next flag</div>
+            {{else if .MyAnswered}}
+                <div class="question">🔒 Answer locked in</div>
+                {{template "countdown" .State}}
+            {{else}}
+                {{template "countdown" .State}}
+                <form method="POST" action="/play/{{.Code}}/answer">
+                    {{if eq .State.Mode "choice"}}
+                    <div class="choices">
+                        {{range .State.Choices}}
+                        <button type="submit" class="btn btn-choice" name="answer" value="{{.}}">{{.}}</button>
+                        {{end}}
+                    </div>
+                    {{else if eq .State.Mode "pickreal"}}
+                    <div class="choices">
+                        {{range $index, $src := .State.FlagOptions}}
+                        <button type="submit" class="btn btn-choice" name="answer" value="{{$index}}">Flag {{inc $index}}</button>
+                        {{end}}
+                    </div>
+                    {{else}}
+                    <div class="buttons">
+                        <button type="submit" class="btn btn-correct" name="answer" value="correct">Real</button>
+                        <button type="submit" class="btn btn-incorrect" name="answer" value="incorrect">Fake</button>
+                    </div>
+                    {{end}}
+                </form>
+            {{end}}
+        </div>
+    </div>
+    <div id="live-updates" data-events="{{.EventsURL}}" data-version="{{.State.Version}}"></div>
+    {{template "liveUpdates"}}
+</body>
+</html>
+`
+
+// spectateTemplate contains the read-only view for a projector or a stream.
+// It has no controls and reloads whenever the game's event stream reports a change
+const spectateTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Flag Quiz Game - Spectating</title>
+    {{template "gameStyles"}}
+    <style>
+        body {
+            max-width: 1200px;
+        }
+        .spectate-flag {
+            max-width: 100%;
+            max-height: 50vh;
+        }
+        .reveal {
+            animation: reveal 0.8s ease-out;
+        }
+        .reveal .answer {
+            animation: pulse 1.2s ease-in-out 0.8s 2;
+        }
+        @keyframes reveal {
+            from { opacity: 0; transform: scale(0.6) rotateX(80deg); }
+            to { opacity: 1; transform: none; }
+        }
+        @keyframes pulse {
+            50% { transform: scale(1.08); box-shadow: 0 0 25px #27ae60; }
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h1>📺 Flag Quiz Game</h1>
+        {{with .JoinURL}}<div class="subtitle">Join at <strong>{{.}}</strong></div>{{end}}
+
+        {{template "scoreboard" .State}}
+
+        <div class="game-area">
+            {{with .State}}
+            {{if .GameOver}}
+                <div class="question reveal">🏆 Game Over! 🏆</div>
+                {{template "finalResults" .}}
+            {{else if eq .CurrentRound 0}}
+                <div class="question">Waiting for the game to start...</div>
+            {{else if or .FlagSrc .FlagOptions}}
+                {{if eq .Mode "choice"}}
+                <div class="question">Which country does this flag belong to?</div>
+                {{else if eq .Mode "pickreal"}}
+                <div class="question">Which one is the real flag?</div>
+                <div class="country-name">{{.CountryName}}</div>
+                {{else if eq .Mode "spot"}}
+                <div class="question">What was changed on this flag?</div>
+                <div class="country-name">{{.CountryName}}</div>
+                {{else}}
+                <div class="question">Is this the correct flag?</div>
+                <div class="country-name">{{.CountryName}}</div>
+                {{end}}
+
+                {{if $.Answered}}
+                <div class="current-turn">Everyone answers - {{range $index, $answered := $.Answered}}{{if $answered}}✓ {{(index $.State.Players $index).Name}} {{end}}{{end}}</div>
+                {{else if .Players}}
+                <div class="current-turn">{{(index .Players .CurrentPlayer).TurnName}}'s turn</div>
+                {{end}}
+                {{template "countdown" .}}
+
+                <div class="{{if .ShowResult}}reveal{{end}}">
+                {{if .FlagOptions}}
+                <div class="pick-options">
+                    {{range $index, $src := .FlagOptions}}
+                    <div class="pick-option {{if and $.State.ShowResult (eq $index $.State.RealOption)}}answer{{end}}">
+                        <img src="{{$src}}" alt="Flag option {{inc $index}}">
+                    </div>
+                    {{end}}
+                </div>
+                {{else if and .ShowResult .HighlightSrc}}
+                <div class="flag-container answer">
+                    <img src="{{.HighlightSrc}}" alt="Changed region" class="spectate-flag">
+                </div>
+                {{else}}
+                <div class="flag-container">
+                    <img src="{{.FlagSrc}}" alt="Flag" class="spectate-flag">
+                </div>
+                {{end}}
+
+                {{if eq .Mode "choice"}}
+                <div class="choices">
+                    {{range .Choices}}
+                    <div class="btn btn-choice {{if and $.State.ShowResult (eq . $.State.CountryName)}}answer{{end}}">{{.}}</div>
+                    {{end}}
+                </div>
+                {{end}}
+
+                {{if .ShowResult}}
+                <div class="result {{if not $.Results}}{{if .ResultCorrect}}correct{{else}}incorrect{{end}}{{end}}">
+                    {{if $.Results}}
+                        {{range $.Results}}
+                        <div class="final-player">{{if .Correct}}✓{{else}}✗{{end}} <strong>{{.Name}}</strong>: {{.Answer}} (+{{.Points}})</div>
+                        {{end}}
+                    {{else}}
+                        {{if .ResultCorrect}}✓ Correct!{{else}}✗ Wrong!{{end}} {{.ResultMessage}}
+                        {{template "resultExtras" .}}
+                    {{end}}
+                    {{if and (eq .Mode "truefalse") (not .IsCorrect) .OriginalSrc}}
+                    <div class="flag-comparison">
+                        <div class="flag-box answer">
+                            <h4>Correct Flag</h4>
+                            <img src="{{.OriginalSrc}}" alt="Correct Flag" class="flag-thumbnail">
+                        </div>
+                    </div>
+                    {{end}}
+                    {{with .TamperDescription}}<div class="stat-label">{{.}}</div>{{end}}
+                </div>
+                {{end}}
+                </div>
+            {{end}}
+            {{end}}
+        </div>
+    </div>
+    <div id="live-updates" data-events="{{.EventsURL}}" data-version="{{.State.Version}}"></div>
+    {{template "liveUpdates"}}
+</body>
+</html>
+`
+
+// dailyTemplate contains the daily challenge's leaderboard and entry form
+const dailyTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Flag Quiz Daily - {{.Date}}</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    {{template "gameStyles"}}
+</head>
+<body>
+    <div class="container">
+        <h1>📅 Daily Challenge</h1>
+        <div class="subtitle">{{.Date}} - the same ten flags for everyone, one attempt each</div>
+
+        <div class="game-area">
+            {{with .Mine}}
+            <div class="result correct">
+                <div class="question">You scored {{.Points}} points ({{.Correct}}/{{len .Grid}})</div>
+                <pre id="share-text">{{.ShareText}}</pre>
+                <button class="btn btn-new" onclick="navigator.clipboard.writeText(document.getElementById('share-text').textContent); this.textContent = 'Copied!'">Copy Results</button>
+            </div>
+            {{else}}
+            <form method="POST" action="/daily">
+                {{if .Error}}<div class="result incorrect">{{.Error}}</div>{{end}}
+                <p>
+                    <label for="name">Your name:</label>
+                    <input type="text" id="name" name="name" value="{{.Name}}" maxlength="30" required>
+                </p>
+                <button type="submit" class="btn btn-new">Play Today's Flags</button>
+            </form>
+            {{end}}
+        </div>
+
+        <div class="final-results">
+            <h2>Today's Leaderboard</h2>
+            {{range $place, $entry := .Entries}}
+            <div class="final-player">
+                #{{inc $place}} <strong>{{$entry.Player}}</strong>: {{$entry.Points}} points ({{$entry.Correct}}/{{len $entry.Grid}})
+                <div>{{range $entry.Grid}}{{if .}}🟩{{else}}🟥{{end}}{{end}}</div>
+            </div>
+            {{else}}
+            <p>Nobody has finished today's challenge yet.</p>
+            {{end}}
+        </div>
+    </div>
+</body>
+</html>
+`
+
+// leaderboardTemplate contains the leaderboard of every recorded game
+const leaderboardTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Flag Quiz Leaderboard</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    {{template "gameStyles"}}
+</head>
+<body>
+    <div class="container">
+        <h1>🏆 Leaderboard</h1>
+        <div class="subtitle">{{if eq .Period "week"}}The last 7 days{{else}}All-time{{end}}, ranked by best game</div>
+
+        <form method="GET" action="/leaderboard" class="game-area">
+            <label for="period">Period:</label>
+            <select id="period" name="period">
+                <option value="all">All-time</option>
+                <option value="week"{{if eq .Period "week"}} selected{{end}}>Last 7 days</option>
+            </select>
+            <label for="mode">Mode:</label>
+            <select id="mode" name="mode">
+                <option value="">Every mode</option>
+                {{range .Modes}}<option value="{{.}}"{{if eq . $.Mode}} selected{{end}}>{{.}}</option>{{end}}
+            </select>
+            <label for="difficulty">Difficulty:</label>
+            <select id="difficulty" name="difficulty">
+                <option value="">Every difficulty</option>
+                {{range .Difficulties}}<option value="{{.}}"{{if eq . $.Difficulty}} selected{{end}}>{{.}}</option>{{end}}
+            </select>
+            <button type="submit" class="btn btn-new">Show</button>
+        </form>
+
+        <div class="final-results">
+            {{range $place, $entry := .Entries}}
+            <div class="final-player">
+                #{{inc $place}} <strong>{{$entry.Player}}</strong>: best game {{$entry.BestScore}} points
+                <div class="stat-label">{{$entry.Games}} games, {{$entry.Wins}} wins, {{$entry.TotalPoints}} points in total, {{$entry.Accuracy}}% correct</div>
+            </div>
+            {{else}}
+            <p>No finished games yet.</p>
+            {{end}}
+        </div>
+        <button class="btn btn-new" onclick="location.href='/setup'">New Game</button>
+    </div>
+</body>
+</html>
+`
+
+// profilesTemplate contains the list of saved players
+const profilesTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Flag Quiz Players</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    {{template "gameStyles"}}
+</head>
+<body>
+    <div class="container">
+        <h1>👤 Players</h1>
+        <div class="subtitle">Everyone who has played here, with their lifetime record</div>
+
+        <div class="final-results">
+            {{range .Profiles}}
+            <div class="final-player">
+                <span class="avatar" style="background-color: {{.Color}}"></span>
+                <a href="/profiles/{{.ID}}"><strong>{{.Name}}</strong></a>:
+                {{.Stats.Games}} games, {{.Stats.Wins}} wins, {{.Stats.Accuracy}}% correct, best game {{.Stats.BestScore}} points
+            </div>
+            {{else}}
+            <p>No players yet. Profiles are created the first time someone plays.</p>
+            {{end}}
+        </div>
+
+        <form method="POST" action="/profiles" class="game-area">
+            {{if .Error}}<div class="result incorrect">{{.Error}}</div>{{end}}
+            <label for="name">New player:</label>
+            <input type="text" id="name" name="name" maxlength="30" required>
+            <button type="submit" class="btn btn-new">Add Player</button>
+        </form>
+        <button class="btn btn-new" onclick="location.href='/setup'">New Game</button>
+    </div>
+</body>
+</html>
+`
+
+// profileTemplate contains a player's lifetime stats and per-country accuracy
+const profileTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Flag Quiz - {{.Profile.Name}}</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    {{template "gameStyles"}}
+</head>
+<body>
+    <div class="container">
+        {{with .Profile}}
+        <h1><span class="avatar" style="background-color: {{.Color}}"></span> {{.Name}}</h1>
+        <div class="subtitle">Playing since {{.Created.Format "2 January 2006"}}</div>
+
+        <div class="score">
+            <div class="stats">
+                <div class="stat"><div class="stat-value">{{.Stats.Games}}</div><div class="stat-label">Games</div></div>
+                <div class="stat"><div class="stat-value">{{.Stats.Wins}}</div><div class="stat-label">Wins</div></div>
+                <div class="stat"><div class="stat-value">{{.Stats.Accuracy}}%</div><div class="stat-label">Correct</div></div>
+                <div class="stat"><div class="stat-value">{{.Stats.Points}}</div><div class="stat-label">Points</div></div>
+                <div class="stat"><div class="stat-value">{{.Stats.BestScore}}</div><div class="stat-label">Best Game</div></div>
+                <div class="stat"><div class="stat-value">{{.Stats.BestStreak}}</div><div class="stat-label">Best Streak</div></div>
+            </div>
+        </div>
+        {{end}}
+
+        <form method="POST" action="/profiles/{{.Profile.ID}}" class="game-area">
+            {{if .Error}}<div class="result incorrect">{{.Error}}</div>{{end}}
+            <label for="color">Avatar color:</label>
+            <input type="color" id="color" name="color" value="{{.Profile.Color}}" list="avatar-colors">
+            <datalist id="avatar-colors">{{range .Colors}}<option value="{{.}}">{{end}}</datalist>
+            <button type="submit" class="btn btn-new">Save</button>
+        </form>
+
+        <div class="final-results">
+            <h2>Accuracy by Country</h2>
+            {{range .Profile.CountryAccuracies}}
+            <div class="final-player">
+                <strong>{{.Country}}</strong>: {{.Percentage}}% ({{.Correct}}/{{.Attempts}})
+            </div>
+            {{else}}
+            <p>No flags answered yet.</p>
+            {{end}}
+        </div>
+        <button class="btn btn-new" onclick="location.href='/profiles'">All Players</button>
+    </div>
+</body>
+</html>
+`
+
+// statsTemplate contains the accuracy breakdowns of every recorded guess
+const statsTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Flag Quiz Stats</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    {{template "gameStyles"}}
+</head>
+<body>
+    <div class="container">
+        <h1>📊 Flag Stats</h1>
+        <div class="subtitle">{{with .PlayerName}}{{.}}'s{{else}}The whole team's{{end}} answers: {{.Stats.Total.Percentage}}% of {{.Stats.Total.Attempts}} correct</div>
+
+        <form method="GET" action="/stats" class="game-area">
+            <label for="player">Player:</label>
+            <select id="player" name="player">
+                <option value="">Everyone</option>
+                {{range .Players}}<option value="{{.Key}}"{{if eq .Key $.Player}} selected{{end}}>{{.Name}}</option>{{end}}
+            </select>
+            <button type="submit" class="btn btn-new">Show</button>
+            <a href="/stats/export?format=csv&player={{.Player}}">Download CSV</a> -
+            <a href="/stats/export?format=json&player={{.Player}}">Download JSON</a>
+        </form>
+
+        <div class="final-results">
+            <h2>Hardest Flags</h2>
+            {{range $place, $row := .Stats.Hardest}}
+            <div class="final-player">#{{inc $place}} <strong>{{$row.Label}}</strong>: {{$row.Percentage}}% ({{$row.Correct}}/{{$row.Attempts}})</div>
+            {{else}}
+            <p>No flag has been missed more than once yet.</p>
+            {{end}}
+        </div>
+
+        <div class="final-results">
+            <h2>By Tamper Mode</h2>
+            {{range .Stats.ByTamper}}
+            <div class="final-player"><strong>{{.Label}}</strong>: {{.Percentage}}% ({{.Correct}}/{{.Attempts}}), average ΔE {{.AverageDeltaE}}, {{.AverageSeconds}}s per answer</div>
+            {{end}}
+        </div>
+
+        <div class="final-results">
+            <h2>By Region</h2>
+            {{range .Stats.ByRegion}}
+            <div class="final-player"><strong>{{.Label}}</strong>: {{.Percentage}}% ({{.Correct}}/{{.Attempts}})</div>
+            {{end}}
+        </div>
+
+        <div class="final-results">
+            <h2>By Country</h2>
+            {{range .Stats.ByCountry}}
+            <div class="final-player"><strong>{{.Label}}</strong>: {{.Percentage}}% ({{.Correct}}/{{.Attempts}}), {{.AverageSeconds}}s per answer</div>
+            {{else}}
+            <p>No guesses recorded yet.</p>
+            {{end}}
+        </div>
+        <button class="btn btn-new" onclick="location.href='/setup'">New Game</button>
+    </div>
+</body>
+</html>
+`
+
+// reviewTemplate steps through the rounds of a finished game
+const reviewTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Flag Quiz Review</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    {{template "gameStyles"}}
+</head>
+<body>
+    <div class="container">
+        <h1>🔎 Game Review</h1>
+        <div class="subtitle">{{.Game.Settings.Mode}} game finished {{.Game.Finished.Format "2 Jan 2006 15:04"}}{{if .Rounds}} - flag {{.Step}} of {{len .Rounds}}{{end}}</div>
+
+        <div class="game-area">
+            {{if .Rounds}}
+                {{template "replayRound" .Round}}
+                {{if .Prev}}<button class="btn" onclick="location.href='/games/{{.ID}}/review?step={{.Prev}}'">Previous</button>{{end}}
+                {{if .Next}}<button class="btn btn-new" onclick="location.href='/games/{{.ID}}/review?step={{.Next}}'">Next</button>{{end}}
+            {{else}}
+                <p>No rounds were played in this game.</p>
+            {{end}}
+        </div>
+
+        <div class="final-results">
+            <h2>Final Results</h2>
+            {{range $place, $player := .Game.Players}}
+            <div class="final-player">#{{inc $place}} <strong>{{$player.Name}}</strong>: {{$player.Correct}} correct, {{$player.Points}} points</div>
+            {{end}}
+        </div>
+        <a href="/games/{{.ID}}/export?format=html">Download HTML</a> -
+        <a href="/games/{{.ID}}/export?format=json">Download JSON</a>
+        <button class="btn btn-new" onclick="location.href='/setup'">New Game</button>
+    </div>
+</body>
+</html>
+`
+
+// replayExportTemplate is a whole game on one page that needs no server
+const replayExportTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <title>Flag Quiz Game {{.Game.Finished.Format "2 Jan 2006 15:04"}}</title>
+    {{template "gameStyles"}}
+</head>
+<body>
+    <div class="container">
+        <h1>🏴 Flag Quiz Game</h1>
+        <div class="subtitle">{{.Game.Settings.Mode}} game finished {{.Game.Finished.Format "2 Jan 2006 15:04"}}</div>
+        <div class="final-results">
+            <h2>Final Results</h2>
+            {{range $place, $player := .Game.Players}}
+            <div class="final-player">#{{inc $place}} <strong>{{$player.Name}}</strong>: {{$player.Correct}} correct, {{$player.Points}} points</div>
+            {{end}}
+        </div>
+        {{range .Rounds}}
+        <div class="game-area">
+            {{template "replayRound" .}}
+        </div>
+        {{end}}
+    </div>
+</body>
+</html>
+`
+
+// dailyPlayTemplate contains the page for a flag of the daily challenge
+const dailyPlayTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Flag Quiz Daily</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    {{template "gameStyles"}}
+</head>
+<body>
+    <div class="container">
+        <h1>📅 Daily Challenge</h1>
+        <div class="subtitle">Flag {{.CurrentRound}} of {{.TotalRounds}}</div>
+
+        {{template "scoreboard" .}}
+
+        <div class="game-area">
+            <div class="question">Is this the correct flag?</div>
+            <div class="country-name">{{.CountryName}}</div>
+            <div class="flag-container">
+                <img src="{{.FlagSrc}}" alt="Flag" class="flag-image">
+            </div>
+
+            {{if .ShowResult}}
+                <div class="result {{if .ResultCorrect}}correct{{else}}incorrect{{end}}">
+                    {{if .ResultCorrect}}✓ Correct!{{else}}✗ Wrong!{{end}} {{.ResultMessage}}
+                    {{template "resultExtras" .}}
+                    {{if and .OriginalSrc (not .IsCorrect)}}
+                    <div class="flag-comparison">
+                        <div class="flag-box">
+                            <h4>Correct Flag</h4>
+                            <img src="{{.OriginalSrc}}" alt="Correct Flag" class="flag-thumbnail">
+                        </div>
+                    </div>
+                    {{end}}
+                </div>
+                <form method="POST" action="/daily/next">
+                    <button type="submit" class="btn btn-new">{{if ge .CurrentRound .TotalRounds}}See Results{{else}}Next Flag{{end}}</button>
+                </form>
+            {{else}}
+                <form method="POST" action="/daily/guess" class="buttons">
+                    <button type="submit" class="btn btn-correct" name="answer" value="correct">Correct Flag</button>
+                    <button type="submit" class="btn btn-incorrect" name="answer" value="incorrect">Fake Flag</button>
+                </form>
+            {{end}}
+        </div>
+    </div>
+</body>
+</html>
+`
+
+// practiceStartTemplate asks who is studying
+const practiceStartTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Flag Practice</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    {{template "gameStyles"}}
+</head>
+<body>
+    <div class="container">
+        <h1>📚 Flag Practice</h1>
+        <div class="subtitle">Learn the flags at your own pace - nothing here is scored</div>
+        <form method="POST" action="/practice" class="game-area">
+            {{if .Error}}<div class="result incorrect">{{.Error}}</div>{{end}}
+            <p>
+                <label for="name">Your name:</label>
+                <input type="text" id="name" name="name" value="{{.Player}}" maxlength="30" required>
+            </p>
+            <p class="stat-label">Your progress is saved under this name, so use the same one next time.</p>
+            <button type="submit" class="btn btn-new">Start Studying</button>
+        </form>
+    </div>
+</body>
+</html>
+`
+
+// practiceTemplate contains a flashcard: the facts of a new flag, or a quiz
+// on one the player has studied before
+const practiceTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Flag Practice</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    {{template "gameStyles"}}
+    <style>
+        .facts {
+            text-align: left;
+            display: inline-block;
+            margin: 10px 0;
+        }
+        .palette-row {
+            display: flex;
+            align-items: center;
+            margin: 4px 0;
+        }
+        .swatch {
+            width: 24px;
+            height: 24px;
+            border: 1px solid #34495e;
+            border-radius: 4px;
+            margin-right: 8px;
+        }
+        .palette-bar {
+            height: 10px;
+            background-color: #3498db;
+            border-radius: 5px;
+            margin-left: 8px;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h1>📚 Flag Practice</h1>
+        <div class="subtitle">{{.Player}} - {{.Seen}} flags studied, {{.Mastered}} mastered - <a href="/practice?switch=1">not you?</a></div>
+
+        <div class="game-area">
+            {{with .Card}}
+            {{if eq .Phase "quiz"}}
+                <div class="question">Which country does this flag belong to?</div>
+            {{else}}
+                <div class="question">{{if eq .Phase "study"}}New flag: {{end}}{{.Country.Name}}</div>
+            {{end}}
+            <div class="flag-container">
+                <img src="{{.FlagSrc}}" alt="Flag" class="flag-image">
+            </div>
+
+            {{if eq .Phase "quiz"}}
+                <form class="choices" method="POST" action="/practice/answer">
+                    {{range .Choices}}
+                    <button type="submit" class="btn btn-choice" name="choice" value="{{.}}">{{.}}</button>
+                    {{end}}
+                </form>
+            {{else}}
+                {{if eq .Phase "answered"}}
+                <div class="result {{if .Correct}}correct{{else}}incorrect{{end}}">
+                    {{if .Correct}}✓ Correct! You'll see this flag again later.{{else}}✗ That was {{.Country.Name}}, not {{.Chosen}}. It will come back soon.{{end}}
+                </div>
+                {{end}}
+                <div class="facts">
+                    <div><strong>Country:</strong> {{.Country.Name}}</div>
+                    {{with .Country.Capital}}<div><strong>Capital:</strong> {{.}}</div>{{end}}
+                    {{with .Country.Region}}<div><strong>Region:</strong> {{.}}{{with $.Card.Country.SubRegion}} ({{.}}){{end}}</div>{{end}}
+                    <div><strong>Colors:</strong></div>
+                    {{range .Palette}}
+                    <div class="palette-row">
+                        <span class="swatch" style="background-color: {{.Hex}};"></span>
+                        {{printf "%.0f" .Percent}}%
+                        <span class="palette-bar" style="width: {{printf "%.0f" .Percent}}px;"></span>
+                    </div>
+                    {{end}}
+                </div>
+                <form method="POST" action="/practice/next">
+                    <button type="submit" class="btn btn-new">{{if eq .Phase "study"}}Got It{{else}}Next Flag{{end}}</button>
+                </form>
+            {{end}}
+            {{end}}
+        </div>
+    </div>
+</body>
+</html>
+`
+
+// debugFlagsTemplate is the tamper gallery: a flag next to every way it can
+// be changed
+const debugFlagsTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Flag Quiz Tamper Gallery</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    {{template "gameStyles"}}
+</head>
+<body>
+    <div class="container">
+        <h1>🐛 Tamper Gallery</h1>
+        <div class="subtitle">Every tamper mode at every difficulty, all from the same seed</div>
+
+        <form method="GET" action="/debug/flags" class="game-area">
+            <label for="country">Country:</label>
+            <select id="country" name="country">
+                {{range .Countries}}<option value="{{.Name}}"{{if eq .Name $.Country}} selected{{end}}>{{.Name}}</option>{{end}}
+            </select>
+            <label for="seed">Seed:</label>
+            <input type="number" id="seed" name="seed" value="{{.Seed}}">
+            <button type="submit" class="btn btn-new">Show</button>
+            {{if .Original}}<a href="/debug/flags?country={{.Country}}&seed={{.NextSeed}}">Next seed</a>{{end}}
+        </form>
+
+        {{with .Error}}<div class="result incorrect">{{.}}</div>{{end}}
+
+        {{if .Original}}
+        <div class="flag-comparison">
+            <div class="flag-box">
+                <h4>Original</h4>
+                <img src="{{pngData .Original}}" alt="Original Flag" class="flag-thumbnail">
+            </div>
+        </div>
+        {{range .Rows}}
+        <h2>{{.Difficulty}}</h2>
+        <div class="flag-comparison">
+            {{range .Variants}}
+            <div class="flag-box">
+                <h4>{{.Mode}}{{if not .Dealt}} <span class="stat-label">(never dealt at this difficulty)</span>{{end}}</h4>
+                <img src="{{pngData .PNG}}" alt="{{.Mode}} variant" class="flag-thumbnail">
+                <div class="stat-label">{{.Description}}</div>
+                <div class="stat-label">ΔE {{printf "%.1f" .DeltaE}}, {{printf "%.1f" .Affected}}% of pixels changed</div>
+            </div>
+            {{end}}
+        </div>
+        {{end}}
+        {{end}}
+    </div>
 </body>
 </html>
 `
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"FlagsGUI/client"
+)
+
+const (
+	defaultTerminalWidth = 64
+	// inProcessURL is the base URL of the API when the game runs inside the
+	// terminal client rather than on a server
+	inProcessURL = "http://flagsgui.local"
+)
+
+// errQuit ends a terminal game early
+var errQuit = errors.New("quit")
+
+// handlerTransport answers HTTP requests by calling a handler directly, so
+// the terminal client can play the in-process engine through the same API
+// it would use against a server
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	w := &bufferedResponse{header: make(http.Header)}
+	t.handler.ServeHTTP(w, req)
+	w.WriteHeader(http.StatusOK)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", w.status, http.StatusText(w.status)),
+		StatusCode:    w.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        w.header,
+		Body:          io.NopCloser(&w.body),
+		ContentLength: int64(w.body.Len()),
+		Request:       req,
+	}, nil
+}
+
+// newInProcessClient returns an API client backed by deps instead of a server
+func newInProcessClient(deps *Dependencies) *client.Client {
+	mux := http.NewServeMux()
+	registerAPIRoutes(mux, deps)
+	mux.HandleFunc("GET /flag/{roundID}/{file}", flagImageHandler(deps))
+
+	api := client.New(inProcessURL)
+	api.HTTPClient = &http.Client{Transport: handlerTransport{handler: mux}}
+	return api
+}
+
+// terminalSize is how many character columns and rows a flag of w×h pixels
+// takes when drawn cols wide. Every character shows two pixels, one above
+// the other
+func terminalSize(w, h, cols int) (int, int) {
+	if w <= 0 || h <= 0 {
+		return 0, 0
+	}
+	if cols > w {
+		cols = w
+	}
+	pixelRows := (h*cols + w/2) / w
+	return cols, (pixelRows + 1) / 2
+}
+
+// renderANSI draws img cols characters wide with upper half blocks, the
+// foreground colouring the top pixel and the background the bottom one
+func renderANSI(img image.Image, cols int) string {
+	bounds := img.Bounds()
+	cols, rows := terminalSize(bounds.Dx(), bounds.Dy(), cols)
+	var out strings.Builder
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			top := averageCell(img, col, 2*row, cols, 2*rows)
+			bottom := averageCell(img, col, 2*row+1, cols, 2*rows)
+			fmt.Fprintf(&out, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm▀", top[0], top[1], top[2], bottom[0], bottom[1], bottom[2])
+		}
+		out.WriteString("\x1b[0m\n")
+	}
+	return out.String()
+}
+
+// averageCell is the mean colour of the pixels under cell (x, y) of a
+// cols×rows grid laid over img
+func averageCell(img image.Image, x, y, cols, rows int) [3]uint8 {
+	bounds := img.Bounds()
+	x0 := bounds.Min.X + x*bounds.Dx()/cols
+	x1 := max(bounds.Min.X+(x+1)*bounds.Dx()/cols, x0+1)
+	y0 := bounds.Min.Y + y*bounds.Dy()/rows
+	y1 := max(bounds.Min.Y+(y+1)*bounds.Dy()/rows, y0+1)
+	if y0 >= bounds.Max.Y {
+		return [3]uint8{}
+	}
+
+	var r, g, b, n uint64
+	for py := y0; py < y1 && py < bounds.Max.Y; py++ {
+		for px := x0; px < x1 && px < bounds.Max.X; px++ {
+			pr, pg, pb, _ := img.At(px, py).RGBA()
+			r, g, b, n = r+uint64(pr), g+uint64(pg), b+uint64(pb), n+1
+		}
+	}
+	return [3]uint8{uint8(r / n >> 8), uint8(g / n >> 8), uint8(b / n >> 8)}
+}
+
+// parseTerminalAnswer turns what a player typed into an API answer
+func parseTerminalAnswer(round *client.Round, input string, cols int) (string, error) {
+	input = strings.TrimSpace(input)
+	if strings.EqualFold(input, "q") || strings.EqualFold(input, "quit") {
+		return "", errQuit
+	}
+
+	switch round.Mode {
+	case client.ModeChoice:
+		if n, err := strconv.Atoi(input); err == nil && n >= 1 && n <= len(round.Choices) {
+			return round.Choices[n-1], nil
+		}
+		for _, choice := range round.Choices {
+			if strings.EqualFold(choice, input) {
+				return choice, nil
+			}
+		}
+		return "", fmt.Errorf("type a number from 1 to %d", len(round.Choices))
+	case client.ModePickReal:
+		if n, err := strconv.Atoi(input); err == nil && n >= 1 && n <= len(round.Options) {
+			return strconv.Itoa(n - 1), nil
+		}
+		return "", fmt.Errorf("type a number from 1 to %d", len(round.Options))
+	case client.ModeSpot:
+		cols, rows := terminalSize(round.FlagWidth, round.FlagHeight, cols)
+		colText, rowText, _ := strings.Cut(input, ",")
+		col, colErr := strconv.Atoi(strings.TrimSpace(colText))
+		row, rowErr := strconv.Atoi(strings.TrimSpace(rowText))
+		if colErr != nil || rowErr != nil || col < 1 || col > cols || row < 1 || row > rows {
+			return "", fmt.Errorf("type a column from 1 to %d and a row from 1 to %d, like 3,2", cols, rows)
+		}
+		x := (2*col - 1) * round.FlagWidth / (2 * cols)
+		y := (2*row - 1) * round.FlagHeight / (2 * rows)
+		return fmt.Sprintf("%d,%d", x, y), nil
+	default:
+		switch strings.ToLower(input) {
+		case "r", "real", "y", "yes":
+			return client.AnswerReal, nil
+		case "f", "fake", "n", "no":
+			return client.AnswerFake, nil
+		}
+		return "", errors.New("type r for real or f for fake")
+	}
+}
+
+// terminalGame plays one game in a terminal, reading answers line by line
+type terminalGame struct {
+	api   *client.Client
+	in    *bufio.Scanner
+	out   io.Writer
+	width int
+}
+
+func (t *terminalGame) Play(ctx context.Context, req client.CreateGameRequest) error {
+	game, err := t.api.CreateGame(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for !game.Finished() {
+		round, err := t.api.Round(ctx, game.ID)
+		var apiErr *client.Error
+		if errors.As(err, &apiErr) && apiErr.Code == "game_over" {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := t.showRound(ctx, round); err != nil {
+			return err
+		}
+
+		result, err := t.answer(ctx, game.ID, round)
+		if errors.Is(err, errQuit) {
+			fmt.Fprintln(t.out, "Game abandoned.")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := t.showResult(ctx, result); err != nil {
+			return err
+		}
+		if game, err = t.api.Game(ctx, game.ID); err != nil {
+			return err
+		}
+	}
+
+	if game, err = t.api.Game(ctx, game.ID); err != nil {
+		return err
+	}
+	fmt.Fprintln(t.out, "\nFinal scores")
+	for i, score := range game.Scores {
+		fmt.Fprintf(t.out, "%d. %-16s %5d points  %d/%d correct, best streak %d\n",
+			i+1, score.Name, score.Points, score.Correct, score.Correct+score.Incorrect, score.BestStreak)
+	}
+	return nil
+}
+
+func (t *terminalGame) showRound(ctx context.Context, round *client.Round) error {
+	fmt.Fprintf(t.out, "\nRound %d of %d, %s's turn\n", round.Round, round.TotalRounds, round.Player)
+	if round.Country != "" {
+		fmt.Fprintf(t.out, "Country: %s\n", round.Country)
+	}
+
+	if round.Mode == client.ModePickReal {
+		for i, option := range round.Options {
+			fmt.Fprintf(t.out, "%d)\n", i+1)
+			if err := t.showFlag(ctx, option, t.width/2); err != nil {
+				return err
+			}
+		}
+	} else if err := t.showFlag(ctx, round.FlagURL, t.width); err != nil {
+		return err
+	}
+
+	if round.Deadline != nil {
+		fmt.Fprintf(t.out, "Answer within %d seconds\n", int(time.Until(*round.Deadline).Round(time.Second).Seconds()))
+	}
+	switch round.Mode {
+	case client.ModeChoice:
+		for i, choice := range round.Choices {
+			fmt.Fprintf(t.out, "  %d) %s\n", i+1, choice)
+		}
+		fmt.Fprint(t.out, "Which country is it? ")
+	case client.ModePickReal:
+		fmt.Fprint(t.out, "Which flag is the real one? ")
+	case client.ModeSpot:
+		cols, rows := terminalSize(round.FlagWidth, round.FlagHeight, t.width)
+		fmt.Fprintf(t.out, "Where was the flag changed? Column 1-%d, row 1-%d: ", cols, rows)
+	default:
+		fmt.Fprint(t.out, "Real or fake? [r/f] ")
+	}
+	return nil
+}
+
+func (t *terminalGame) showFlag(ctx context.Context, path string, width int) error {
+	img, err := t.api.Image(ctx, path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(t.out, renderANSI(img, width))
+	return nil
+}
+
+// answer reads lines until one is an answer the round accepts
+func (t *terminalGame) answer(ctx context.Context, id string, round *client.Round) (*client.AnswerResult, error) {
+	for {
+		if !t.in.Scan() {
+			return nil, errQuit
+		}
+		answer, err := parseTerminalAnswer(round, t.in.Text(), t.width)
+		if errors.Is(err, errQuit) {
+			return nil, err
+		}
+		if err != nil {
+			fmt.Fprintf(t.out, "%s: ", err)
+			continue
+		}
+
+		result, err := t.api.Answer(ctx, id, answer)
+		var apiErr *client.Error
+		if errors.As(err, &apiErr) && apiErr.Code == "invalid_answer" {
+			fmt.Fprintf(t.out, "%s, try again: ", apiErr.Message)
+			continue
+		}
+		return result, err
+	}
+}
+
+func (t *terminalGame) showResult(ctx context.Context, result *client.AnswerResult) error {
+	fmt.Fprintf(t.out, "%s (%+d)\n", result.Message, result.Points)
+	if result.Real || result.OriginalURL == "" {
+		return nil
+	}
+	if result.TamperDescription != "" {
+		fmt.Fprintf(t.out, "It was tampered with: %s\n", result.TamperDescription)
+	}
+	fmt.Fprintln(t.out, "The real flag:")
+	return t.showFlag(ctx, result.OriginalURL, t.width/2)
+}
+
+// playOptions are the play command's own flags
+type playOptions struct {
+	Server     string
+	Players    string
+	Rounds     int
+	Mode       string
+	Difficulty string
+	TimeLimit  int
+	Width      int
+}
+
+func (opts *playOptions) bindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&opts.Server, "server", "", "URL of a flag quiz server to play against, instead of in-process")
+	fs.StringVar(&opts.Players, "players", "Player 1", "comma-separated player names")
+	fs.IntVar(&opts.Rounds, "rounds", 10, "number of rounds")
+	fs.StringVar(&opts.Mode, "mode", string(ModeTrueFalse), "truefalse, choice, pickreal or spot")
+	fs.StringVar(&opts.Difficulty, "difficulty", string(DifficultyNormal), "easy, normal or hard")
+	fs.IntVar(&opts.TimeLimit, "time-limit", 0, "seconds per answer, 0 for no limit")
+	fs.IntVar(&opts.Width, "width", terminalWidth(), "width of the flags in characters")
+}
+
+// runPlay plays a game in the terminal, against opts.Server or in-process
+// if there is none
+func runPlay(cfg Config, opts playOptions, in io.Reader, out io.Writer) error {
+	req := client.CreateGameRequest{
+		Rounds:     opts.Rounds,
+		Mode:       opts.Mode,
+		Difficulty: opts.Difficulty,
+		TimeLimit:  opts.TimeLimit,
+		Seed:       cfg.Seed,
+	}
+	for _, name := range strings.Split(opts.Players, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			req.Players = append(req.Players, name)
+		}
+	}
+
+	api := client.New(opts.Server)
+	if opts.Server == "" {
+		api = newInProcessClient(newDependencies(cfg))
+	}
+	game := &terminalGame{api: api, in: bufio.NewScanner(in), out: out, width: opts.Width}
+	return game.Play(context.Background(), req)
+}
+
+// terminalWidth leaves a margin inside $COLUMNS, if the shell exports it
+func terminalWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 8 {
+		return columns - 4
+	}
+	return defaultTerminalWidth
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"net/http"
+	"strings"
+	"testing"
+
+	"FlagsGUI/client"
+)
+
+func TestRenderANSIUsesHalfBlocks(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if y < 2 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+
+	rendered := renderANSI(img, 2)
+
+	cell := "\x1b[38;2;255;0;0m\x1b[48;2;0;0;255m▀"
+	if rendered != cell+cell+"\x1b[0m\n" {
+		t.Errorf("Expected one row of red over blue, got %q", rendered)
+	}
+}
+
+func TestTerminalSizeKeepsAspectRatio(t *testing.T) {
+	tests := []struct {
+		w, h, cols         int
+		wantCols, wantRows int
+	}{
+		{320, 160, 40, 40, 10},
+		{300, 200, 60, 60, 20},
+		{20, 10, 40, 20, 5},
+		{0, 10, 40, 0, 0},
+	}
+	for _, test := range tests {
+		cols, rows := terminalSize(test.w, test.h, test.cols)
+		if cols != test.wantCols || rows != test.wantRows {
+			t.Errorf("terminalSize(%d, %d, %d) = %d, %d, expected %d, %d", test.w, test.h, test.cols, cols, rows, test.wantCols, test.wantRows)
+		}
+	}
+}
+
+// Test_GIVEN_TypedInput_WHEN_ParsingAnswers_THEN_ExpectAPIAnswers tests the keyboard answers of every mode
+func Test_GIVEN_TypedInput_WHEN_ParsingAnswers_THEN_ExpectAPIAnswers(t *testing.T) {
+	// Arrange
+	choice := &client.Round{Mode: client.ModeChoice, Choices: []string{"Chad", "Peru", "Austria"}}
+	pick := &client.Round{Mode: client.ModePickReal, Options: []string{"/a", "/b", "/c"}}
+	spot := &client.Round{Mode: client.ModeSpot, FlagWidth: 320, FlagHeight: 160}
+	trueFalse := &client.Round{Mode: client.ModeTrueFalse}
+
+	tests := []struct {
+		round *client.Round
+		input string
+		want  string
+		ok    bool
+	}{
+		{trueFalse, "r", client.AnswerReal, true},
+		{trueFalse, " Fake ", client.AnswerFake, true},
+		{trueFalse, "maybe", "", false},
+		{choice, "2", "Peru", true},
+		{choice, "austria", "Austria", true},
+		{choice, "4", "", false},
+		{pick, "3", "2", true},
+		{pick, "0", "", false},
+		{spot, "1,1", "4,8", true},
+		{spot, "40,10", "316,152", true},
+		{spot, "41,1", "", false},
+	}
+	for _, test := range tests {
+		// Act
+		answer, err := parseTerminalAnswer(test.round, test.input, 40)
+
+		// Assert
+		if (err == nil) != test.ok || answer != test.want {
+			t.Errorf("%s %q: expected %q, got %q (%v)", test.round.Mode, test.input, test.want, answer, err)
+		}
+	}
+	if _, err := parseTerminalAnswer(trueFalse, "q", 40); err != errQuit {
+		t.Errorf("Expected q to quit, got %v", err)
+	}
+}
+
+// Test_GIVEN_InProcessEngine_WHEN_PlayingInTerminal_THEN_ExpectFlagsAndScores tests a whole terminal game
+func Test_GIVEN_InProcessEngine_WHEN_PlayingInTerminal_THEN_ExpectFlagsAndScores(t *testing.T) {
+	// Arrange
+	_, deps := newAPITestMux()
+	var out strings.Builder
+	seed := int64(5)
+	game := &terminalGame{
+		api:   newInProcessClient(deps),
+		in:    bufio.NewScanner(strings.NewReader("what\nr\nf\n")),
+		out:   &out,
+		width: 20,
+	}
+
+	// Act
+	err := game.Play(context.Background(), client.CreateGameRequest{Players: []string{"Anna"}, Rounds: 2, Seed: &seed})
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+	for _, want := range []string{"Round 1 of 2, Anna's turn", "Round 2 of 2", "▀", "type r for real or f for fake", "Final scores", "1. Anna"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in the output:\n%s", want, text)
+		}
+	}
+}
+
+func TestTerminalGameQuitsAtEndOfInput(t *testing.T) {
+	_, deps := newAPITestMux()
+	var out strings.Builder
+	game := &terminalGame{api: newInProcessClient(deps), in: bufio.NewScanner(strings.NewReader("")), out: &out, width: 20}
+
+	err := game.Play(context.Background(), client.CreateGameRequest{Players: []string{"Anna"}})
+
+	if err != nil || !strings.Contains(out.String(), "Game abandoned.") || strings.Contains(out.String(), "Final scores") {
+		t.Errorf("Expected the game to be abandoned, got %v:\n%s", err, out.String())
+	}
+}
+
+func TestInProcessClientKeepsAPIErrors(t *testing.T) {
+	_, deps := newAPITestMux()
+
+	_, err := newInProcessClient(deps).Round(context.Background(), "missing")
+
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound || apiErr.Code != "game_not_found" {
+		t.Errorf("Expected a game_not_found error, got %v", err)
+	}
+}
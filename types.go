@@ -1,15 +1,74 @@
 package main
 
+import (
+	"image"
+	"math/rand"
+	"time"
+)
+
+// Team lets several people share one scoreboard entry, taking turns to answer
+type Team struct {
+	Members []string
+	Turn    int
+}
+
 type Player struct {
 	Name       string
+	ProfileID  string // empty for teams and players without a profile
+	Color      string
+	Team       *Team
 	Correct    int
 	Incorrect  int
 	Total      int
 	Percentage int
+	Points     int
+
+	Lives           int
+	Eliminated      bool
+	EliminatedAt    int // 1 for the first player knocked out of a survival game
+	EliminatedRound int
+
+	Streak       int
+	BestStreak   int
+	Achievements []string
+}
+
+// GameMode selects which question is asked each round
+type GameMode string
+
+const (
+	ModeTrueFalse GameMode = "truefalse"
+	ModeChoice    GameMode = "choice"
+	ModePickReal  GameMode = "pickreal"
+	ModeSpot      GameMode = "spot"
+)
+
+// Difficulty sets how subtle the changes to tampered flags are
+type Difficulty string
+
+const (
+	DifficultyEasy   Difficulty = "easy"
+	DifficultyNormal Difficulty = "normal"
+	DifficultyHard   Difficulty = "hard"
+)
+
+// GameSettings holds the options chosen on the setup page
+type GameSettings struct {
+	Mode        GameMode   `json:"mode"`
+	Difficulty  Difficulty `json:"difficulty"`
+	PickOptions int        `json:"pickOptions"`
+	TimeLimit   int        `json:"timeLimit"` // seconds per answer, 0 for no limit
+	Lives       int        `json:"lives"`     // survival mode when above 0
+	Selection   string     `json:"selection,omitempty"`
+	Exploration float64    `json:"exploration,omitempty"`
+	Regions     []string   `json:"regions,omitempty"` // only countries from these regions, when set
 }
 
 type GameState struct {
+	GameID string
+	GameSettings
 	Players       []Player
+	Eliminations  int
 	CurrentPlayer int
 	GameStarted   bool
 	TotalRounds   int
@@ -17,15 +76,85 @@ type GameState struct {
 	GameOver      bool
 	IsCorrect     bool
 	CountryName   string
-	FlagData      string
-	OriginalFlag  string
-	ModifiedFlag  string
+	Region        string
+	RoundID       string
+	FlagSrc       string
+	OriginalSrc   string
+	ModifiedSrc   string
 	ShowResult    bool
 	ResultCorrect bool
 	ResultMessage string
+	Choices       []string
+	ChosenAnswer  string
+	FlagOptions   []string
+	RealOption    int
+	PickedOption  int
+
+	TamperDescription string
+	TamperMode        string
+	DeltaE            float64
+	HighlightSrc      string
+	FlagWidth         int
+	FlagHeight        int
+	SpotX             int
+	SpotY             int
+	tamperMask        *image.Alpha
+
+	StartedAt     time.Time
+	RoundStarted  time.Time
+	RoundDeadline time.Time
+	ResponseTime  time.Duration
+	TimeBonus     int
+
+	Multiplier      float64
+	NewAchievements []Achievement
+	Guesses         []Guess
+	loggedGuesses   int
+
+	// Replay logs every revealed round, and ReplayID is where it was
+	// saved once the game ended
+	Replay          []ReplayRound
+	ReplayID        string
+	replayedGuesses int
+
+	// Seed is set for games whose random choices can be replayed
+	Seed      int64
+	Seeded    bool
+	rng       *rand.Rand
+	rngSource *countingSource
+	selector  CountrySelector
+
+	// dealing is set while the local game's next flag downloads
+	dealing string
+
+	// Version goes up with every published event, so a page can tell
+	// whether it is out of date
+	Version int
+}
+
+// Guess is one player's answer to one flag. Truth tells whether the flag
+// shown was genuine, and an empty Answer means time ran out
+type Guess struct {
+	GameID     string    `json:"gameId"`
+	Mode       GameMode  `json:"mode"`
+	Round      int       `json:"round"`
+	Player     string    `json:"player"`
+	ProfileID  string    `json:"profileId,omitempty"`
+	Country    string    `json:"country"`
+	Region     string    `json:"region,omitempty"`
+	TamperMode string    `json:"tamperMode"`
+	DeltaE     float64   `json:"deltaE"`
+	Truth      bool      `json:"truth"`
+	Answer     string    `json:"answer"`
+	Correct    bool      `json:"correct"`
+	ResponseMS int64     `json:"responseMs"`
+	At         time.Time `json:"at"`
 }
 
 type CountryFlag struct {
-	Name    string
-	FlagURL string
+	Name      string
+	FlagURL   string
+	Capital   string
+	Region    string
+	SubRegion string
 }
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A minimal server side of RFC 6455: the opening handshake, masked client
+// frames, fragmented messages, and ping, pong and close control frames.
+// Extensions and subprotocols are not supported
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+const (
+	maxWebSocketMessage = 64 << 10
+	maxControlPayload   = 125
+	wsWriteTimeout      = 10 * time.Second
+)
+
+// Close codes sent to clients
+const (
+	closeNormal        = 1000
+	closeProtocolError = 1002
+	closeTooBig        = 1009
+)
+
+var (
+	errNotWebSocket   = errors.New("not a websocket handshake")
+	errProtocol       = errors.New("websocket protocol error")
+	errCrossOrigin    = errors.New("websocket origin does not match the host")
+	errMessageTooBig  = errors.New("websocket message too big")
+	errConnectionGone = errors.New("websocket connection closed")
+)
+
+// WebSocket is an upgraded connection. Reads must come from one goroutine;
+// writes may come from any
+type WebSocket struct {
+	conn        net.Conn
+	reader      *bufio.Reader
+	idleTimeout time.Duration
+
+	writeMu sync.Mutex
+	closed  bool
+}
+
+// websocketAccept computes the Sec-WebSocket-Accept answer for a client key
+func websocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sameOrigin reports whether an upgrade comes from a page of this server.
+// Browsers always send Origin, so requests without one come from other
+// clients and are let through
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// UpgradeWebSocket completes the opening handshake and takes over the connection
+func UpgradeWebSocket(w http.ResponseWriter, r *http.Request) (*WebSocket, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != "GET" || key == "" ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, errNotWebSocket.Error(), http.StatusBadRequest)
+		return nil, errNotWebSocket
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, errNotWebSocket
+	}
+	if !sameOrigin(r) {
+		// stops other sites from joining rooms with a visitor's cookies
+		http.Error(w, errCrossOrigin.Error(), http.StatusForbidden)
+		return nil, errCrossOrigin
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websockets are not supported", http.StatusInternalServerError)
+		return nil, errNotWebSocket
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + websocketAccept(key) + "\r\n\r\n"
+	if _, err := rw.WriteString(response); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &WebSocket{conn: conn, reader: rw.Reader}, nil
+}
+
+type wsFrame struct {
+	fin     bool
+	opcode  byte
+	payload []byte
+}
+
+func (ws *WebSocket) readFrame() (wsFrame, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(ws.reader, header[:]); err != nil {
+		return wsFrame{}, err
+	}
+	frame := wsFrame{fin: header[0]&0x80 != 0, opcode: header[0] & 0x0F}
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+
+	// no extensions are negotiated, so the reserved bits must be clear, and
+	// clients must always mask what they send
+	if header[0]&0x70 != 0 || !masked {
+		return frame, errProtocol
+	}
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(ws.reader, ext[:]); err != nil {
+			return frame, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(ws.reader, ext[:]); err != nil {
+			return frame, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if frame.opcode >= opClose && (length > maxControlPayload || !frame.fin) {
+		return frame, errProtocol
+	}
+	if length > maxWebSocketMessage {
+		return frame, errMessageTooBig
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(ws.reader, mask[:]); err != nil {
+		return frame, err
+	}
+	frame.payload = make([]byte, length)
+	if _, err := io.ReadFull(ws.reader, frame.payload); err != nil {
+		return frame, err
+	}
+	for i := range frame.payload {
+		frame.payload[i] ^= mask[i%4]
+	}
+	return frame, nil
+}
+
+// ReadMessage returns the next text or binary message. Pings are answered
+// and pongs skipped along the way; a close frame is answered and reported
+// as errConnectionGone
+func (ws *WebSocket) ReadMessage() (byte, []byte, error) {
+	var opcode byte
+	var message []byte
+	for {
+		if ws.idleTimeout > 0 {
+			ws.conn.SetReadDeadline(time.Now().Add(ws.idleTimeout))
+		}
+		frame, err := ws.readFrame()
+		if err != nil {
+			switch {
+			case errors.Is(err, errProtocol):
+				ws.closeWith(closeProtocolError)
+			case errors.Is(err, errMessageTooBig):
+				ws.closeWith(closeTooBig)
+			}
+			return 0, nil, err
+		}
+
+		switch frame.opcode {
+		case opPing:
+			if err := ws.writeFrame(opPong, frame.payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			ws.closeWith(closeNormal)
+			return 0, nil, errConnectionGone
+		case opText, opBinary:
+			if message != nil {
+				return 0, nil, errProtocol
+			}
+			opcode = frame.opcode
+			message = frame.payload
+		case opContinuation:
+			if message == nil {
+				return 0, nil, errProtocol
+			}
+			if len(message)+len(frame.payload) > maxWebSocketMessage {
+				ws.closeWith(closeTooBig)
+				return 0, nil, errMessageTooBig
+			}
+			message = append(message, frame.payload...)
+		default:
+			ws.closeWith(closeProtocolError)
+			return 0, nil, errProtocol
+		}
+
+		if frame.fin {
+			return opcode, message, nil
+		}
+	}
+}
+
+func (ws *WebSocket) writeFrame(opcode byte, payload []byte) error {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+
+	if ws.closed {
+		return errConnectionGone
+	}
+
+	// server frames are never masked
+	header := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length < 126:
+		header = append(header, byte(length))
+	case length <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	ws.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if _, err := ws.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// WriteText sends a text message
+func (ws *WebSocket) WriteText(data []byte) error {
+	return ws.writeFrame(opText, data)
+}
+
+// Ping asks the client to prove it is still there
+func (ws *WebSocket) Ping() error {
+	return ws.writeFrame(opPing, nil)
+}
+
+// SetIdleTimeout drops the connection when the client sends no frame at
+// all, not even a pong, for longer than d
+func (ws *WebSocket) SetIdleTimeout(d time.Duration) {
+	ws.idleTimeout = d
+}
+
+// closeWith sends a close frame with the given code and drops the connection
+func (ws *WebSocket) closeWith(code uint16) {
+	ws.writeFrame(opClose, binary.BigEndian.AppendUint16(nil, code))
+	ws.Close()
+}
+
+func (ws *WebSocket) Close() error {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+
+	if ws.closed {
+		return nil
+	}
+	ws.closed = true
+	return ws.conn.Close()
+}